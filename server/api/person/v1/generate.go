@@ -0,0 +1,4 @@
+//Package personv1 is a gRPC API of people, it is generated from person.proto
+package personv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative person/v1/person.proto
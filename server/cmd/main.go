@@ -1,18 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	personv1 "server/server/api/person/v1"
 	"server/server/config"
+	"server/server/db"
 	personDel "server/server/internal/Person/delivery"
+	personRepository "server/server/internal/Person/repository"
+	personMemRep "server/server/internal/Person/repository/memory"
 	personRep "server/server/internal/Person/repository/postgres"
+	personSQLiteRep "server/server/internal/Person/repository/sqlite"
 	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
 	"server/server/internal/middleware"
+	"server/server/internal/migrate"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	_ "modernc.org/sqlite"
 )
 
 const PORT = ":8080"
@@ -46,15 +69,138 @@ func GetPostgres(psqlInfo string) (*sql.DB, error) {
 	return db, nil
 }
 
-func main() {
-	router := mux.NewRouter()
-	db, err := GetPostgres(psqlInfo)
+//GetSQLite opens sqlite database with connection for writes and pool of connections for reads
+func GetSQLite(path string) (*sql.DB, *sql.DB, error) {
+	writeDB, readDB, err := personSQLiteRep.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Println("Successfully opened sqlite database!")
+	return writeDB, readDB, nil
+}
+
+//GetMigrator gets migrator of schema of database with embedded migrations
+func GetMigrator(database *sql.DB, dialect string) (*migrate.Migrator, error) {
+	fsys, dir := fs.FS(db.PostgresMigrations), "."
+	if dialect == migrate.DialectSQLite {
+		fsys, dir = db.SQLiteMigrations, "sqlite"
+	}
+
+	migrations, err := migrate.Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(database, dialect, migrations), nil
+}
+
+//runMigrate runs migrate subcommand: up, down, to <version> or status
+func runMigrate(migrator *migrate.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|to <version>|status")
+	}
+
+	var err error
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "to":
+		if len(args) < 2 {
+			return errors.New("usage: migrate to <version>")
+		}
+		version, parseErr := strconv.Atoi(args[1])
+		if parseErr != nil {
+			return parseErr
+		}
+		err = migrator.To(version)
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	current, latest, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version %d of %d\n", current, latest)
+	return nil
+}
+
+//purgeDeletedPersons periodically removes people which were soft-deleted longer than retention ago
+func purgeDeletedPersons(personUC personUsecase.PersonUsecaseI, appConfig *config.App, infoLogger *zap.SugaredLogger, errorLogger *zap.SugaredLogger) {
+	ticker := time.NewTicker(appConfig.PurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := personUC.PurgeDeletedPersons(appConfig.PurgeRetention)
+		if err != nil {
+			errorLogger.Errorw("problems with purging deleted people", zap.Error(err))
+			continue
+		}
+		if purged > 0 {
+			infoLogger.Infow("purged deleted people", zap.Int64("purged", purged))
+		}
+	}
+}
+
+//shutdownOnSignal gracefully stops server on SIGINT or SIGTERM, done is closed after HTTP handlers
+//and gRPC calls are finished
+func shutdownOnSignal(server *http.Server, grpcServer *grpc.Server, healthServer *health.Server, done chan<- struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	defer close(done)
+
+	healthServer.Shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	grpcStopped := make(chan struct{})
+	go func() {
+		stopGRPC(ctx, grpcServer)
+		close(grpcStopped)
+	}()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		fmt.Printf("error shutting down server: %s\n", err)
+	}
+	<-grpcStopped
+}
+
+//stopGRPC waits for gRPC calls to finish, calls which are still running after ctx is done are cancelled
+func stopGRPC(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+}
+
+//serveGRPC serves gRPC calls on a separate port from REST API
+func serveGRPC(grpcServer *grpc.Server, addr string) {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		fmt.Println(err, " ", psqlInfo)
-		log.Fatalf("cant connect to postgres")
+		fmt.Printf("error listening for gRPC server: %s\n", err)
 		return
 	}
-	defer db.Close()
+	fmt.Println("gRPC server start at", addr)
+	err = grpcServer.Serve(listener)
+	if err != nil {
+		fmt.Printf("error serving gRPC: %s\n", err)
+	}
+}
+
+func main() {
+	router := mux.NewRouter()
 
 	baseLogger, err := config.Cfg.Build()
 	if err != nil {
@@ -70,30 +216,145 @@ func main() {
 	}
 	defer errorLogger.Sync()
 
+	appConfig, err := config.LoadApp()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	flag.BoolVar(&appConfig.MigrateOnStartup, "migrate", appConfig.MigrateOnStartup, "apply schema migrations on startup")
+	flag.Parse()
+	migrateCommand := flag.Arg(0) == "migrate"
+
 	logger := middleware.NewACLog(baseLogger.Sugar(), errorLogger.Sugar())
+	adminAuth := middleware.NewAdminAuth(appConfig.AdminToken)
+
+	var personRepo personRepository.PersonRepositoryI
+	var memoryRepo *personMemRep.PersonRepo
+	var database *sql.DB
+	var dialect string
+	switch appConfig.Storage {
+	case config.StorageMemory:
+		if migrateCommand {
+			fmt.Println("memory storage has no schema to migrate")
+			return
+		}
+		memoryRepo = personMemRep.NewPersonRepo()
+		if appConfig.MemorySnapshot != "" {
+			err = memoryRepo.Load(appConfig.MemorySnapshot)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+		personRepo = memoryRepo
+	case config.StorageSQLite:
+		var readDatabase *sql.DB
+		database, readDatabase, err = GetSQLite(appConfig.SQLitePath())
+		if err != nil {
+			fmt.Println(err)
+			log.Fatalf("cant open sqlite database")
+			return
+		}
+		defer database.Close()
+		defer readDatabase.Close()
+		dialect = migrate.DialectSQLite
+		personRepo = personSQLiteRep.NewPersonRepo(database, readDatabase)
+	default:
+		if appConfig.DatabaseURL != "" {
+			psqlInfo = appConfig.DatabaseURL
+		}
+		database, err = GetPostgres(psqlInfo)
+		if err != nil {
+			fmt.Println(err, " ", psqlInfo)
+			log.Fatalf("cant connect to postgres")
+			return
+		}
+		defer database.Close()
+		dialect = migrate.DialectPostgres
+		personRepo = personRep.NewPersonRepo(database)
+	}
+
+	if database != nil && (migrateCommand || appConfig.MigrateOnStartup) {
+		migrator, err := GetMigrator(database, dialect)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
-	personRepo := personRep.NewPersonRepo(db)
-	personUC := personUsecase.NewPersonUsecase(personRepo)
+		args := []string{"up"}
+		if migrateCommand {
+			args = flag.Args()[1:]
+		}
+		err = runMigrate(migrator, args)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if migrateCommand {
+			return
+		}
+	}
+
+	personUC := personUsecase.NewPersonUsecase(personRepo, dto.NewDuplicateRules(appConfig.DuplicateRules, appConfig.DuplicateMaxDistance))
+	err = personUC.FillPhonetic()
+	if err != nil {
+		errorLogger.Sugar().Errorw("problems with filling phonetic codes", zap.Error(err))
+	}
 	personHandler := personDel.NewPersonHandler(personUC, logger)
+	graphQLHandler := personDel.NewGraphQLHandler(personUC, logger, appConfig.GraphQLMaxDepth, appConfig.GraphQLMaxComplexity)
+	personGRPC := personDel.NewPersonGRPCServer(personUC, logger)
+
+	go purgeDeletedPersons(personUC, appConfig, baseLogger.Sugar(), errorLogger.Sugar())
 
 	personHandler.RegisterHandler(router)
+	graphQLHandler.RegisterHandler(router)
+	missingRoutes, err := personDel.MissingFromOpenAPI(router)
+	if err != nil {
+		errorLogger.Sugar().Errorw("problems with checking OpenAPI document", zap.Error(err))
+	}
+	for _, route := range missingRoutes {
+		errorLogger.Sugar().Errorw("route is missing from OpenAPI document", zap.String("route", route))
+	}
 
 	router.Use(middleware.PanicMiddleware)
 	router.Use(logger.ACLogMiddleware)
+	router.Use(adminAuth.AdminMiddleware)
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logger.UnaryInterceptor, logger.RecoverUnaryInterceptor, adminAuth.UnaryInterceptor),
+		grpc.ChainStreamInterceptor(logger.StreamInterceptor, logger.RecoverStreamInterceptor, adminAuth.StreamInterceptor),
+	)
+	personGRPC.RegisterHandler(grpcServer)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(personv1.PersonService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	go serveGRPC(grpcServer, appConfig.GRPCAddr)
 
 	server := &http.Server{
 		Addr:    PORT,
 		Handler: router,
 	}
 
+	shutdown := make(chan struct{})
+	go shutdownOnSignal(server, grpcServer, healthServer, shutdown)
+
 	fmt.Println("Server start at port", PORT[1:])
 	err = server.ListenAndServe()
 
 	if errors.Is(err, http.ErrServerClosed) {
+		//ListenAndServe returns as soon as shutdown begins, snapshot is saved after running requests are finished
+		<-shutdown
 		fmt.Printf("server closed\n")
 
 	} else if err != nil {
 		fmt.Printf("error listening for server: %s\n", err)
 	}
 
+	if memoryRepo != nil && appConfig.MemorySnapshot != "" {
+		err = memoryRepo.Save(appConfig.MemorySnapshot)
+		if err != nil {
+			fmt.Printf("error saving memory snapshot: %s\n", err)
+		}
+	}
 }
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Storages of people, storage is chosen by scheme of DatabaseURL or by STORAGE
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+//App is a config of application
+type App struct {
+	DatabaseURL          string
+	Storage              string
+	MemorySnapshot       string
+	AdminToken           string
+	PurgeRetention       time.Duration
+	PurgeInterval        time.Duration
+	MigrateOnStartup     bool
+	DuplicateRules       []string
+	DuplicateMaxDistance int
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+	GRPCAddr             string
+}
+
+//LoadApp reads config of application from environment
+func LoadApp() (*App, error) {
+	app := &App{
+		DatabaseURL:          os.Getenv("DATABASE_URL"),
+		MemorySnapshot:       os.Getenv("MEMORY_SNAPSHOT"),
+		AdminToken:           os.Getenv("ADMIN_TOKEN"),
+		PurgeRetention:       30 * 24 * time.Hour,
+		PurgeInterval:        time.Hour,
+		DuplicateRules:       []string{"exact", "fuzzy"},
+		DuplicateMaxDistance: 2,
+		GraphQLMaxDepth:      10,
+		GraphQLMaxComplexity: 2000,
+		GRPCAddr:             ":9090",
+	}
+
+	scheme := app.DatabaseURL
+	if i := strings.Index(scheme, ":"); i >= 0 {
+		scheme = scheme[:i]
+	}
+	switch scheme {
+	case "", "postgres", "postgresql":
+		app.Storage = StoragePostgres
+	case "sqlite":
+		app.Storage = StorageSQLite
+	case "memory":
+		app.Storage = StorageMemory
+	default:
+		return nil, fmt.Errorf("unknown database scheme %q", scheme)
+	}
+
+	//STORAGE chose storage before DATABASE_URL did, it still works alone and must agree with DATABASE_URL
+	if value := os.Getenv("STORAGE"); value != "" {
+		switch {
+		case value != StoragePostgres && value != StorageSQLite && value != StorageMemory:
+			return nil, fmt.Errorf("unknown storage %q", value)
+		case app.DatabaseURL == "" && value == StorageSQLite:
+			return nil, fmt.Errorf("STORAGE=%s requires DATABASE_URL like sqlite://personinfo.db", value)
+		case app.DatabaseURL != "" && value != app.Storage:
+			return nil, fmt.Errorf("STORAGE=%s conflicts with DATABASE_URL of %s storage", value, app.Storage)
+		}
+		app.Storage = value
+	}
+
+	var err error
+	if value := os.Getenv("PURGE_RETENTION"); value != "" {
+		app.PurgeRetention, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if value := os.Getenv("PURGE_INTERVAL"); value != "" {
+		app.PurgeInterval, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		if app.PurgeInterval <= 0 {
+			return nil, fmt.Errorf("PURGE_INTERVAL must be positive, got %s", app.PurgeInterval)
+		}
+	}
+
+	if value := os.Getenv("MIGRATE_ON_STARTUP"); value != "" {
+		app.MigrateOnStartup, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//DUPLICATE_RULES is a comma-separated list of exact, phonetic and fuzzy, "none" disables duplicate detection
+	if value := os.Getenv("DUPLICATE_RULES"); value != "" {
+		app.DuplicateRules = []string{}
+		for _, rule := range strings.Split(value, ",") {
+			rule = strings.TrimSpace(rule)
+			switch rule {
+			case "exact", "phonetic", "fuzzy":
+				app.DuplicateRules = append(app.DuplicateRules, rule)
+			case "none":
+			default:
+				return nil, fmt.Errorf("unknown duplicate rule %q", rule)
+			}
+		}
+	}
+	//DUPLICATE_MAX_DISTANCE is a maximum count of typos in name and surname for fuzzy duplicate rule
+	if value := os.Getenv("DUPLICATE_MAX_DISTANCE"); value != "" {
+		app.DuplicateMaxDistance, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//GRAPHQL_MAX_DEPTH and GRAPHQL_MAX_COMPLEXITY limit GraphQL operations, zero disables the limit
+	if value := os.Getenv("GRAPHQL_MAX_DEPTH"); value != "" {
+		app.GraphQLMaxDepth, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if value := os.Getenv("GRAPHQL_MAX_COMPLEXITY"); value != "" {
+		app.GraphQLMaxComplexity, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//GRPC_ADDR is an address of gRPC server, it listens on a separate port from REST API
+	if value := os.Getenv("GRPC_ADDR"); value != "" {
+		app.GRPCAddr = value
+	}
+
+	return app, nil
+}
+
+//SQLitePath gets path to sqlite database file from DatabaseURL like sqlite://personinfo.db
+func (app *App) SQLitePath() string {
+	return strings.TrimPrefix(strings.TrimPrefix(app.DatabaseURL, "sqlite:"), "//")
+}
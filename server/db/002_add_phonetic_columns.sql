@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+
+ALTER TABLE public.PERSON ADD COLUMN IF NOT EXISTS NAME_PHONETIC text[];
+ALTER TABLE public.PERSON ADD COLUMN IF NOT EXISTS SURNAME_PHONETIC text[];
+
+CREATE INDEX IF NOT EXISTS person_name_phonetic_idx ON public.PERSON USING GIN (NAME_PHONETIC);
+CREATE INDEX IF NOT EXISTS person_surname_phonetic_idx ON public.PERSON USING GIN (SURNAME_PHONETIC);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS person_surname_phonetic_idx;
+DROP INDEX IF EXISTS person_name_phonetic_idx;
+
+ALTER TABLE public.PERSON DROP COLUMN IF EXISTS SURNAME_PHONETIC;
+ALTER TABLE public.PERSON DROP COLUMN IF EXISTS NAME_PHONETIC;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,14 @@
+-- Write your migrate up statements here
+
+ALTER TABLE public.PERSON ADD COLUMN IF NOT EXISTS DELETED_AT TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS person_deleted_at_idx ON public.PERSON (DELETED_AT) WHERE DELETED_AT IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS person_deleted_at_idx;
+
+ALTER TABLE public.PERSON DROP COLUMN IF EXISTS DELETED_AT;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,10 @@
+-- Write your migrate up statements here
+
+ALTER TABLE public.PERSON ADD COLUMN IF NOT EXISTS VERSION integer NOT NULL DEFAULT 1;
+
+---- create above / drop below ----
+
+ALTER TABLE public.PERSON DROP COLUMN IF EXISTS VERSION;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,33 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS public.PERSON_HISTORY
+(
+    ID bigserial NOT NULL,
+    PERSON_ID integer NOT NULL,
+    OPERATION varchar NOT NULL,
+    VERSION integer NOT NULL,
+    BEFORE jsonb,
+    AFTER jsonb,
+    ACTOR varchar NOT NULL,
+    REQUEST_ID varchar NOT NULL,
+    CHANGED_AT TIMESTAMP WITH TIME ZONE default NOW() NOT NULL,
+    PRIMARY KEY (ID)
+);
+
+CREATE INDEX IF NOT EXISTS person_history_person_id_idx ON public.PERSON_HISTORY (PERSON_ID, ID);
+CREATE INDEX IF NOT EXISTS person_history_changed_at_idx ON public.PERSON_HISTORY (CHANGED_AT);
+
+-- people created before history existed get their current state as the first record
+INSERT INTO public.PERSON_HISTORY (PERSON_ID, OPERATION, VERSION, AFTER, ACTOR, REQUEST_ID, CHANGED_AT)
+SELECT ID, 'create', VERSION,
+       jsonb_build_object('id', ID, 'name', NAME, 'surname', SURNAME, 'patronymic', PATRONYMIC,
+                          'age', AGE, 'gender', GENDER, 'nation', NATION, 'deleted_at', DELETED_AT, 'version', VERSION),
+       'migration', '', COALESCE(UPDATED_AT, CREATED_AT)
+FROM public.PERSON;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS public.PERSON_HISTORY;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
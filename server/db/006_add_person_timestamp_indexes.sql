@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+CREATE INDEX IF NOT EXISTS person_created_at_idx ON public.PERSON (CREATED_AT);
+CREATE INDEX IF NOT EXISTS person_updated_at_idx ON public.PERSON (UPDATED_AT);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS person_updated_at_idx;
+DROP INDEX IF EXISTS person_created_at_idx;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,19 @@
+-- Write your migrate up statements here
+
+-- ids of people merged into other people, rows are kept after merged people are purged
+CREATE TABLE IF NOT EXISTS public.PERSON_REDIRECT
+(
+    SOURCE_ID integer NOT NULL,
+    TARGET_ID integer NOT NULL,
+    MERGED_AT TIMESTAMP WITH TIME ZONE default NOW() NOT NULL,
+    PRIMARY KEY (SOURCE_ID)
+);
+
+CREATE INDEX IF NOT EXISTS person_redirect_target_id_idx ON public.PERSON_REDIRECT (TARGET_ID);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS public.PERSON_REDIRECT;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
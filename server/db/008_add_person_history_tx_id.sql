@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+
+-- id of transaction which made the change, the change feed is ordered by it. Existing records
+-- get the id of this migration, so they keep their order by ids
+ALTER TABLE public.PERSON_HISTORY ADD COLUMN IF NOT EXISTS TX_ID bigint NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX IF NOT EXISTS person_history_tx_id_idx ON public.PERSON_HISTORY (TX_ID, ID);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS person_history_tx_id_idx;
+ALTER TABLE public.PERSON_HISTORY DROP COLUMN IF EXISTS TX_ID;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,13 @@
+package db
+
+import "embed"
+
+//PostgresMigrations are migrations of postgres storage in tern format
+//
+//go:embed *.sql
+var PostgresMigrations embed.FS
+
+//SQLiteMigrations are migrations of sqlite storage in tern format
+//
+//go:embed sqlite/*.sql
+var SQLiteMigrations embed.FS
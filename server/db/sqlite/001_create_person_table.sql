@@ -0,0 +1,29 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS PERSON
+(
+    ID integer PRIMARY KEY AUTOINCREMENT,
+    NAME varchar NOT NULL,
+    SURNAME varchar NOT NULL,
+    PATRONYMIC varchar,
+    AGE integer NOT NULL,
+    GENDER varchar NOT NULL,
+    NATION varchar NOT NULL,
+    CREATED_AT DATETIME default (strftime('%Y-%m-%d %H:%M:%f', 'now')) NOT NULL,
+    UPDATED_AT DATETIME default (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TRIGGER IF NOT EXISTS set_timestamp
+AFTER UPDATE ON PERSON
+FOR EACH ROW WHEN NEW.UPDATED_AT IS OLD.UPDATED_AT
+BEGIN
+  UPDATE PERSON SET UPDATED_AT = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE ID = NEW.ID;
+END;
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS set_timestamp;
+DROP TABLE PERSON;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
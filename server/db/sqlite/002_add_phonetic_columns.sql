@@ -0,0 +1,13 @@
+-- Write your migrate up statements here
+
+-- phonetic codes are stored as JSON arrays
+ALTER TABLE PERSON ADD COLUMN NAME_PHONETIC text;
+ALTER TABLE PERSON ADD COLUMN SURNAME_PHONETIC text;
+
+---- create above / drop below ----
+
+ALTER TABLE PERSON DROP COLUMN SURNAME_PHONETIC;
+ALTER TABLE PERSON DROP COLUMN NAME_PHONETIC;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
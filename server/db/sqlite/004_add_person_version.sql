@@ -0,0 +1,10 @@
+-- Write your migrate up statements here
+
+ALTER TABLE PERSON ADD COLUMN VERSION integer NOT NULL DEFAULT 1;
+
+---- create above / drop below ----
+
+ALTER TABLE PERSON DROP COLUMN VERSION;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
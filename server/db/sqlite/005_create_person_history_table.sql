@@ -0,0 +1,33 @@
+-- Write your migrate up statements here
+
+CREATE TABLE IF NOT EXISTS PERSON_HISTORY
+(
+    ID integer PRIMARY KEY AUTOINCREMENT,
+    PERSON_ID integer NOT NULL,
+    OPERATION varchar NOT NULL,
+    VERSION integer NOT NULL,
+    BEFORE text,
+    AFTER text,
+    ACTOR varchar NOT NULL,
+    REQUEST_ID varchar NOT NULL,
+    CHANGED_AT DATETIME default (strftime('%Y-%m-%d %H:%M:%f', 'now')) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS person_history_person_id_idx ON PERSON_HISTORY (PERSON_ID, ID);
+CREATE INDEX IF NOT EXISTS person_history_changed_at_idx ON PERSON_HISTORY (CHANGED_AT);
+
+-- people created before history existed get their current state as the first record
+INSERT INTO PERSON_HISTORY (PERSON_ID, OPERATION, VERSION, AFTER, ACTOR, REQUEST_ID, CHANGED_AT)
+SELECT ID, 'create', VERSION,
+       json_object('id', ID, 'name', NAME, 'surname', SURNAME, 'patronymic', PATRONYMIC,
+                   'age', AGE, 'gender', GENDER, 'nation', NATION,
+                   'deleted_at', strftime('%Y-%m-%dT%H:%M:%fZ', DELETED_AT), 'version', VERSION),
+       'migration', '', COALESCE(UPDATED_AT, CREATED_AT)
+FROM PERSON;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS PERSON_HISTORY;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
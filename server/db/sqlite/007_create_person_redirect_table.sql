@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+
+-- ids of people merged into other people, rows are kept after merged people are purged
+CREATE TABLE IF NOT EXISTS PERSON_REDIRECT
+(
+    SOURCE_ID integer PRIMARY KEY,
+    TARGET_ID integer NOT NULL,
+    MERGED_AT DATETIME default (strftime('%Y-%m-%d %H:%M:%f', 'now')) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS person_redirect_target_id_idx ON PERSON_REDIRECT (TARGET_ID);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS PERSON_REDIRECT;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -0,0 +1,106 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"server/server/internal/domain/dto"
+)
+
+//maxBatchSize is a maximum size of batch in bytes
+const maxBatchSize = 10 << 20
+
+//batchSuccess are statuses of succeeded operations of batch
+var batchSuccess = map[string]int{
+	dto.BatchCreate: http.StatusCreated,
+	dto.BatchUpdate: http.StatusOK,
+	dto.BatchDelete: http.StatusNoContent,
+}
+
+//BatchItem is a result of operation of batch, Error is problem details of failed operation
+type BatchItem struct {
+	Index   int      `json:"index"`
+	Op      string   `json:"op"`
+	Status  int      `json:"status"`
+	ID      uint     `json:"id,omitempty"`
+	Version uint     `json:"version,omitempty"`
+	Error   *Problem `json:"error,omitempty"`
+}
+
+//BatchResponse is a result of batch with results of operations in order of request
+type BatchResponse struct {
+	Atomic    bool         `json:"atomic"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []*BatchItem `json:"results"`
+}
+
+//BatchPersons applies batch of creations, updates and deletions. The answer is 200 if all operations
+//succeeded and 207 with statuses and problems of operations otherwise
+func (handler *PersonHandler) BatchPersons(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiateDocument(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		handler.writeProblem(w, r, http.StatusBadRequest, "bad content-type", errors.New("bad content-type"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchSize)
+	jsonbody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		handler.writeProblem(w, r, status, "problems with reading json", err)
+		return
+	}
+
+	batch := &dto.Batch{}
+	err = json.Unmarshal(jsonbody, batch)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with unmarshalling json", err)
+		return
+	}
+
+	result, err := handler.persons.ApplyBatch(r.Context(), batch, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems with applying batch", err)
+		return
+	}
+
+	resp := &BatchResponse{
+		Atomic:    result.Atomic,
+		Succeeded: result.Succeeded,
+		Failed:    result.Failed,
+		Results:   []*BatchItem{},
+	}
+	for _, itemResult := range result.Results {
+		item := &BatchItem{
+			Index:   itemResult.Index,
+			Op:      itemResult.Op,
+			Status:  batchSuccess[itemResult.Op],
+			ID:      itemResult.ID,
+			Version: itemResult.Version,
+		}
+		if itemResult.Err != nil {
+			item.Error = problemFor(itemResult.Err)
+			item.Status = item.Error.Status
+			if item.Status >= http.StatusInternalServerError {
+				handler.logger.LogError("problems with operation of batch", itemResult.Err, w.Header().Get("request-id"), r.URL.Path)
+			}
+		}
+		resp.Results = append(resp.Results, item)
+	}
+
+	status := http.StatusOK
+	if resp.Failed != 0 {
+		status = http.StatusMultiStatus
+	}
+	handler.writeBody(w, r, format, status, resp)
+}
@@ -0,0 +1,45 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBatchBodySize(t *testing.T) {
+	//usecase is never called for rejected bodies
+	router := newTestRouter(nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid json", `{"operations":`, http.StatusBadRequest},
+		{"too large", `{"operations":[` + strings.Repeat(" ", maxBatchSize) + `]}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/people/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != problemType {
+				t.Errorf("Content-Type = %q, want %q", got, problemType)
+			}
+			problem := &Problem{}
+			if err := json.Unmarshal(rec.Body.Bytes(), problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != tt.wantStatus {
+				t.Errorf("problem has status %d, want %d", problem.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"io"
+	"server/server/internal/domain/dto"
+	"strconv"
+	"time"
+)
+
+//writeAggregateCSV writes groups as CSV with columns of dimensions followed by columns of metrics
+func writeAggregateCSV(w io.Writer, result *dto.AggregateResult) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(append(append([]string{}, result.GroupBy...), result.Metrics...))
+	if err != nil {
+		return err
+	}
+
+	for _, row := range result.Rows {
+		record := []string{}
+		for _, dimension := range result.GroupBy {
+			record = append(record, row.Group[dimension])
+		}
+		for _, metric := range result.Metrics {
+			record = append(record, strconv.FormatFloat(row.Metrics[metric], 'f', -1, 64))
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+//personColumns are columns of people written as CSV
+var personColumns = []string{"id", "name", "surname", "patronymic", "age", "gender", "nation", "deleted_at", "version", "created_at", "updated_at"}
+
+//personCSV writes people as CSV, header row is written before the first person
+type personCSV struct {
+	writer  *csv.Writer
+	started bool
+}
+
+func newPersonCSV(w io.Writer) *personCSV {
+	return &personCSV{
+		writer: csv.NewWriter(w),
+	}
+}
+
+func (list *personCSV) Write(person *dto.Person) error {
+	if !list.started {
+		list.started = true
+		err := list.writer.Write(personColumns)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := list.writer.Write([]string{
+		strconv.FormatUint(uint64(person.ID), 10),
+		person.Name,
+		person.Surname,
+		person.Patronymic,
+		strconv.FormatUint(uint64(person.Age), 10),
+		person.Gender,
+		person.Nation,
+		csvTime(person.DeletedAt),
+		strconv.FormatUint(uint64(person.Version), 10),
+		csvTime(&person.CreatedAt),
+		csvTime(person.UpdatedAt),
+	})
+	if err != nil {
+		return err
+	}
+	//rows are flushed one by one, so they reach client when stream is flushed
+	list.writer.Flush()
+	return list.writer.Error()
+}
+
+func (list *personCSV) Close() error {
+	if !list.started {
+		list.started = true
+		err := list.writer.Write(personColumns)
+		if err != nil {
+			return err
+		}
+	}
+	list.writer.Flush()
+	return list.writer.Error()
+}
+
+//csvTime formats time like JSON does, nil time is an empty cell
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,32 @@
+package delivery
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+//personCursorPrefix is a prefix of opaque cursors of pages of people
+const personCursorPrefix = "person:"
+
+//errInvalidCursor is returned for cursors which were not made by personCursor
+var errInvalidCursor = errors.New("cursor is invalid")
+
+//personCursor makes opaque cursor of people after the person, it is shared by GraphQL and gRPC pages
+func personCursor(id uint) string {
+	return base64.StdEncoding.EncodeToString([]byte(personCursorPrefix + strconv.FormatUint(uint64(id), 10)))
+}
+
+//parsePersonCursor gets id of person of the cursor
+func parsePersonCursor(cursor string) (uint, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), personCursorPrefix) {
+		return 0, errInvalidCursor
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(data), personCursorPrefix), 10, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,84 @@
+package delivery
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//openAPI is OpenAPI document of the API, it must describe every registered route
+//
+//go:embed openapi.json
+var openAPI []byte
+
+//docsPage renders openAPI with Redoc
+//
+//go:embed docs.html
+var docsPage []byte
+
+//routeVariable matches variables of mux path templates with their patterns
+var routeVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+//GetOpenAPI gets OpenAPI document
+func (handler *PersonHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(openAPI)
+	if err != nil {
+		handler.logger.LogError("problems with writing OpenAPI document", err, w.Header().Get("request-id"), r.URL.Path)
+	}
+}
+
+//GetDocs gets documentation page
+func (handler *PersonHandler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := w.Write(docsPage)
+	if err != nil {
+		handler.logger.LogError("problems with writing docs", err, w.Header().Get("request-id"), r.URL.Path)
+	}
+}
+
+//MissingFromOpenAPI lists routes of router which are not described by OpenAPI document as "METHOD /path".
+//HEAD is described by GET
+func MissingFromOpenAPI(router *mux.Router) ([]string, error) {
+	spec := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	err := json.Unmarshal(openAPI, &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	reported := map[string]bool{}
+	err = router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		path := routeVariable.ReplaceAllString(template, "{$1}")
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if method == http.MethodHead {
+				method = http.MethodGet
+			}
+			if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok && !reported[method+" "+path] {
+				reported[method+" "+path] = true
+				missing = append(missing, method+" "+path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
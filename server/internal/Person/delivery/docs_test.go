@@ -0,0 +1,49 @@
+package delivery
+
+import (
+	"net/http"
+	personUsecase "server/server/internal/Person/usecase"
+	mw "server/server/internal/middleware"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+//newTestRouter registers REST and GraphQL api served by the usecase like main does
+func newTestRouter(persons personUsecase.PersonUsecaseI) *mux.Router {
+	logger := mw.NewACLog(zap.NewNop().Sugar(), zap.NewNop().Sugar())
+	router := mux.NewRouter()
+	NewPersonHandler(persons, logger).RegisterHandler(router)
+	NewGraphQLHandler(persons, logger, 10, 1000).RegisterHandler(router)
+	return router
+}
+
+func TestOpenAPIDescribesRoutes(t *testing.T) {
+	missing, err := MissingFromOpenAPI(newTestRouter(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("routes are missing from openapi.json: %q", missing)
+	}
+}
+
+func TestMissingFromOpenAPI(t *testing.T) {
+	router := newTestRouter(nil)
+	router.HandleFunc("/api/people/{id:[0-9]+}/photo", nil).Methods(http.MethodGet, http.MethodHead, http.MethodPut)
+
+	missing, err := MissingFromOpenAPI(router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GET /api/people/{id}/photo", "PUT /api/people/{id}/photo"}
+	if len(missing) != len(want) {
+		t.Fatalf("MissingFromOpenAPI() = %q, want %q", missing, want)
+	}
+	for i := range want {
+		if missing[i] != want[i] {
+			t.Fatalf("MissingFromOpenAPI() = %q, want %q", missing, want)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package delivery
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"server/server/internal/domain/dto"
+	"server/server/internal/transcode"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//xmlRoot is a root element of XML responses, it matches Result
+const xmlRoot = "Result"
+
+//bodyEncoder writes bodies of responses in one representation
+type bodyEncoder interface {
+	//Encode writes the whole body
+	Encode(w io.Writer, body interface{}) error
+	//List starts a list of people which are written one by one
+	List(w io.Writer) listEncoder
+}
+
+//listEncoder writes people of a list one by one
+type listEncoder interface {
+	Write(person *dto.Person) error
+	Close() error
+}
+
+//format is a representation of people, the first media type is used as Content-Type
+type format struct {
+	name       string
+	mediaTypes []string
+	encoder    bodyEncoder
+}
+
+//formats are representations which clients choose by Accept header or format parameter.
+//The first one is used when client accepts anything
+var formats = []*format{
+	{"json", []string{"application/json"}, jsonEncoder{}},
+	{"ndjson", []string{ndjsonType}, ndjsonEncoder{}},
+	{"csv", []string{"text/csv"}, csvEncoder{}},
+	{"xml", []string{"application/xml", "text/xml"}, xmlEncoder{}},
+	{"yaml", []string{"application/yaml", "application/x-yaml", "text/yaml"}, yamlEncoder{}},
+	{"msgpack", []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}, msgpackEncoder{}},
+}
+
+//acceptRange is a media range of Accept header with its quality
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+//documentFormats are representations of bodies which are not tables of people, they can not be written as CSV
+var documentFormats = formatsExcept("csv")
+
+func formatsExcept(name string) []*format {
+	chosen := []*format{}
+	for _, f := range formats {
+		if f.name != name {
+			chosen = append(chosen, f)
+		}
+	}
+	return chosen
+}
+
+//negotiate chooses format of people by format parameter or Accept header
+//and answers 406 if none of formats is acceptable
+func (handler *PersonHandler) negotiate(w http.ResponseWriter, r *http.Request) (*format, bool) {
+	return handler.negotiateFormats(w, r, formats, "format")
+}
+
+//negotiateDocument chooses format of bodies which are not people like negotiate does
+func (handler *PersonHandler) negotiateDocument(w http.ResponseWriter, r *http.Request) (*format, bool) {
+	return handler.negotiateFormats(w, r, documentFormats, "format")
+}
+
+//negotiateFormats chooses one of candidates by the query parameter or Accept header,
+//the parameter is not read if it is empty
+func (handler *PersonHandler) negotiateFormats(w http.ResponseWriter, r *http.Request, candidates []*format, param string) (*format, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	if name := r.URL.Query().Get(param); param != "" && name != "" {
+		for _, f := range candidates {
+			if f.name == name {
+				return f, true
+			}
+		}
+		handler.writeProblem(w, r, http.StatusNotAcceptable, "problems with parameters", errors.New("format is not supported"))
+		return nil, false
+	}
+
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return candidates[0], true
+	}
+	for _, accepted := range parseAccept(header) {
+		for _, f := range candidates {
+			for _, mediaType := range f.mediaTypes {
+				if matchMediaRange(accepted.mediaType, mediaType) {
+					return f, true
+				}
+			}
+		}
+	}
+
+	handler.writeProblem(w, r, http.StatusNotAcceptable, "problems with parameters", errors.New("none of accepted media types is supported"))
+	return nil, false
+}
+
+//parseAccept gets acceptable media ranges from the most preferred one, ranges with zero quality are skipped
+func parseAccept(header string) []*acceptRange {
+	ranges := []*acceptRange{}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if strQuality, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(strQuality, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		ranges = append(ranges, &acceptRange{mediaType: mediaType, quality: quality})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+func matchMediaRange(mediaRange string, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+}
+
+//writeBody writes body in the chosen format with the status
+func (handler *PersonHandler) writeBody(w http.ResponseWriter, r *http.Request, f *format, status int, body interface{}) {
+	w.Header().Set("Content-Type", f.mediaTypes[0])
+	w.WriteHeader(status)
+	err := f.encoder.Encode(w, body)
+	if err != nil {
+		handler.writeError(w, r, "problems with encoding body", err)
+		return
+	}
+}
+
+//writeList writes people in the chosen format
+func (handler *PersonHandler) writeList(w http.ResponseWriter, r *http.Request, f *format, persons []*dto.Person) {
+	stream := newPersonStream(w, f)
+	for _, person := range persons {
+		err := stream.Write(person)
+		if err != nil {
+			handler.logger.LogError("problems with writing people", err, w.Header().Get("request-id"), r.URL.Path)
+			return
+		}
+	}
+	err := stream.Close()
+	if err != nil {
+		handler.logger.LogError("problems with writing people", err, w.Header().Get("request-id"), r.URL.Path)
+		return
+	}
+}
+
+//marshalResult makes JSON of the body wrapped into Result, other formats are converted from it
+func marshalResult(body interface{}) ([]byte, error) {
+	return json.Marshal(&Result{Body: body})
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(w io.Writer, body interface{}) error {
+	return json.NewEncoder(w).Encode(&Result{Body: body})
+}
+
+func (jsonEncoder) List(w io.Writer) listEncoder {
+	return &jsonList{w: w, encoder: json.NewEncoder(w)}
+}
+
+//jsonList writes people as {"Body":[...]} like other lists
+type jsonList struct {
+	w       io.Writer
+	encoder *json.Encoder
+	started bool
+}
+
+func (list *jsonList) Write(person *dto.Person) error {
+	prefix := ","
+	if !list.started {
+		prefix = `{"Body":[`
+		list.started = true
+	}
+	_, err := io.WriteString(list.w, prefix)
+	if err != nil {
+		return err
+	}
+	return list.encoder.Encode(person)
+}
+
+func (list *jsonList) Close() error {
+	if !list.started {
+		_, err := io.WriteString(list.w, `{"Body":[]}`+"\n")
+		return err
+	}
+	_, err := io.WriteString(list.w, "]}\n")
+	return err
+}
+
+//ndjsonEncoder writes one JSON value per line without Result wrapper
+type ndjsonEncoder struct{}
+
+func (ndjsonEncoder) Encode(w io.Writer, body interface{}) error {
+	return json.NewEncoder(w).Encode(body)
+}
+
+func (ndjsonEncoder) List(w io.Writer) listEncoder {
+	return &ndjsonList{encoder: json.NewEncoder(w)}
+}
+
+type ndjsonList struct {
+	encoder *json.Encoder
+}
+
+func (list *ndjsonList) Write(person *dto.Person) error {
+	return list.encoder.Encode(person)
+}
+
+func (list *ndjsonList) Close() error {
+	return nil
+}
+
+//csvEncoder writes people as CSV with header row, search results are written as found people
+//and groups of aggregation are written as their own table
+type csvEncoder struct{}
+
+func (csvEncoder) Encode(w io.Writer, body interface{}) error {
+	persons := []*dto.Person{}
+	switch value := body.(type) {
+	case *dto.AggregateResult:
+		return writeAggregateCSV(w, value)
+	case *dto.Person:
+		persons = append(persons, value)
+	case []*dto.Person:
+		persons = value
+	case *dto.SearchResult:
+		persons = value.People
+	default:
+		return errors.New("body can not be written as CSV")
+	}
+
+	list := csvEncoder{}.List(w)
+	for _, person := range persons {
+		err := list.Write(person)
+		if err != nil {
+			return err
+		}
+	}
+	return list.Close()
+}
+
+func (csvEncoder) List(w io.Writer) listEncoder {
+	return newPersonCSV(w)
+}
+
+//xmlEncoder writes bodies as XML converted from JSON, items of lists are item elements
+type xmlEncoder struct{}
+
+func (xmlEncoder) Encode(w io.Writer, body interface{}) error {
+	data, err := marshalResult(body)
+	if err != nil {
+		return err
+	}
+	return transcode.XML(w, xmlRoot, data)
+}
+
+func (xmlEncoder) List(w io.Writer) listEncoder {
+	return &xmlList{w: w}
+}
+
+type xmlList struct {
+	w       io.Writer
+	started bool
+}
+
+func (list *xmlList) Write(person *dto.Person) error {
+	if !list.started {
+		list.started = true
+		_, err := io.WriteString(list.w, xml.Header+"<"+xmlRoot+"><Body>")
+		if err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(person)
+	if err != nil {
+		return err
+	}
+	return transcode.XMLElement(list.w, "item", data)
+}
+
+func (list *xmlList) Close() error {
+	if !list.started {
+		_, err := io.WriteString(list.w, xml.Header+"<"+xmlRoot+"><Body></Body></"+xmlRoot+">")
+		return err
+	}
+	_, err := io.WriteString(list.w, "</Body></"+xmlRoot+">")
+	return err
+}
+
+//yamlEncoder writes bodies as YAML converted from JSON
+type yamlEncoder struct{}
+
+func (yamlEncoder) Encode(w io.Writer, body interface{}) error {
+	data, err := marshalResult(body)
+	if err != nil {
+		return err
+	}
+	return transcode.YAML(w, data)
+}
+
+func (yamlEncoder) List(w io.Writer) listEncoder {
+	return &yamlList{w: w}
+}
+
+type yamlList struct {
+	w       io.Writer
+	started bool
+}
+
+func (list *yamlList) Write(person *dto.Person) error {
+	if !list.started {
+		list.started = true
+		_, err := io.WriteString(list.w, "Body:\n")
+		if err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(person)
+	if err != nil {
+		return err
+	}
+	return transcode.YAMLItem(list.w, data, 2)
+}
+
+func (list *yamlList) Close() error {
+	if !list.started {
+		_, err := io.WriteString(list.w, "Body: []\n")
+		return err
+	}
+	return nil
+}
+
+//msgpackEncoder writes bodies as MessagePack converted from JSON. Length of MessagePack arrays
+//is written before items, so lists are collected before they are written
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Encode(w io.Writer, body interface{}) error {
+	data, err := marshalResult(body)
+	if err != nil {
+		return err
+	}
+	return transcode.MsgPack(w, data)
+}
+
+func (msgpackEncoder) List(w io.Writer) listEncoder {
+	return &msgpackList{w: w, persons: []*dto.Person{}}
+}
+
+type msgpackList struct {
+	w       io.Writer
+	persons []*dto.Person
+}
+
+func (list *msgpackList) Write(person *dto.Person) error {
+	list.persons = append(list.persons, person)
+	return nil
+}
+
+func (list *msgpackList) Close() error {
+	return msgpackEncoder{}.Encode(list.w, list.persons)
+}
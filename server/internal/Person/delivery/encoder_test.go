@@ -0,0 +1,98 @@
+package delivery
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	memoryRep "server/server/internal/Person/repository/memory"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateDocuments(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	_, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{}))
+
+	tests := []struct {
+		name            string
+		target          string
+		accept          string
+		wantStatus      int
+		wantContentType string
+	}{
+		{"json by default", "/api/people/stats", "", http.StatusOK, "application/json"},
+		{"yaml by Accept", "/api/people/stats", "application/yaml", http.StatusOK, "application/yaml"},
+		{"xml by parameter", "/api/people/1/history?format=xml", "application/json", http.StatusOK, "application/xml"},
+		{"msgpack of changes", "/api/changes", "application/msgpack", http.StatusOK, "application/msgpack"},
+		//statistics are not a table of people
+		{"csv of stats", "/api/people/stats?format=csv", "", http.StatusNotAcceptable, problemType},
+		{"csv by Accept", "/api/duplicates", "text/csv", http.StatusNotAcceptable, problemType},
+		{"aggregate as csv", "/api/people/aggregate?group_by=nation&metrics=count&format=csv", "", http.StatusOK, "text/csv"},
+		{"aggregate as yaml", "/api/people/aggregate?group_by=nation&metrics=count", "application/yaml", http.StatusOK, "application/yaml"},
+		{"unknown format of aggregate", "/api/people/aggregate?group_by=nation&metrics=count&format=pdf", "", http.StatusNotAcceptable, problemType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestNegotiateChanges(t *testing.T) {
+	router := newTestRouter(personUsecase.NewPersonUsecase(memoryRep.NewPersonRepo(), &dto.DuplicateRules{}))
+
+	//the answer is negotiated before anything is changed
+	req := httptest.NewRequest(http.MethodPost, "/api/people", strings.NewReader(`{"name":"Ivan","surname":"Petrov"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("creation status = %d, want %d", rec.Code, http.StatusNotAcceptable)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/people/batch?format=yaml", strings.NewReader(`{"operations":[{"op":"delete","id":1}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMultiStatus || rec.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("batch answered %d with %q, want %d with YAML", rec.Code, rec.Header().Get("Content-Type"), http.StatusMultiStatus)
+	}
+
+	//format parameter of import is the format of file
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	file, err := form.CreateFormFile("file", "people.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("name,surname\n"))
+	form.Close()
+	req = httptest.NewRequest(http.MethodPost, "/api/people/import?format=csv&dry_run=true", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/xml")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("import answered %d with %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"server/server/internal/domain/dto"
+	"strconv"
+	"strings"
+)
+
+//etag makes ETag header value from version of person
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+//entityTag is an entity-tag of conditional request without its quotes
+type entityTag struct {
+	opaque string
+	weak   bool
+}
+
+//parseETags parses comma-separated list of entity-tags of RFC 9110, empty elements of the list are skipped
+func parseETags(header string) ([]entityTag, bool) {
+	tags := []entityTag{}
+	rest := strings.Trim(header, " \t,")
+	for rest != "" {
+		tag := entityTag{}
+		if strings.HasPrefix(rest, "W/") {
+			tag.weak = true
+			rest = rest[2:]
+		}
+		if rest == "" || rest[0] != '"' {
+			return nil, false
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		tag.opaque = rest[1 : end+1]
+		tags = append(tags, tag)
+
+		rest = strings.TrimLeft(rest[end+2:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, false
+		}
+		rest = strings.TrimLeft(rest, " \t,")
+	}
+	return tags, true
+}
+
+//parseIfMatch gets versions listed in If-Match header, any is set for missing header and "*".
+//Weak and unknown tags never match because If-Match uses strong comparison, so they are skipped
+func parseIfMatch(header string) (versions []uint, any bool, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true, true
+	}
+
+	tags, ok := parseETags(header)
+	if !ok || len(tags) == 0 {
+		return nil, false, false
+	}
+	for _, tag := range tags {
+		version, err := strconv.ParseUint(tag.opaque, 10, 64)
+		if tag.weak || err != nil || version == 0 {
+			continue
+		}
+		versions = append(versions, uint(version))
+	}
+	return versions, false, true
+}
+
+//matchIfNoneMatch reports whether If-None-Match header matches the tag.
+//If-None-Match uses weak comparison, so W/ prefix is ignored
+func matchIfNoneMatch(header string, tag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	tags, _ := parseETags(header)
+	for _, candidate := range tags {
+		if `"`+candidate.opaque+`"` == tag {
+			return true
+		}
+	}
+	return false
+}
+
+//ifMatchVersion gets version required by If-Match header, zero means any version.
+//A list of versions is narrowed to the current version of person, usecase checks it again in its transaction.
+//Problem is written when the header is broken or does not match
+func (handler *PersonHandler) ifMatchVersion(w http.ResponseWriter, r *http.Request, id uint) (uint, bool) {
+	versions, any, ok := parseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		handler.writeProblem(w, r, http.StatusPreconditionFailed, "precondition failed", errors.New("bad If-Match header"))
+		return 0, false
+	}
+	if any {
+		return 0, true
+	}
+	if len(versions) == 1 {
+		return versions[0], true
+	}
+
+	if len(versions) > 1 {
+		person, err := handler.persons.GetPerson(id)
+		if err != nil {
+			handler.writeError(w, r, "problems getting person", err)
+			return 0, false
+		}
+		for _, version := range versions {
+			if version == person.Version {
+				return version, true
+			}
+		}
+	}
+	handler.writeError(w, r, "precondition failed", dto.ErrVersionMismatch)
+	return 0, false
+}
@@ -0,0 +1,149 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	memoryRep "server/server/internal/Person/repository/memory"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	"strings"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header       string
+		wantVersions []uint
+		wantAny      bool
+		wantOk       bool
+	}{
+		{"", nil, true, true},
+		{"*", nil, true, true},
+		{` "3" `, []uint{3}, false, true},
+		{`"12"`, []uint{12}, false, true},
+		{`"1", "3"`, []uint{1, 3}, false, true},
+		{`"1",,"3",`, []uint{1, 3}, false, true},
+		//weak tags never match strongly
+		{`W/"3"`, nil, false, true},
+		{`W/"2", "3"`, []uint{3}, false, true},
+		{`"0"`, nil, false, true},
+		{`"abc", "4"`, []uint{4}, false, true},
+		{`"a,b", "4"`, []uint{4}, false, true},
+		{`3`, nil, false, false},
+		{`"`, nil, false, false},
+		{`"3" "4"`, nil, false, false},
+		{`,`, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			versions, any, ok := parseIfMatch(tt.header)
+			if fmt.Sprint(versions) != fmt.Sprint(tt.wantVersions) || any != tt.wantAny || ok != tt.wantOk {
+				t.Errorf("parseIfMatch(%q) = %v, %v, %v, want %v, %v, %v", tt.header, versions, any, ok, tt.wantVersions, tt.wantAny, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestMatchIfNoneMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"3"`, true},
+		{`W/"3"`, true},
+		{`"1", "3"`, true},
+		{`W/"1", W/"3"`, true},
+		{`"1,3"`, false},
+		{`*`, true},
+		{`"4"`, false},
+		{``, false},
+		{`3`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := matchIfNoneMatch(tt.header, etag(3)); got != tt.want {
+				t.Errorf("matchIfNoneMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPersonConditional(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	_, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{}))
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{"get", http.MethodGet, "", http.StatusOK},
+		{"head", http.MethodHead, "", http.StatusOK},
+		{"same version", http.MethodGet, `"1"`, http.StatusNotModified},
+		{"weak tag", http.MethodGet, `W/"1"`, http.StatusNotModified},
+		{"other version", http.MethodGet, `"2"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/people/1", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("ETag"); got != `"1"` {
+				t.Errorf("ETag = %q, want %q", got, `"1"`)
+			}
+			if rec.Header().Get("Last-Modified") == "" {
+				t.Error("Last-Modified is missing")
+			}
+		})
+	}
+}
+
+func TestIfMatchList(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	_, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{}))
+
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+		wantETag   string
+	}{
+		{"other versions", `"2", "3"`, http.StatusPreconditionFailed, ""},
+		{"weak current version", `W/"1"`, http.StatusPreconditionFailed, ""},
+		{"current version in list", `"3", W/"2", "1"`, http.StatusOK, `"2"`},
+		{"any version", `*`, http.StatusOK, `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/people/1", strings.NewReader(`{"age":31}`))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req.Header.Set("If-Match", tt.ifMatch)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
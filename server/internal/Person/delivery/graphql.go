@@ -0,0 +1,626 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	"server/server/internal/graphqllimit"
+	mw "server/server/internal/middleware"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+//Sizes of pages of people connection
+const (
+	defaultGraphQLPage = 20
+	maxGraphQLPage     = 100
+)
+
+type graphQLRequestKey struct{}
+
+//graphQLRequest is data of HTTP request used by resolvers
+type graphQLRequest struct {
+	change    *dto.ChangeInfo
+	requestID string
+	path      string
+}
+
+//graphQLQuery is a body of GraphQL request
+type graphQLQuery struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+//graphQLResponse is an answer of GraphQL request, rejected requests have no data
+type graphQLResponse struct {
+	Data   interface{}                `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+//graphQLError is an error of resolver with a code in extensions
+type graphQLError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (err *graphQLError) Error() string {
+	return err.message
+}
+
+func (err *graphQLError) Extensions() map[string]interface{} {
+	return err.extensions
+}
+
+//personConnection is a page of people, people passing filter are counted only when totalCount is asked
+type personConnection struct {
+	persons     []*dto.Person
+	hasNextPage bool
+	filter      *dto.PersonFilter
+}
+
+//GraphQLHandler handles GraphQL requests over people
+type GraphQLHandler struct {
+	persons personUsecase.PersonUsecaseI
+	logger  *mw.ACLog
+	schema  graphql.Schema
+	limits  *graphqllimit.Limits
+}
+
+//NewGraphQLHandler creates new GraphQL handler, zero limits disable limits of operations
+func NewGraphQLHandler(persons personUsecase.PersonUsecaseI, logger *mw.ACLog, maxDepth int, maxComplexity int) *GraphQLHandler {
+	handler := &GraphQLHandler{
+		persons: persons,
+		logger:  logger,
+	}
+	schema, err := handler.newSchema()
+	if err != nil {
+		//schema is static, so it is broken only by programming errors
+		panic(err)
+	}
+	handler.schema = schema
+	handler.limits = &graphqllimit.Limits{
+		MaxDepth:      maxDepth,
+		MaxComplexity: maxComplexity,
+		Multipliers:   map[string]func(args map[string]interface{}) int{"Query.people": peopleMultiplier},
+	}
+	return handler
+}
+
+//RegisterHandler registers GraphQL endpoint
+func (handler *GraphQLHandler) RegisterHandler(router *mux.Router) {
+	router.HandleFunc("/graphql", handler.Query).Methods(http.MethodGet, http.MethodPost)
+}
+
+//Query executes GraphQL request from JSON body or from query parameters of GET request.
+//Mutations are not executed by GET requests, operations over limits are not executed at all
+func (handler *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request := &graphQLQuery{}
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if strVariables := query.Get("variables"); strVariables != "" {
+			err := json.Unmarshal([]byte(strVariables), &request.Variables)
+			if err != nil {
+				handler.writeRequestError(w, r, http.StatusBadRequest, errors.New("variables are not JSON object"))
+				return
+			}
+		}
+	} else {
+		err := json.NewDecoder(r.Body).Decode(request)
+		if err != nil {
+			handler.writeRequestError(w, r, http.StatusBadRequest, errors.New("body is not GraphQL request"))
+			return
+		}
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		handler.writeRequestError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method == http.MethodGet && hasMutation(doc, request.OperationName) {
+		w.Header().Set("Allow", http.MethodPost)
+		handler.writeRequestError(w, r, http.StatusMethodNotAllowed, errors.New("mutations are executed only by POST requests"))
+		return
+	}
+
+	//limits are measured on valid documents only. Cycles of fragments are rejected before other rules,
+	//because the rule of overlapping fields of graphql-go recurses over them endlessly
+	validation := graphql.ValidateDocument(&handler.schema, doc, []graphql.ValidationRuleFn{graphql.NoFragmentCyclesRule})
+	if validation.IsValid {
+		validation = graphql.ValidateDocument(&handler.schema, doc, nil)
+	}
+	if !validation.IsValid {
+		handler.writeResponse(w, r, http.StatusBadRequest, &graphQLResponse{Errors: validation.Errors})
+		return
+	}
+	err = handler.limits.Check(&handler.schema, doc, request.OperationName, request.Variables)
+	if err != nil {
+		handler.writeRequestError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), graphQLRequestKey{}, &graphQLRequest{
+		change:    changeInfo(w, r),
+		requestID: w.Header().Get("request-id"),
+		path:      r.URL.Path,
+	})
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        handler.schema,
+		AST:           doc,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       ctx,
+	})
+	//errors without data are errors of request like missing variables
+	status := http.StatusOK
+	if result.Data == nil && len(result.Errors) != 0 {
+		status = http.StatusBadRequest
+	}
+	handler.writeResponse(w, r, status, &graphQLResponse{Data: result.Data, Errors: result.Errors})
+}
+
+//hasMutation checks that the executed operation of document is a mutation
+func hasMutation(doc *ast.Document, operationName string) bool {
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok || operation.Operation != ast.OperationTypeMutation {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return true
+		}
+	}
+	return false
+}
+
+//writeRequestError writes the error of request, codes of limits are kept in extensions
+func (handler *GraphQLHandler) writeRequestError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	handler.writeResponse(w, r, status, &graphQLResponse{Errors: []gqlerrors.FormattedError{formatted}})
+}
+
+func (handler *GraphQLHandler) writeResponse(w http.ResponseWriter, r *http.Request, status int, response *graphQLResponse) {
+	if status != http.StatusOK {
+		handler.logger.LogError("invalid GraphQL request", errors.New(response.Errors[0].Message), w.Header().Get("request-id"), r.URL.Path)
+	}
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		handler.logger.LogError("problems with marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
+	}
+}
+
+//resolverError converts errors of usecase to GraphQL errors with codes taken from problem types.
+//Internal errors are logged and hidden from clients
+func (handler *GraphQLHandler) resolverError(ctx context.Context, err error) error {
+	problem := problemFor(err)
+	if problem.Status >= http.StatusInternalServerError {
+		request, _ := ctx.Value(graphQLRequestKey{}).(*graphQLRequest)
+		if request != nil {
+			handler.logger.LogError("problems with resolving GraphQL field", err, request.requestID, request.path)
+		}
+		return &graphQLError{message: "internal error", extensions: map[string]interface{}{"code": "INTERNAL"}}
+	}
+
+	code := strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(problem.Type, "/problems/"), "-", "_"))
+	if problem.Type == "about:blank" {
+		code = "BAD_REQUEST"
+	}
+	extensions := map[string]interface{}{"code": code}
+	if len(problem.Errors) != 0 {
+		extensions["fieldErrors"] = problem.Errors
+	}
+	if len(problem.Candidates) != 0 {
+		ids := []string{}
+		for _, candidate := range problem.Candidates {
+			ids = append(ids, strconv.FormatUint(uint64(candidate.Person.ID), 10))
+		}
+		extensions["duplicateIds"] = ids
+	}
+	return &graphQLError{message: problem.Detail, extensions: extensions}
+}
+
+func badInput(message string) error {
+	return &graphQLError{message: message, extensions: map[string]interface{}{"code": "BAD_REQUEST"}}
+}
+
+func (handler *GraphQLHandler) newSchema() (graphql.Schema, error) {
+	personType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Person",
+		Fields: graphql.Fields{
+			"id":         personField(graphql.NewNonNull(graphql.ID), func(person *dto.Person) interface{} { return strconv.FormatUint(uint64(person.ID), 10) }),
+			"name":       personField(graphql.NewNonNull(graphql.String), func(person *dto.Person) interface{} { return person.Name }),
+			"surname":    personField(graphql.NewNonNull(graphql.String), func(person *dto.Person) interface{} { return person.Surname }),
+			"patronymic": personField(graphql.String, func(person *dto.Person) interface{} { return person.Patronymic }),
+			"age":        personField(graphql.Int, func(person *dto.Person) interface{} { return person.Age }),
+			"gender":     personField(graphql.String, func(person *dto.Person) interface{} { return person.Gender }),
+			"nation":     personField(graphql.String, func(person *dto.Person) interface{} { return person.Nation }),
+			"version":    personField(graphql.NewNonNull(graphql.Int), func(person *dto.Person) interface{} { return person.Version }),
+			"createdAt":  personField(graphql.NewNonNull(graphql.String), func(person *dto.Person) interface{} { return graphQLTime(&person.CreatedAt) }),
+			"updatedAt":  personField(graphql.String, func(person *dto.Person) interface{} { return graphQLTime(person.UpdatedAt) }),
+			"deletedAt":  personField(graphql.String, func(person *dto.Person) interface{} { return graphQLTime(person.DeletedAt) }),
+		},
+	})
+
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PersonEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return personCursor(p.Source.(*dto.Person).ID), nil
+			}},
+			"node": &graphql.Field{Type: graphql.NewNonNull(personType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			}},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*personConnection).hasNextPage, nil
+			}},
+			"endCursor": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				connection := p.Source.(*personConnection)
+				if len(connection.persons) == 0 {
+					return nil, nil
+				}
+				return personCursor(connection.persons[len(connection.persons)-1].ID), nil
+			}},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PersonConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(edgeType)), Resolve: connectionPersons},
+			"nodes": &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(personType)), Resolve: connectionPersons},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			}},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: handler.resolveTotalCount},
+		},
+	})
+
+	filterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PersonFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"includeDeleted": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"createdAfter":   &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "RFC 3339 timestamp"},
+			"updatedSince":   &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "RFC 3339 timestamp"},
+			"name":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"surname":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"gender":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"nation":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"minAge":         &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"maxAge":         &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	createInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreatePersonInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"surname":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"patronymic": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	updateInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdatePersonInput",
+		Description: "Missing fields are kept, empty patronymic clears it",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"surname":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"patronymic": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"age":        &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"gender":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"nation":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"person": &graphql.Field{
+				Type:    personType,
+				Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: handler.resolvePerson,
+			},
+			"people": &graphql.Field{
+				Type: connectionType,
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: filterType},
+					"first":  {Type: graphql.Int, DefaultValue: defaultGraphQLPage},
+					"after":  {Type: graphql.String},
+				},
+				Resolve: handler.resolvePeople,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createPerson": &graphql.Field{
+				Type: personType,
+				Args: graphql.FieldConfigArgument{
+					"input": {Type: graphql.NewNonNull(createInputType)},
+					"force": {Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: handler.resolveCreatePerson,
+			},
+			"updatePerson": &graphql.Field{
+				Type: personType,
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"input":   {Type: graphql.NewNonNull(updateInputType)},
+					"version": {Type: graphql.Int},
+				},
+				Resolve: handler.resolveUpdatePerson,
+			},
+			"deletePerson": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"version": {Type: graphql.Int},
+				},
+				Resolve: handler.resolveDeletePerson,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+//peopleMultiplier tells how many people a page may have, pages over maximum are measured as the maximum page
+func peopleMultiplier(args map[string]interface{}) int {
+	value, ok := args["first"]
+	if !ok {
+		return defaultGraphQLPage
+	}
+	first, ok := value.(int64)
+	if !ok || first < 1 || first > maxGraphQLPage {
+		return maxGraphQLPage
+	}
+	return int(first)
+}
+
+func personField(fieldType graphql.Output, get func(person *dto.Person) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: fieldType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*dto.Person)), nil
+		},
+	}
+}
+
+func connectionPersons(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*personConnection).persons, nil
+}
+
+//graphQLTime formats time like JSON does, nil time is null
+func graphQLTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func personID(value interface{}) (uint, error) {
+	strID, _ := value.(string)
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		return 0, badInput("id is not number")
+	}
+	return uint(id), nil
+}
+
+//optionalVersion gets expected version of person, zero means any version
+func optionalVersion(args map[string]interface{}) (uint, error) {
+	value, ok := args["version"]
+	if !ok || value == nil {
+		return 0, nil
+	}
+	version, _ := value.(int)
+	if version < 1 {
+		return 0, badInput("version is not positive Int")
+	}
+	return uint(version), nil
+}
+
+func changeFromContext(ctx context.Context) *dto.ChangeInfo {
+	request, _ := ctx.Value(graphQLRequestKey{}).(*graphQLRequest)
+	if request == nil {
+		return &dto.ChangeInfo{Actor: dto.SystemActor}
+	}
+	return request.change
+}
+
+//resolvePerson gets person by id, missing and deleted people are null
+func (handler *GraphQLHandler) resolvePerson(p graphql.ResolveParams) (interface{}, error) {
+	id, err := personID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	person, err := handler.persons.GetPerson(id)
+	if err != nil {
+		if err == dto.ErrNotFound || err == dto.ErrGone {
+			return nil, nil
+		}
+		return nil, handler.resolverError(p.Context, err)
+	}
+	return person, nil
+}
+
+//resolvePeople gets a page of people ordered by id, filters are combined with AND.
+//The page is read with one more person which tells that the next page exists
+func (handler *GraphQLHandler) resolvePeople(p graphql.ResolveParams) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 0 || first > maxGraphQLPage {
+		return nil, badInput("first must be between 0 and " + strconv.Itoa(maxGraphQLPage))
+	}
+
+	filter, err := graphQLFilter(p.Context, p.Args["filter"])
+	if err != nil {
+		return nil, err
+	}
+
+	page := *filter
+	if after, ok := p.Args["after"].(string); ok {
+		page.After, err = parsePersonCursor(after)
+		if err != nil {
+			return nil, badInput(err.Error())
+		}
+	}
+
+	connection := &personConnection{persons: []*dto.Person{}, filter: filter}
+	if first == 0 {
+		return connection, nil
+	}
+	page.Limit = uint(first) + 1
+	persons, err := handler.persons.GetPersons(&page)
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	if len(persons) > first {
+		persons = persons[:first]
+		connection.hasNextPage = true
+	}
+	connection.persons = persons
+	return connection, nil
+}
+
+//resolveTotalCount counts all people passing filter of the connection regardless of the page
+func (handler *GraphQLHandler) resolveTotalCount(p graphql.ResolveParams) (interface{}, error) {
+	total, err := handler.persons.CountPersons(p.Source.(*personConnection).filter)
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	return total, nil
+}
+
+//graphQLFilter converts PersonFilter input to filter of people
+func graphQLFilter(ctx context.Context, value interface{}) (*dto.PersonFilter, error) {
+	filter := &dto.PersonFilter{}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return filter, nil
+	}
+
+	if include, _ := fields["includeDeleted"].(bool); include {
+		if !mw.IsAdminContext(ctx) {
+			return nil, &graphQLError{message: "includeDeleted is available only for admins", extensions: map[string]interface{}{"code": "FORBIDDEN"}}
+		}
+		filter.IncludeDeleted = true
+	}
+	for name, field := range map[string]**time.Time{"createdAfter": &filter.CreatedAfter, "updatedSince": &filter.UpdatedSince} {
+		strTime, ok := fields[name].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, strTime)
+		if err != nil {
+			return nil, badInput("filter." + name + " is not RFC 3339 timestamp")
+		}
+		*field = &t
+	}
+	filter.Name, _ = fields["name"].(string)
+	filter.Surname, _ = fields["surname"].(string)
+	filter.Gender, _ = fields["gender"].(string)
+	filter.Nation, _ = fields["nation"].(string)
+	for name, field := range map[string]**uint{"minAge": &filter.MinAge, "maxAge": &filter.MaxAge} {
+		age, ok := fields[name].(int)
+		if !ok {
+			continue
+		}
+		if age < 0 {
+			return nil, badInput("filter." + name + " is not positive Int")
+		}
+		uintAge := uint(age)
+		*field = &uintAge
+	}
+	return filter, nil
+}
+
+func (handler *GraphQLHandler) resolveCreatePerson(p graphql.ResolveParams) (interface{}, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+	force, _ := p.Args["force"].(bool)
+
+	newPerson := &dto.Person{}
+	newPerson.Name, _ = input["name"].(string)
+	newPerson.Surname, _ = input["surname"].(string)
+	newPerson.Patronymic, _ = input["patronymic"].(string)
+
+	id, err := handler.persons.CreatePerson(p.Context, newPerson, force, changeFromContext(p.Context))
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	person, err := handler.persons.GetPerson(id)
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	return person, nil
+}
+
+//resolveUpdatePerson applies input as JSON Merge Patch, GraphQL has no null literals here,
+//so empty patronymic clears it like gRPC updates do
+func (handler *GraphQLHandler) resolveUpdatePerson(p graphql.ResolveParams) (interface{}, error) {
+	id, err := personID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	version, err := optionalVersion(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(p.Args["input"])
+	if err != nil {
+		return nil, badInput("input is not UpdatePersonInput")
+	}
+
+	_, err = handler.persons.PatchPerson(id, &dto.Patch{Kind: dto.PatchMerge, Body: body}, version, changeFromContext(p.Context))
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	person, err := handler.persons.GetPerson(id)
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	return person, nil
+}
+
+func (handler *GraphQLHandler) resolveDeletePerson(p graphql.ResolveParams) (interface{}, error) {
+	id, err := personID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	version, err := optionalVersion(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.persons.DeletePerson(id, version, changeFromContext(p.Context))
+	if err != nil {
+		return nil, handler.resolverError(p.Context, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,156 @@
+package delivery
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	memoryRep "server/server/internal/Person/repository/memory"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	mw "server/server/internal/middleware"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func TestGraphQLLimits(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	_, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	persons := personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{})
+	logger := mw.NewACLog(zap.NewNop().Sugar(), zap.NewNop().Sugar())
+	router := mux.NewRouter()
+	NewGraphQLHandler(persons, logger, 3, 500).RegisterHandler(router)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"within limits", `{people(first: 10){totalCount nodes{id name}}}`, http.StatusOK, ""},
+		{"depth at limit", `{person(id: "1"){id name}}`, http.StatusOK, ""},
+		{"depth over limit", `{people{edges{node{id}}}}`, http.StatusBadRequest, "DEPTH_LIMIT"},
+		{"depth of fragment", `{people{...edges}} fragment edges on PersonConnection{edges{node{id}}}`, http.StatusBadRequest, "DEPTH_LIMIT"},
+		//every field of the page is resolved for each of 100 people: 1 + (1 + 11)*100
+		{"complexity of page", `{people(first: 100){nodes{id name surname patronymic age gender nation version createdAt updatedAt deletedAt}}}`,
+			http.StatusBadRequest, "COMPLEXITY_LIMIT"},
+		//page over maximum costs like the maximum page: 1 + (1 + 5)*100
+		{"complexity of too big page", `{people(first: 1000000){nodes{id name surname patronymic age}}}`, http.StatusBadRequest, "COMPLEXITY_LIMIT"},
+		{"complexity within limit", `{people(first: 100){nodes{id name surname}}}`, http.StatusOK, ""},
+		{"complexity of aliases", `{a: people(first: 100){nodes{id name}} b: people(first: 100){nodes{id name}} c: people(first: 100){nodes{id}}}`,
+			http.StatusBadRequest, "COMPLEXITY_LIMIT"},
+		{"cycle of fragments", `{person(id: "1"){...a}} fragment a on Person{...b} fragment b on Person{...a}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(&graphQLQuery{Query: tt.query})
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assertGraphQLResponse(t, rec, tt.wantStatus, tt.wantCode)
+		})
+	}
+}
+
+func TestGraphQLLimitsOfGet(t *testing.T) {
+	router := newTestRouter(nil)
+
+	//resolvers are not called for rejected operations, so nil usecase is never used
+	query := `{people(first: 100){nodes{id name surname patronymic age gender nation version createdAt updatedAt deletedAt}}}`
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assertGraphQLResponse(t, rec, http.StatusBadRequest, "COMPLEXITY_LIMIT")
+}
+
+//assertGraphQLResponse checks status and code of the only error, no errors are expected for empty code
+//of successful response
+func assertGraphQLResponse(t *testing.T, rec *httptest.ResponseRecorder, wantStatus int, wantCode string) {
+	t.Helper()
+	if rec.Code != wantStatus {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, wantStatus, rec.Body.String())
+	}
+	response := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if wantStatus == http.StatusOK {
+		if len(response.Errors) != 0 {
+			t.Errorf("errors = %+v, want none", response.Errors)
+		}
+		return
+	}
+	if len(response.Errors) != 1 || response.Data != nil {
+		t.Fatalf("body %s, want one error without data", rec.Body.String())
+	}
+	if wantCode != "" && response.Errors[0].Extensions["code"] != wantCode {
+		t.Errorf("error %q has code %v, want %s", response.Errors[0].Message, response.Errors[0].Extensions["code"], wantCode)
+	}
+}
+
+func TestGraphQLPeople(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	for _, name := range []string{"Ivan", "Petr", "Anna"} {
+		_, err := repo.CreatePerson(&dto.DBGetPerson{Name: name, Surname: "Petrov", Patronymic: sql.NullString{String: "Ivanovich", Valid: true}, Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := newTestRouter(personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{}))
+	execute := func(query string, variables map[string]interface{}) string {
+		body, _ := json.Marshal(&graphQLQuery{Query: query, Variables: variables})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	page := `query($after: String){people(first: 2, after: $after){totalCount nodes{id name} pageInfo{hasNextPage endCursor}}}`
+	got := execute(page, nil)
+	want := `{"data":{"people":{"nodes":[{"id":"1","name":"Ivan"},{"id":"2","name":"Petr"}],"pageInfo":{"endCursor":"` +
+		personCursor(2) + `","hasNextPage":true},"totalCount":3}}}`
+	if got != want {
+		t.Errorf("first page = %s, want %s", got, want)
+	}
+	got = execute(page, map[string]interface{}{"after": personCursor(2)})
+	want = `{"data":{"people":{"nodes":[{"id":"3","name":"Anna"}],"pageInfo":{"endCursor":"` +
+		personCursor(3) + `","hasNextPage":false},"totalCount":3}}}`
+	if got != want {
+		t.Errorf("last page = %s, want %s", got, want)
+	}
+
+	got = execute(`{people(filter: {name: "anna"}){totalCount nodes{id}}}`, nil)
+	if want := `{"data":{"people":{"nodes":[{"id":"3"}],"totalCount":1}}}`; got != want {
+		t.Errorf("filtered page = %s, want %s", got, want)
+	}
+
+	got = execute(`{people(filter: {includeDeleted: true}){totalCount}}`, nil)
+	if !strings.Contains(got, `"code":"FORBIDDEN"`) {
+		t.Errorf("deleted people for anonymous = %s, want FORBIDDEN error", got)
+	}
+
+	got = execute(`mutation{updatePerson(id: "1", input: {patronymic: ""}, version: 1){patronymic version}}`, nil)
+	if want := `{"data":{"updatePerson":{"patronymic":"","version":2}}}`; got != want {
+		t.Errorf("update = %s, want %s", got, want)
+	}
+	got = execute(`mutation{deletePerson(id: "1", version: 1)}`, nil)
+	if !strings.Contains(got, `"code":"VERSION_MISMATCH"`) {
+		t.Errorf("delete of old version = %s, want VERSION_MISMATCH error", got)
+	}
+}
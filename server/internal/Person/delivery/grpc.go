@@ -0,0 +1,364 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	personv1 "server/server/api/person/v1"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	mw "server/server/internal/middleware"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//Sizes of pages of gRPC lists
+const (
+	defaultGRPCPage = 20
+	maxGRPCPage     = 100
+)
+
+//watchInterval is a period of polling the change feed when Watch reached its end
+const watchInterval = time.Second
+
+//grpcErrorDomain is a domain of gRPC error details
+const grpcErrorDomain = "person.v1"
+
+//grpcCodes maps HTTP statuses of problems to gRPC codes
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusGone:                codes.NotFound,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+//grpcUpdateFields are paths of update mask, patronymic can be cleared
+var grpcUpdateFields = map[string]func(fields *personv1.PersonFields) interface{}{
+	"name":    func(fields *personv1.PersonFields) interface{} { return fields.Name },
+	"surname": func(fields *personv1.PersonFields) interface{} { return fields.Surname },
+	"patronymic": func(fields *personv1.PersonFields) interface{} {
+		if fields.Patronymic == "" {
+			return nil
+		}
+		return fields.Patronymic
+	},
+	"age":    func(fields *personv1.PersonFields) interface{} { return fields.Age },
+	"gender": func(fields *personv1.PersonFields) interface{} { return fields.Gender },
+	"nation": func(fields *personv1.PersonFields) interface{} { return fields.Nation },
+}
+
+//PersonGRPCServer serves people over gRPC
+type PersonGRPCServer struct {
+	personv1.UnimplementedPersonServiceServer
+	persons personUsecase.PersonUsecaseI
+	logger  *mw.ACLog
+}
+
+//NewPersonGRPCServer creates new object of PersonGRPCServer
+func NewPersonGRPCServer(persons personUsecase.PersonUsecaseI, logger *mw.ACLog) *PersonGRPCServer {
+	return &PersonGRPCServer{
+		persons: persons,
+		logger:  logger,
+	}
+}
+
+//RegisterHandler registers PersonService in gRPC server
+func (server *PersonGRPCServer) RegisterHandler(grpcServer *grpc.Server) {
+	personv1.RegisterPersonServiceServer(grpcServer, server)
+}
+
+//Get gets person by id, deleted people are not found
+func (server *PersonGRPCServer) Get(ctx context.Context, req *personv1.GetRequest) (*personv1.Person, error) {
+	person, err := server.persons.GetPerson(uint(req.Id))
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	return toProtoPerson(person), nil
+}
+
+//List gets a page of people ordered by id
+func (server *PersonGRPCServer) List(ctx context.Context, req *personv1.ListRequest) (*personv1.ListResponse, error) {
+	size := uint(req.PageSize)
+	if req.PageSize == 0 {
+		size = defaultGRPCPage
+	}
+	if req.PageSize < 0 || size > maxGRPCPage {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", maxGRPCPage)
+	}
+
+	filter, err := grpcFilter(ctx, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if req.PageToken != "" {
+		filter.After, err = parsePersonCursor(req.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "page_token is invalid")
+		}
+	}
+
+	total, err := server.persons.CountPersons(filter)
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	//one more person tells that the next page exists
+	filter.Limit = size + 1
+	persons, err := server.persons.GetPersons(filter)
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+
+	resp := &personv1.ListResponse{
+		Persons:   []*personv1.Person{},
+		TotalSize: int32(total),
+	}
+	if uint(len(persons)) > size {
+		persons = persons[:size]
+		resp.NextPageToken = personCursor(persons[len(persons)-1].ID)
+	}
+	for _, person := range persons {
+		resp.Persons = append(resp.Persons, toProtoPerson(person))
+	}
+	return resp, nil
+}
+
+//Create creates person, duplicates are reported unless force is set
+func (server *PersonGRPCServer) Create(ctx context.Context, req *personv1.CreateRequest) (*personv1.Person, error) {
+	newPerson := &dto.Person{
+		Name:       req.Name,
+		Surname:    req.Surname,
+		Patronymic: req.Patronymic,
+	}
+	id, err := server.persons.CreatePerson(ctx, newPerson, req.Force, grpcChange(ctx))
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	person, err := server.persons.GetPerson(id)
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	return toProtoPerson(person), nil
+}
+
+//Update applies fields listed in update mask as JSON Merge Patch
+func (server *PersonGRPCServer) Update(ctx context.Context, req *personv1.UpdateRequest) (*personv1.Person, error) {
+	if req.UpdateMask == nil || len(req.UpdateMask.Paths) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "update_mask is empty")
+	}
+	fields := req.Person
+	if fields == nil {
+		fields = &personv1.PersonFields{}
+	}
+
+	patch := map[string]interface{}{}
+	for _, path := range req.UpdateMask.Paths {
+		get, ok := grpcUpdateFields[path]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "update_mask: %s is not a field of person", path)
+		}
+		patch[path] = get(fields)
+	}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+
+	_, err = server.persons.PatchPerson(uint(req.Id), &dto.Patch{Kind: dto.PatchMerge, Body: body}, uint(req.Version), grpcChange(ctx))
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	person, err := server.persons.GetPerson(uint(req.Id))
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	return toProtoPerson(person), nil
+}
+
+//Delete soft-deletes person
+func (server *PersonGRPCServer) Delete(ctx context.Context, req *personv1.DeleteRequest) (*personv1.DeleteResponse, error) {
+	err := server.persons.DeletePerson(uint(req.Id), uint(req.Version), grpcChange(ctx))
+	if err != nil {
+		return nil, server.grpcError(ctx, err)
+	}
+	return &personv1.DeleteResponse{}, nil
+}
+
+//Watch sends events of the change feed and polls it for new events until client cancels the call
+func (server *PersonGRPCServer) Watch(req *personv1.WatchRequest, stream personv1.PersonService_WatchServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	cursor := req.Since
+	for {
+		feed, err := server.persons.GetChanges(cursor, 0)
+		if err != nil {
+			return server.grpcError(ctx, err)
+		}
+		for _, event := range feed.Events {
+			err = stream.Send(toProtoEvent(event))
+			if err != nil {
+				return err
+			}
+		}
+		cursor = feed.NextCursor
+		if feed.HasMore {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+//grpcError converts errors of usecase to gRPC statuses with codes taken from problem types.
+//Internal errors are logged and hidden from clients
+func (server *PersonGRPCServer) grpcError(ctx context.Context, err error) error {
+	problem := problemFor(err)
+	code, ok := grpcCodes[problem.Status]
+	if !ok {
+		code = codes.Unknown
+	}
+	if problem.Status >= http.StatusInternalServerError {
+		method, _ := grpc.Method(ctx)
+		server.logger.LogError("problems with gRPC call", err, mw.RequestID(ctx), method)
+		return status.Error(code, "internal error")
+	}
+
+	reason := strings.ToUpper(strings.ReplaceAll(strings.TrimPrefix(problem.Type, "/problems/"), "-", "_"))
+	if problem.Type == "about:blank" {
+		reason = "BAD_REQUEST"
+	}
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: grpcErrorDomain}
+	if len(problem.Candidates) != 0 {
+		ids := []string{}
+		for _, candidate := range problem.Candidates {
+			ids = append(ids, strconv.FormatUint(uint64(candidate.Person.ID), 10))
+		}
+		info.Metadata = map[string]string{"duplicate_ids": strings.Join(ids, ",")}
+	}
+
+	details := []protoadapt.MessageV1{info}
+	if len(problem.Errors) != 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range problem.Errors {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field,
+				Description: fieldErr.Code + ": " + fieldErr.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st, err := status.New(code, problem.Detail).WithDetails(details...)
+	if err != nil {
+		return status.Error(code, problem.Detail)
+	}
+	return st.Err()
+}
+
+//grpcFilter converts filter of people, only admins see deleted people
+func grpcFilter(ctx context.Context, req *personv1.PersonFilter) (*dto.PersonFilter, error) {
+	filter := &dto.PersonFilter{}
+	if req == nil {
+		return filter, nil
+	}
+
+	if req.IncludeDeleted && !mw.IsAdminContext(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "include_deleted is available only for admins")
+	}
+	filter.IncludeDeleted = req.IncludeDeleted
+	if req.CreatedAfter != nil {
+		createdAfter := req.CreatedAfter.AsTime()
+		filter.CreatedAfter = &createdAfter
+	}
+	if req.UpdatedSince != nil {
+		updatedSince := req.UpdatedSince.AsTime()
+		filter.UpdatedSince = &updatedSince
+	}
+	filter.Name = req.Name
+	filter.Surname = req.Surname
+	filter.Gender = req.Gender
+	filter.Nation = req.Nation
+	if req.MinAge != nil {
+		minAge := uint(*req.MinAge)
+		filter.MinAge = &minAge
+	}
+	if req.MaxAge != nil {
+		maxAge := uint(*req.MaxAge)
+		filter.MaxAge = &maxAge
+	}
+	return filter, nil
+}
+
+//grpcChange gets actor of changes from metadata of call
+func grpcChange(ctx context.Context) *dto.ChangeInfo {
+	actor := "anonymous"
+	if actors := metadata.ValueFromIncomingContext(ctx, actorHeader); len(actors) != 0 && actors[0] != "" {
+		actor = actors[0]
+	}
+	return &dto.ChangeInfo{
+		Actor:     actor,
+		RequestID: mw.RequestID(ctx),
+	}
+}
+
+func toProtoPerson(person *dto.Person) *personv1.Person {
+	return &personv1.Person{
+		Id:         uint64(person.ID),
+		Name:       person.Name,
+		Surname:    person.Surname,
+		Patronymic: person.Patronymic,
+		Age:        uint32(person.Age),
+		Gender:     person.Gender,
+		Nation:     person.Nation,
+		Version:    uint64(person.Version),
+		CreatedAt:  timestamppb.New(person.CreatedAt),
+		UpdatedAt:  protoTime(person.UpdatedAt),
+		DeletedAt:  protoTime(person.DeletedAt),
+	}
+}
+
+func toProtoEvent(event *dto.ChangeEvent) *personv1.ChangeEvent {
+	protoEvent := &personv1.ChangeEvent{
+		Cursor:    event.Cursor,
+		PersonId:  uint64(event.PersonID),
+		Version:   uint64(event.Version),
+		ChangedAt: timestamppb.New(event.ChangedAt),
+	}
+	switch event.Type {
+	case dto.ChangeTypeCreate:
+		protoEvent.Type = personv1.ChangeEvent_TYPE_CREATE
+	case dto.ChangeTypeUpdate:
+		protoEvent.Type = personv1.ChangeEvent_TYPE_UPDATE
+	case dto.ChangeTypeDelete:
+		protoEvent.Type = personv1.ChangeEvent_TYPE_DELETE
+	}
+	if event.Person != nil {
+		protoEvent.Person = toProtoPerson(event.Person)
+	}
+	return protoEvent
+}
+
+//protoTime converts optional time, nil time is not set
+func protoTime(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,54 @@
+package delivery
+
+import (
+	"context"
+	personv1 "server/server/api/person/v1"
+	memoryRep "server/server/internal/Person/repository/memory"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	mw "server/server/internal/middleware"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestGRPCList(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	for _, person := range []*dto.DBGetPerson{
+		{Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"},
+		{Name: "Anna", Surname: "Smith", Age: 25, Gender: "female", Nation: "GB"},
+		{Name: "Olga", Surname: "Ivanova", Age: 30, Gender: "female", Nation: "RU"},
+		{Name: "Maria", Surname: "Ivanova", Age: 40, Gender: "female", Nation: "RU"},
+	} {
+		_, err := repo.CreatePerson(person, &dto.ChangeInfo{Actor: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := NewPersonGRPCServer(personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{}), mw.NewACLog(zap.NewNop().Sugar(), zap.NewNop().Sugar()))
+
+	maxAge := uint32(35)
+	req := &personv1.ListRequest{PageSize: 1, Filter: &personv1.PersonFilter{Gender: "FEMALE", MaxAge: &maxAge}}
+	pages := [][]int64{}
+	for {
+		resp, err := server.List(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.TotalSize != 2 {
+			t.Fatalf("total size = %d, want 2", resp.TotalSize)
+		}
+		page := []int64{}
+		for _, person := range resp.Persons {
+			page = append(page, int64(person.Id))
+		}
+		pages = append(pages, page)
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	if len(pages) != 2 || len(pages[0]) != 1 || pages[0][0] != 2 || len(pages[1]) != 1 || pages[1][0] != 3 {
+		t.Fatalf("pages = %v, want [[2] [3]]", pages)
+	}
+}
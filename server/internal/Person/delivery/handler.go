@@ -10,20 +10,20 @@ import (
 	mw "server/server/internal/middleware"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+//actorHeader is a header with the name of person making changes. It is not authenticated,
+//any client can set it, so actor of history is what client claims and must not be trusted for audit
+const actorHeader = "X-Actor"
+
 //Result struct
 type Result struct {
 	Body interface{}
 }
 
-//RespError struct
-type RespError struct {
-	Err string
-}
-
 //PersonHandler handles requests connectded to persons
 type PersonHandler struct {
 	persons personUsecase.PersonUsecaseI
@@ -41,220 +41,436 @@ func NewPersonHandler(persons personUsecase.PersonUsecaseI, logger *mw.ACLog) *P
 //RegisterHandler registers api of person info
 func (handler *PersonHandler) RegisterHandler(router *mux.Router) {
 	router.HandleFunc("/api/people", handler.GetPersonList).Methods(http.MethodGet)
+	router.HandleFunc("/api/people/search", handler.SearchPersons).Methods(http.MethodGet)
+	router.HandleFunc("/api/people/stats", handler.GetStats).Methods(http.MethodGet)
+	router.HandleFunc("/api/people/aggregate", handler.AggregatePersons).Methods(http.MethodGet)
 	router.HandleFunc("/api/people/age/{age:[0-9]+}", handler.GetPersonByAgeList).Methods(http.MethodGet)
 	router.HandleFunc("/api/people/gender/{gender}", handler.GetPersonByGenderList).Methods(http.MethodGet)
 	router.HandleFunc("/api/people/nation/{nation}", handler.GetPersonByNationList).Methods(http.MethodGet)
 	router.HandleFunc("/api/people/limit/{limit:[0-9]+}", handler.GetPersonWithLimitList).Methods(http.MethodGet)
-	router.HandleFunc("/api/people/{id:[0-9]+}", handler.DeletePerson).Methods(http.MethodDelete)
-	router.HandleFunc("/api/people/{id:[0-9]+}", handler.UpdatePerson).Methods(http.MethodPatch)
+	router.HandleFunc("/api/people/{id:[0-9]+}", handler.redirectMerged(handler.GetPerson)).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/api/people/{id:[0-9]+}", handler.redirectMerged(handler.DeletePerson)).Methods(http.MethodDelete)
+	//restore and history are not redirected, they are the way to see and undo merge of person
+	router.HandleFunc("/api/people/{id:[0-9]+}/restore", handler.RestorePerson).Methods(http.MethodPost)
+	router.HandleFunc("/api/people/{id:[0-9]+}/history", handler.GetPersonHistory).Methods(http.MethodGet)
+	router.HandleFunc("/api/people/{id:[0-9]+}/revert", handler.redirectMerged(handler.RevertPerson)).Methods(http.MethodPost)
+	router.HandleFunc("/api/people/{id:[0-9]+}/merge", handler.redirectMerged(handler.MergePerson)).Methods(http.MethodPost)
+	router.HandleFunc("/api/people/{id:[0-9]+}", handler.redirectMerged(handler.UpdatePerson)).Methods(http.MethodPatch)
+	router.HandleFunc("/api/people/{id:[0-9]+}", handler.redirectMerged(handler.ReplacePerson)).Methods(http.MethodPut)
 	router.HandleFunc("/api/people", handler.CreatePerson).Methods(http.MethodPost)
+	router.HandleFunc("/api/people/batch", handler.BatchPersons).Methods(http.MethodPost)
+	router.HandleFunc("/api/people/import", handler.ImportPersons).Methods(http.MethodPost)
+	router.HandleFunc("/api/changes", handler.GetChanges).Methods(http.MethodGet)
+	router.HandleFunc("/api/duplicates", handler.GetDuplicates).Methods(http.MethodGet)
+	router.HandleFunc("/api/openapi.json", handler.GetOpenAPI).Methods(http.MethodGet)
+	router.HandleFunc("/api/docs", handler.GetDocs).Methods(http.MethodGet)
+	router.NotFoundHandler = http.HandlerFunc(handler.routeNotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(handler.methodNotAllowed)
 }
 
+//GetPersonList gets people in the format accepted by client.
+//Current people are streamed from repository, so they are not loaded into memory at once
 func (handler *PersonHandler) GetPersonList(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
+	asOf, ok := handler.timeParam(w, r, "as_of")
+	if !ok {
+		return
+	}
 
-	pers, err := handler.persons.GetPersons()
+	stream := newPersonStream(w, format)
+
+	var err error
+	if asOf != nil {
+		var pers []*dto.Person
+		pers, err = handler.persons.GetPersonsAsOf(*asOf, filter)
+		for i := 0; err == nil && i < len(pers); i++ {
+			err = stream.Write(pers[i])
+		}
+	} else {
+		err = handler.persons.StreamPersons(r.Context(), filter, stream.Write)
+	}
 
 	if err != nil {
+		if !stream.Started() {
+			handler.writeError(w, r, "problems with getting people", err)
+			return
+		}
+		//written part of the list can not be taken back, client gets incomplete body
 		handler.logger.LogError("problems with getting people", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	body := pers
-
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(&Result{Body: body})
-
+	err = stream.Close()
 	if err != nil {
-		handler.logger.LogError("problems with marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.logger.LogError("problems with writing people", err, w.Header().Get("request-id"), r.URL.Path)
 		return
 	}
 }
 
 func (handler *PersonHandler) GetPersonByAgeList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
 
 	vars := mux.Vars(r)
 	strage, ok := vars["age"]
 	if !ok {
-		handler.logger.LogError("problems with parameters", errors.New("age is missing in parameters"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("age is missing in parameters"))
 		return
 	}
 
 	age64, err := strconv.ParseUint(strage, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		handler.logger.LogError("problems with parameters", errors.New("age is not number"), w.Header().Get("request-id"), r.URL.Path)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("age is not number"))
 		return
 	}
 
 	age := uint(age64)
 
-	pers, err := handler.persons.GetPersonsByAge(age)
-
-	if err != nil {
-		handler.logger.LogError("problems with getting people", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
 		return
 	}
 
-	body := pers
+	asOf, ok := handler.timeParam(w, r, "as_of")
+	if !ok {
+		return
+	}
 
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(&Result{Body: body})
+	var pers []*dto.Person
+	if asOf != nil {
+		pers, err = handler.personsAsOf(*asOf, filter, func(person *dto.Person) bool {
+			return person.Age == age
+		})
+	} else {
+		pers, err = handler.persons.GetPersonsByAge(age, filter)
+	}
 
 	if err != nil {
-		handler.logger.LogError("problems with marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems with getting people", err)
 		return
 	}
+
+	handler.writeList(w, r, format, pers)
 }
 
 func (handler *PersonHandler) GetPersonByGenderList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
 
 	vars := mux.Vars(r)
 	gender, ok := vars["gender"]
 	if !ok {
-		handler.logger.LogError("problems with parameters", errors.New("gender is missing in parameters"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("gender is missing in parameters"))
 		return
 	}
 
-	pers, err := handler.persons.GetPersonsByGender(gender)
-
-	if err != nil {
-		handler.logger.LogError("problems with getting people", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
 		return
 	}
 
-	body := pers
+	asOf, ok := handler.timeParam(w, r, "as_of")
+	if !ok {
+		return
+	}
 
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(&Result{Body: body})
+	var pers []*dto.Person
+	var err error
+	if asOf != nil {
+		pers, err = handler.personsAsOf(*asOf, filter, func(person *dto.Person) bool {
+			return person.Gender == gender
+		})
+	} else {
+		pers, err = handler.persons.GetPersonsByGender(gender, filter)
+	}
 
 	if err != nil {
-		handler.logger.LogError("problems with marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems with getting people", err)
 		return
 	}
+
+	handler.writeList(w, r, format, pers)
 }
 
 func (handler *PersonHandler) GetPersonByNationList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
 
 	vars := mux.Vars(r)
 	nation, ok := vars["nation"]
 	if !ok {
-		handler.logger.LogError("problems with parameters", errors.New("nation is missing in parameters"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("nation is missing in parameters"))
 		return
 	}
 
-	pers, err := handler.persons.GetPersonsByNation(strings.ToUpper(nation))
-
-	if err != nil {
-		handler.logger.LogError("problems with getting people", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
 		return
 	}
 
-	body := pers
+	nation = strings.ToUpper(nation)
+
+	asOf, ok := handler.timeParam(w, r, "as_of")
+	if !ok {
+		return
+	}
 
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(&Result{Body: body})
+	var pers []*dto.Person
+	var err error
+	if asOf != nil {
+		pers, err = handler.personsAsOf(*asOf, filter, func(person *dto.Person) bool {
+			return person.Nation == nation
+		})
+	} else {
+		pers, err = handler.persons.GetPersonsByNation(nation, filter)
+	}
 
 	if err != nil {
-		handler.logger.LogError("problems with marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems with getting people", err)
 		return
 	}
+
+	handler.writeList(w, r, format, pers)
 }
 
 func (handler *PersonHandler) GetPersonWithLimitList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
 
 	vars := mux.Vars(r)
 	strlimit, ok := vars["limit"]
 	if !ok {
-		handler.logger.LogError("problems with parameters", errors.New("limit is missing in parameters"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("limit is missing in parameters"))
 		return
 	}
 
 	limit64, err := strconv.ParseUint(strlimit, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		handler.logger.LogError("problems with parameters", errors.New("limit is not number"), w.Header().Get("request-id"), r.URL.Path)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("limit is not number"))
 		return
 	}
 
 	limit := uint(limit64)
 
-	pers, err := handler.persons.GetPersonsWithLimit(limit)
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
+		return
+	}
+
+	asOf, ok := handler.timeParam(w, r, "as_of")
+	if !ok {
+		return
+	}
+
+	var pers []*dto.Person
+	if asOf != nil {
+		pers, err = handler.personsAsOf(*asOf, filter, func(*dto.Person) bool {
+			return true
+		})
+		if uint(len(pers)) > limit {
+			pers = pers[:limit]
+		}
+	} else {
+		pers, err = handler.persons.GetPersonsWithLimit(limit, filter)
+	}
 
 	if err != nil {
-		handler.logger.LogError("problems with getting people", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems with getting people", err)
+		return
+	}
+
+	handler.writeList(w, r, format, pers)
+}
+
+func (handler *PersonHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	strid, ok := vars["id"]
+	if !ok {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
 		return
 	}
 
-	body := pers
+	id64, err := strconv.ParseUint(strid, 10, 64)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
+		return
+	}
+
+	id := uint(id64)
 
-	encoder := json.NewEncoder(w)
-	err = encoder.Encode(&Result{Body: body})
+	version, ok := handler.ifMatchVersion(w, r, id)
+	if !ok {
+		return
+	}
 
+	err = handler.persons.DeletePerson(id, version, changeInfo(w, r))
 	if err != nil {
-		handler.logger.LogError("problems with marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems deleting person", err)
 		return
 	}
 }
 
-func (handler *PersonHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
+//GetPerson gets person by id. Responses are revalidated by ETag, so clients get fresh version after every change
+func (handler *PersonHandler) GetPerson(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	strid, ok := vars["id"]
+	if !ok {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
+		return
+	}
+
+	id64, err := strconv.ParseUint(strid, 10, 64)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
+		return
+	}
+
+	id := uint(id64)
+
+	person, err := handler.persons.GetPerson(id)
+	if err != nil {
+		handler.writeError(w, r, "problems with getting person", err)
+		return
+	}
+
+	tag := etag(person.Version)
+	modified := person.CreatedAt
+	if person.UpdatedAt != nil {
+		modified = *person.UpdatedAt
+	}
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if matchIfNoneMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, person)
+}
+
+//RestorePerson restores soft-deleted person
+func (handler *PersonHandler) RestorePerson(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	strid, ok := vars["id"]
+	if !ok {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
+		return
+	}
+
+	id64, err := strconv.ParseUint(strid, 10, 64)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
+		return
+	}
+
+	id := uint(id64)
+
+	err = handler.persons.RestorePerson(id, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems restoring person", err)
+		return
+	}
+}
+
+//GetPersonHistory gets all changes of person
+func (handler *PersonHandler) GetPersonHistory(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiateDocument(w, r)
+	if !ok {
+		return
+	}
+
 	vars := mux.Vars(r)
 	strid, ok := vars["id"]
 	if !ok {
-		handler.logger.LogError("problems with parameters", errors.New("id is missing in parameters"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
 		return
 	}
 
 	id64, err := strconv.ParseUint(strid, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		handler.logger.LogError("problems with parameters", errors.New("id is not number"), w.Header().Get("request-id"), r.URL.Path)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
 		return
 	}
 
 	id := uint(id64)
 
-	err = handler.persons.DeletePerson(id)
+	history, err := handler.persons.GetPersonHistory(id)
 	if err != nil {
-		handler.logger.LogError("problems deleting person", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems with getting history", err)
 		return
 	}
+
+	handler.writeBody(w, r, format, http.StatusOK, history)
+}
+
+//RevertPerson restores fields of person from one of previous versions
+func (handler *PersonHandler) RevertPerson(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	strid, ok := vars["id"]
+	if !ok {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
+		return
+	}
+
+	id64, err := strconv.ParseUint(strid, 10, 64)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
+		return
+	}
+
+	id := uint(id64)
+
+	revert := &dto.Revert{}
+	err = json.NewDecoder(r.Body).Decode(revert)
+	if err != nil || revert.Version == 0 {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with unmarshalling json", errors.New("version to revert is required"))
+		return
+	}
+
+	newVersion, err := handler.persons.RevertPerson(id, revert.Version, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems reverting person", err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(newVersion))
 }
 
 func (handler *PersonHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	strid, ok := vars["id"]
 	if !ok {
-		handler.logger.LogError("problems with parameters", errors.New("id is missing in parameters"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
 		return
 	}
 
 	id64, err := strconv.ParseUint(strid, 10, 64)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		handler.logger.LogError("problems with parameters", errors.New("id is not number"), w.Header().Get("request-id"), r.URL.Path)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
 		return
 	}
 
@@ -262,39 +478,46 @@ func (handler *PersonHandler) UpdatePerson(w http.ResponseWriter, r *http.Reques
 
 	jsonbody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		handler.logger.LogError("problems with reading json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with reading json", err)
+		return
+	}
+
+	if kind := patchKind(r.Header.Get("Content-Type")); kind != "" {
+		handler.patchPerson(w, r, id, &dto.Patch{Kind: kind, Body: jsonbody})
 		return
 	}
 
-	updatePerson := &dto.Person{ID: id}
+	//plain JSON keeps old semantics where empty fields are not changed
+	updatePerson := &dto.Person{}
 	err = json.Unmarshal(jsonbody, &updatePerson)
 	if err != nil {
-		handler.logger.LogError("prbolems with unmarshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "prbolems with unmarshalling json", err)
 		return
 	}
+	updatePerson.ID = id
 
-	err = handler.persons.UpdatePerson(updatePerson)
-	if err != nil {
-		if err == dto.ErrNotFound {
-			handler.logger.LogError("person not found", err, w.Header().Get("request-id"), r.URL.Path)
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
+	version, ok := handler.ifMatchVersion(w, r, updatePerson.ID)
+	if !ok {
+		return
+	}
 
-		handler.logger.LogError("problems updating person", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+	newVersion, err := handler.persons.UpdatePerson(updatePerson, version, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems updating person", err)
 		return
 	}
+
+	w.Header().Set("ETag", etag(newVersion))
 }
 
 func (handler *PersonHandler) CreatePerson(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	format, ok := handler.negotiateDocument(w, r)
+	if !ok {
+		return
+	}
 
 	if r.Header.Get("Content-Type") != "application/json" {
-		handler.logger.LogError("bad content-type", errors.New("bad content-type"), w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "bad content-type", errors.New("bad content-type"))
 		return
 	}
 
@@ -303,35 +526,305 @@ func (handler *PersonHandler) CreatePerson(w http.ResponseWriter, r *http.Reques
 	jsonbody, err := ioutil.ReadAll(r.Body)
 
 	if err != nil {
-		handler.logger.LogError("problems with reading json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with reading json", err)
 		return
 	}
 
 	err = json.Unmarshal(jsonbody, &reqPerson)
 
 	if err != nil {
-		handler.logger.LogError("problems with unmarshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusBadRequest)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with unmarshalling json", err)
+		return
+	}
+
+	force := false
+	if strForce := r.URL.Query().Get("force"); strForce != "" {
+		force, err = strconv.ParseBool(strForce)
+		if err != nil {
+			handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("force is not boolean"))
+			return
+		}
+	}
+
+	id, err := handler.persons.CreatePerson(r.Context(), &reqPerson, force, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems with creating user", err)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusCreated, &dto.RespID{ID: id})
+}
+
+//SearchPersons searches people by name, surname and patronymic, exactly or phonetically
+func (handler *PersonHandler) SearchPersons(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	search := &dto.PersonSearch{
+		Name:       query.Get("name"),
+		Surname:    query.Get("surname"),
+		Patronymic: query.Get("patronymic"),
+		Mode:       query.Get("mode"),
+	}
+
+	if search.Name == "" && search.Surname == "" && search.Patronymic == "" {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("name, surname or patronymic is required"))
+		return
+	}
+
+	if search.Mode == "" {
+		search.Mode = dto.SearchModeExact
+	}
+	if search.Mode != dto.SearchModeExact && search.Mode != dto.SearchModePhonetic {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("unknown search mode"))
+		return
+	}
+
+	result, err := handler.persons.SearchPersons(search)
+	if err != nil {
+		handler.writeError(w, r, "problems with searching people", err)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, result)
+}
+
+//MergePerson merges person from the body into person from the url
+func (handler *PersonHandler) MergePerson(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	strid, ok := vars["id"]
+	if !ok {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
 		return
 	}
 
-	id, err := handler.persons.CreatePerson(&reqPerson)
+	id64, err := strconv.ParseUint(strid, 10, 64)
 	if err != nil {
-		handler.logger.LogError("problems with creating user", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	id := uint(id64)
+
+	merge := &dto.Merge{}
+	err = json.NewDecoder(r.Body).Decode(merge)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with unmarshalling json", err)
+		return
+	}
 
-	body := &dto.RespID{ID: id}
+	version, ok := handler.ifMatchVersion(w, r, id)
+	if !ok {
+		return
+	}
 
-	err = json.NewEncoder(w).Encode(&Result{Body: body})
+	newVersion, err := handler.persons.MergePerson(id, merge, version, changeInfo(w, r))
 	if err != nil {
-		handler.logger.LogError("problems marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
-		w.WriteHeader(http.StatusInternalServerError)
+		handler.writeError(w, r, "problems merging people", err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(newVersion))
+}
+
+//GetStats gets demographic statistics of people, buckets are comma-separated bounds of age buckets
+func (handler *PersonHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiateDocument(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
 		return
 	}
 
+	bounds := []uint{}
+	if strBuckets := r.URL.Query().Get("buckets"); strBuckets != "" {
+		for _, strBound := range strings.Split(strBuckets, ",") {
+			bound, err := strconv.ParseUint(strings.TrimSpace(strBound), 10, 64)
+			if err != nil {
+				handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("buckets are not numbers"))
+				return
+			}
+			bounds = append(bounds, uint(bound))
+		}
+	}
+
+	var top uint
+	if strTop := r.URL.Query().Get("top"); strTop != "" {
+		top64, err := strconv.ParseUint(strTop, 10, 64)
+		if err != nil {
+			handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("top is not number"))
+			return
+		}
+		top = uint(top64)
+	}
+
+	stats, err := handler.persons.GetStats(filter, bounds, top)
+	if err != nil {
+		handler.writeError(w, r, "problems with getting stats", err)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, stats)
+}
+
+//AggregatePersons groups people by group_by dimensions and computes metrics of groups,
+//groups are written as a table when CSV is chosen
+func (handler *PersonHandler) AggregatePersons(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := handler.personFilter(w, r)
+	if !ok {
+		return
+	}
+
+	aggregate := &dto.Aggregate{GroupBy: []string{}, Metrics: []string{}}
+	if strGroupBy := r.URL.Query().Get("group_by"); strGroupBy != "" {
+		aggregate.GroupBy = strings.Split(strGroupBy, ",")
+	}
+	if strMetrics := r.URL.Query().Get("metrics"); strMetrics != "" {
+		aggregate.Metrics = strings.Split(strMetrics, ",")
+	}
+
+	result, err := handler.persons.Aggregate(aggregate, filter)
+	if err != nil {
+		handler.writeError(w, r, "problems with aggregating people", err)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, result)
+}
+
+//GetChanges gets the change feed of people after since cursor
+func (handler *PersonHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiateDocument(w, r)
+	if !ok {
+		return
+	}
+
+	var limit uint
+	if strLimit := r.URL.Query().Get("limit"); strLimit != "" {
+		limit64, err := strconv.ParseUint(strLimit, 10, 64)
+		if err != nil {
+			handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("limit is not number"))
+			return
+		}
+		limit = uint(limit64)
+	}
+
+	feed, err := handler.persons.GetChanges(r.URL.Query().Get("since"), limit)
+	if err != nil {
+		handler.writeError(w, r, "problems with getting changes", err)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, feed)
+}
+
+//GetDuplicates gets clusters of people suspected to be duplicates
+func (handler *PersonHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	format, ok := handler.negotiateDocument(w, r)
+	if !ok {
+		return
+	}
+
+	clusters, err := handler.persons.GetDuplicates()
+	if err != nil {
+		handler.writeError(w, r, "problems with getting duplicates", err)
+		return
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, clusters)
+}
+
+//includeDeleted parses include_deleted parameter, which is available only for admins
+func (handler *PersonHandler) includeDeleted(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	strInclude := r.URL.Query().Get("include_deleted")
+	if strInclude == "" {
+		return false, true
+	}
+
+	include, err := strconv.ParseBool(strInclude)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("include_deleted is not boolean"))
+		return false, false
+	}
+
+	if include && !mw.IsAdmin(r) {
+		handler.writeProblem(w, r, http.StatusForbidden, "forbidden", errors.New("include_deleted is available only for admins"))
+		return false, false
+	}
+
+	return include, true
+}
+
+//personFilter parses include_deleted, created_after and updated_since parameters of lists
+func (handler *PersonHandler) personFilter(w http.ResponseWriter, r *http.Request) (*dto.PersonFilter, bool) {
+	includeDeleted, ok := handler.includeDeleted(w, r)
+	if !ok {
+		return nil, false
+	}
+	filter := &dto.PersonFilter{IncludeDeleted: includeDeleted}
+
+	filter.CreatedAfter, ok = handler.timeParam(w, r, "created_after")
+	if !ok {
+		return nil, false
+	}
+
+	filter.UpdatedSince, ok = handler.timeParam(w, r, "updated_since")
+	if !ok {
+		return nil, false
+	}
+
+	return filter, true
+}
+
+//personsAsOf gets people as they were at the given time which match the condition of list
+func (handler *PersonHandler) personsAsOf(asOf time.Time, filter *dto.PersonFilter, match func(*dto.Person) bool) ([]*dto.Person, error) {
+	pers, err := handler.persons.GetPersonsAsOf(asOf, filter)
+	if err != nil {
+		return nil, err
+	}
+	matched := []*dto.Person{}
+	for _, person := range pers {
+		if match(person) {
+			matched = append(matched, person)
+		}
+	}
+	return matched, nil
+}
+
+//timeParam parses optional RFC 3339 timestamp parameter
+func (handler *PersonHandler) timeParam(w http.ResponseWriter, r *http.Request, param string) (*time.Time, bool) {
+	strTime := r.URL.Query().Get(param)
+	if strTime == "" {
+		return nil, true
+	}
+
+	t, err := time.Parse(time.RFC3339, strTime)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New(param+" is not RFC 3339 timestamp"))
+		return nil, false
+	}
+	return &t, true
+}
+
+//changeInfo describes who makes changes in the request
+func changeInfo(w http.ResponseWriter, r *http.Request) *dto.ChangeInfo {
+	actor := r.Header.Get(actorHeader)
+	if actor == "" {
+		actor = "anonymous"
+	}
+	return &dto.ChangeInfo{
+		Actor:     actor,
+		RequestID: w.Header().Get("request-id"),
+	}
 }
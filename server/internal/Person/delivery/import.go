@@ -0,0 +1,351 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"server/server/internal/domain/dto"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+//Formats of imported files
+const (
+	importCSV  = "csv"
+	importXLSX = "xlsx"
+)
+
+//importFormats maps content types of imported files to their formats
+var importFormats = map[string]string{
+	"text/csv":                 importCSV,
+	"application/csv":          importCSV,
+	"application/vnd.ms-excel": importCSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": importXLSX,
+}
+
+//maxImportSize is a maximum size of imported file in bytes
+const maxImportSize = 10 << 20
+
+//maxUnzipSize is a maximum size of unzipped parts of XLSX file in bytes, it rejects zip bombs which fit
+//into maxImportSize. Sheets are read in memory up to this size
+const maxUnzipSize = 64 << 20
+
+//zipMagic starts XLSX files, they are ZIP archives
+var zipMagic = []byte("PK\x03\x04")
+
+//ImportRowResult is a result of row of imported file, Error is problem details of skipped or failed row
+type ImportRowResult struct {
+	Line            int      `json:"line"`
+	Status          string   `json:"status"`
+	ID              uint     `json:"id,omitempty"`
+	DuplicateOfLine int      `json:"duplicate_of_line,omitempty"`
+	Error           *Problem `json:"error,omitempty"`
+}
+
+//ImportResponse is a summary of import, valid counts rows which would be created by dry run
+type ImportResponse struct {
+	DryRun  bool               `json:"dry_run"`
+	Total   int                `json:"total"`
+	Valid   int                `json:"valid"`
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Rows    []*ImportRowResult `json:"rows"`
+}
+
+//importFile is an uploaded file with mapping of its columns to fields of person
+type importFile struct {
+	data        []byte
+	format      string
+	mapping     map[string]string
+	contentType string
+	name        string
+}
+
+//ImportPersons imports people from CSV or XLSX file sent as "file" field of multipart form or as the request body.
+//The first row of file is a header, columns are mapped to fields by mapping parameter or by their names
+func (handler *PersonHandler) ImportPersons(w http.ResponseWriter, r *http.Request) {
+	//format parameter is the format of imported file, so the answer is chosen by Accept header only
+	format, ok := handler.negotiateFormats(w, r, documentFormats, "")
+	if !ok {
+		return
+	}
+
+	options := &dto.ImportOptions{}
+	options.DryRun, ok = handler.boolParam(w, r, "dry_run")
+	if !ok {
+		return
+	}
+	options.Force, ok = handler.boolParam(w, r, "force")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, err := readImportFile(r)
+	if err != nil {
+		handler.writeError(w, r, "problems with reading imported file", err)
+		return
+	}
+
+	table, lines, err := readTable(file, r.URL.Query().Get("sheet"), r.URL.Query().Get("delimiter"))
+	if err != nil {
+		handler.writeError(w, r, "problems with parsing imported file", err)
+		return
+	}
+
+	rows, err := mapRows(table, lines, file.mapping)
+	if err != nil {
+		handler.writeError(w, r, "problems with columns of imported file", err)
+		return
+	}
+
+	result, err := handler.persons.ImportPersons(r.Context(), rows, options, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems with importing people", err)
+		return
+	}
+
+	resp := &ImportResponse{
+		DryRun:  result.DryRun,
+		Total:   result.Total,
+		Valid:   result.Valid,
+		Created: result.Created,
+		Skipped: result.Skipped,
+		Failed:  result.Failed,
+		Rows:    []*ImportRowResult{},
+	}
+	for _, rowResult := range result.Rows {
+		row := &ImportRowResult{
+			Line:            rowResult.Line,
+			Status:          rowResult.Status,
+			ID:              rowResult.ID,
+			DuplicateOfLine: rowResult.DuplicateOfLine,
+		}
+		if rowResult.Err != nil {
+			row.Error = problemFor(rowResult.Err)
+			if row.Error.Status >= http.StatusInternalServerError {
+				handler.logger.LogError("problems with importing row", rowResult.Err, w.Header().Get("request-id"), r.URL.Path)
+			}
+		}
+		resp.Rows = append(resp.Rows, row)
+	}
+
+	handler.writeBody(w, r, format, http.StatusOK, resp)
+}
+
+//boolParam parses optional boolean parameter, it is false if it is not passed
+func (handler *PersonHandler) boolParam(w http.ResponseWriter, r *http.Request, param string) (bool, bool) {
+	strValue := r.URL.Query().Get(param)
+	if strValue == "" {
+		return false, true
+	}
+
+	value, err := strconv.ParseBool(strValue)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New(param+" is not boolean"))
+		return false, false
+	}
+	return value, true
+}
+
+//readImportFile reads file and mapping from multipart form or file from the request body and mapping from parameters.
+//Format is taken from format parameter, content type or extension of file, XLSX is also recognized by its content
+func readImportFile(r *http.Request) (*importFile, error) {
+	file := &importFile{contentType: r.Header.Get("Content-Type")}
+	strMapping := r.URL.Query().Get("mapping")
+
+	mediaType, _, _ := mime.ParseMediaType(file.contentType)
+	if mediaType == "multipart/form-data" {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+		}
+		found := false
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+			}
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+			}
+			switch part.FormName() {
+			case "file":
+				found = true
+				file.data = data
+				file.name = part.FileName()
+				file.contentType = part.Header.Get("Content-Type")
+			case "mapping":
+				strMapping = string(data)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: form has no file field", dto.ErrInvalidImport)
+		}
+	} else {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+		}
+		file.data = data
+	}
+
+	if strMapping != "" {
+		err := json.Unmarshal([]byte(strMapping), &file.mapping)
+		if err != nil {
+			return nil, fmt.Errorf("%w: mapping is not JSON object of column names", dto.ErrInvalidImport)
+		}
+	}
+
+	file.format = r.URL.Query().Get("format")
+	if file.format == "" {
+		mediaType, _, _ = mime.ParseMediaType(file.contentType)
+		file.format = importFormats[mediaType]
+	}
+	if file.format == "" {
+		switch strings.ToLower(path.Ext(file.name)) {
+		case ".csv":
+			file.format = importCSV
+		case ".xlsx":
+			file.format = importXLSX
+		}
+	}
+	if file.format == "" {
+		file.format = importCSV
+		if bytes.HasPrefix(file.data, zipMagic) {
+			file.format = importXLSX
+		}
+	}
+	return file, nil
+}
+
+//readTable reads cells of file with numbers of lines where rows start, quoted cells of CSV may span
+//several lines. XLSX is read from the named sheet or from the first one
+func readTable(file *importFile, sheet string, delimiter string) ([][]string, []int, error) {
+	switch file.format {
+	case importCSV:
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(file.data, []byte("\xef\xbb\xbf"))))
+		reader.FieldsPerRecord = -1
+		if delimiter != "" {
+			comma, size := utf8.DecodeRuneInString(delimiter)
+			if size != len(delimiter) {
+				return nil, nil, fmt.Errorf("%w: delimiter must be one character", dto.ErrInvalidImport)
+			}
+			reader.Comma = comma
+		}
+		table := [][]string{}
+		lines := []int{}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+			}
+			line, _ := reader.FieldPos(0)
+			table = append(table, record)
+			lines = append(lines, line)
+		}
+		return table, lines, nil
+	case importXLSX:
+		workbook, err := excelize.OpenReader(bytes.NewReader(file.data), excelize.Options{
+			UnzipSizeLimit:    maxUnzipSize,
+			UnzipXMLSizeLimit: maxUnzipSize,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+		}
+		defer workbook.Close()
+		if sheet == "" {
+			sheet = workbook.GetSheetName(0)
+		}
+		table, err := workbook.GetRows(sheet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", dto.ErrInvalidImport, err)
+		}
+		//empty rows between filled ones are kept, so rows are numbered like in the sheet
+		lines := make([]int, len(table))
+		for i := range table {
+			lines[i] = i + 1
+		}
+		return table, lines, nil
+	}
+	return nil, nil, fmt.Errorf("%w: format %q is not supported", dto.ErrInvalidImport, file.format)
+}
+
+//mapRows reads people from rows after header, lines are numbers of lines of rows in file. Mapping gets names
+//of columns by fields, not mapped fields are read from columns named like them. Empty rows are skipped
+func mapRows(table [][]string, lines []int, mapping map[string]string) ([]*dto.ImportRow, error) {
+	if len(table) == 0 {
+		return nil, fmt.Errorf("%w: file has no header row", dto.ErrInvalidImport)
+	}
+	for field := range mapping {
+		if !contains(dto.ImportFields, field) {
+			return nil, fmt.Errorf("%w: %s is not imported field of person", dto.ErrInvalidImport, field)
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range dto.ImportFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		for i, header := range table[0] {
+			if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(name)) {
+				columns[field] = i
+				break
+			}
+		}
+		if _, ok := columns[field]; !ok && (mapped || contains(dto.PersonRequiredFields, field)) {
+			return nil, fmt.Errorf("%w: column %q of %s is not found", dto.ErrInvalidImport, name, field)
+		}
+	}
+
+	rows := []*dto.ImportRow{}
+	//the first row is the header
+	for i := 1; i < len(table); i++ {
+		record := table[i]
+		cell := func(field string) string {
+			column, ok := columns[field]
+			if !ok || column >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[column])
+		}
+		person := &dto.Person{
+			Name:       cell("name"),
+			Surname:    cell("surname"),
+			Patronymic: cell("patronymic"),
+		}
+		if person.Name == "" && person.Surname == "" && person.Patronymic == "" {
+			continue
+		}
+		rows = append(rows, &dto.ImportRow{Line: lines[i], Person: person})
+	}
+	return rows, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package delivery
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"server/server/internal/domain/dto"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestReadTableLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantLines []int
+	}{
+		{"one line records", "name,surname\nIvan,Petrov\nAnna,Smith\n", []int{1, 2, 3}},
+		//quoted cells keep line breaks, next records start on later lines
+		{"quoted newlines", "name,surname\n\"Ivan\nIvanovich\",Petrov\nAnna,\"Smith\n\nJones\"\nOleg,Sidorov\n", []int{1, 2, 4, 7}},
+		{"empty lines", "name,surname\n\nIvan,Petrov\n", []int{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, lines, err := readTable(&importFile{data: []byte(tt.data), format: importCSV}, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("lines = %v, want %v", lines, tt.wantLines)
+			}
+			for i := range lines {
+				if lines[i] != tt.wantLines[i] {
+					t.Fatalf("lines = %v, want %v", lines, tt.wantLines)
+				}
+			}
+		})
+	}
+}
+
+func TestMapRowsLines(t *testing.T) {
+	table := [][]string{{"name", "surname"}, {"Ivan\nIvanovich", "Petrov"}, {"", ""}, {"Anna", "Smith"}}
+	rows, err := mapRows(table, []int{1, 2, 4, 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || rows[1].Line != 5 {
+		t.Fatalf("rows = %+v, want rows of lines 2 and 5", rows)
+	}
+}
+
+func TestReadTableXLSX(t *testing.T) {
+	workbook := excelize.NewFile()
+	for cell, value := range map[string]string{"A1": "name", "B1": "surname", "A2": "Ivan", "B2": "Petrov", "A4": "Anna", "B4": "Smith"} {
+		if err := workbook.SetCellValue("Sheet1", cell, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := workbook.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, lines, err := readTable(&importFile{data: buf.Bytes(), format: importXLSX}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := mapRows(table, lines, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 2 || rows[1].Line != 4 || rows[1].Person.Name != "Anna" {
+		t.Fatalf("rows = %+v, want rows of lines 2 and 4", rows)
+	}
+}
+
+func TestReadTableZipBomb(t *testing.T) {
+	//the sheet is compressed below maxImportSize, but it is larger than maxUnzipSize when unzipped
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := []byte(strings.Repeat("<row/>", 1<<16))
+	for written := 0; written <= maxUnzipSize; written += len(chunk) {
+		if _, err := sheet.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > maxImportSize {
+		t.Fatalf("zip has %d bytes, want it to fit into maxImportSize", buf.Len())
+	}
+
+	_, _, err = readTable(&importFile{data: buf.Bytes(), format: importXLSX}, "", "")
+	if !errors.Is(err, dto.ErrInvalidImport) || !strings.Contains(err.Error(), "unzip size") {
+		t.Fatalf("readTable() error = %v, want error of unzip size", err)
+	}
+}
@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"server/server/internal/domain/dto"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+//Content types of patches
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+//patchKind gets kind of patch from Content-Type header. Other content types are plain JSON
+//as they were before patches were supported
+func patchKind(header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case mergePatchType:
+		return dto.PatchMerge
+	case jsonPatchType:
+		return dto.PatchJSON
+	default:
+		return ""
+	}
+}
+
+//ReplacePerson replaces all fields of person, required fields must be present
+func (handler *PersonHandler) ReplacePerson(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	strid, ok := vars["id"]
+	if !ok {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is missing in parameters"))
+		return
+	}
+
+	id64, err := strconv.ParseUint(strid, 10, 64)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with parameters", errors.New("id is not number"))
+		return
+	}
+
+	if patchKind(r.Header.Get("Content-Type")) != "" {
+		handler.writeProblem(w, r, http.StatusUnsupportedMediaType, "problems with parameters", errors.New("patch can not replace person"))
+		return
+	}
+
+	jsonbody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		handler.writeProblem(w, r, http.StatusBadRequest, "problems with reading json", err)
+		return
+	}
+
+	handler.patchPerson(w, r, uint(id64), &dto.Patch{Kind: dto.PatchReplace, Body: jsonbody})
+}
+
+//patchPerson applies patch checking If-Match header and sets ETag of the new version
+func (handler *PersonHandler) patchPerson(w http.ResponseWriter, r *http.Request, id uint, patch *dto.Patch) {
+	version, ok := handler.ifMatchVersion(w, r, id)
+	if !ok {
+		return
+	}
+
+	newVersion, err := handler.persons.PatchPerson(id, patch, version, changeInfo(w, r))
+	if err != nil {
+		handler.writeError(w, r, "problems updating person", err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(newVersion))
+}
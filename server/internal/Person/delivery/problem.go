@@ -0,0 +1,123 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"server/server/internal/domain/dto"
+)
+
+// problemType is a content type of problem details
+const problemType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body, Errors and Candidates are its extension members
+type Problem struct {
+	Type       string                    `json:"type"`
+	Title      string                    `json:"title"`
+	Status     int                       `json:"status"`
+	Detail     string                    `json:"detail,omitempty"`
+	Instance   string                    `json:"instance,omitempty"`
+	RequestID  string                    `json:"request_id,omitempty"`
+	Errors     []*dto.FieldError         `json:"errors,omitempty"`
+	Candidates []*dto.DuplicateCandidate `json:"candidates,omitempty"`
+}
+
+// problemKind is HTTP status and problem type of domain error
+type problemKind struct {
+	status int
+	slug   string
+}
+
+// domainProblems maps domain errors to HTTP statuses
+var domainProblems = map[error]problemKind{
+	dto.ErrNotFound:           {http.StatusNotFound, "not-found"},
+	dto.ErrGone:               {http.StatusGone, "gone"},
+	dto.ErrVersionMismatch:    {http.StatusPreconditionFailed, "version-mismatch"},
+	dto.ErrInvalidCursor:      {http.StatusBadRequest, "invalid-cursor"},
+	dto.ErrInvalidPatch:       {http.StatusBadRequest, "invalid-patch"},
+	dto.ErrPatchConflict:      {http.StatusConflict, "patch-conflict"},
+	dto.ErrInvalidMerge:       {http.StatusBadRequest, "invalid-merge"},
+	dto.ErrInvalidBuckets:     {http.StatusBadRequest, "invalid-buckets"},
+	dto.ErrInvalidAggregate:   {http.StatusBadRequest, "invalid-aggregate"},
+	dto.ErrInvalidBatch:       {http.StatusBadRequest, "invalid-batch"},
+	dto.ErrRolledBack:         {http.StatusFailedDependency, "rolled-back"},
+	dto.ErrInvalidImport:      {http.StatusBadRequest, "invalid-import"},
+	dto.ErrDuplicateRow:       {http.StatusConflict, "duplicate-row"},
+	dto.ErrImportTimeout:      {http.StatusGatewayTimeout, "import-timeout"},
+	dto.ErrDuplicateOperation: {http.StatusConflict, "duplicate-operation"},
+	dto.ErrVersionNotFound:    {http.StatusNotFound, "version-not-found"},
+}
+
+// problemFor makes problem details of error, unknown errors are internal errors
+func problemFor(err error) *Problem {
+	var validationErr *dto.ValidationError
+	if errors.As(err, &validationErr) {
+		return newProblem(http.StatusUnprocessableEntity, "validation", err)
+	}
+
+	var duplicateErr *dto.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		problem := newProblem(http.StatusConflict, "duplicate", err)
+		problem.Candidates = duplicateErr.Candidates
+		return problem
+	}
+
+	for domainErr, kind := range domainProblems {
+		if errors.Is(err, domainErr) {
+			return newProblem(kind.status, kind.slug, err)
+		}
+	}
+	return newProblem(http.StatusInternalServerError, "", err)
+}
+
+// newProblem makes problem details. Details of internal errors are not shown to clients
+func newProblem(status int, slug string, err error) *Problem {
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+	if slug != "" {
+		problem.Type = "/problems/" + slug
+	}
+	if err != nil && status < http.StatusInternalServerError {
+		problem.Detail = err.Error()
+	}
+	var validationErr *dto.ValidationError
+	if errors.As(err, &validationErr) {
+		problem.Errors = validationErr.Errors
+	}
+	return problem
+}
+
+// writeError logs error and answers with its problem details, status is taken from domainProblems
+func (handler *PersonHandler) writeError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	handler.logger.LogError(msg, err, w.Header().Get("request-id"), r.URL.Path)
+	handler.sendProblem(w, r, problemFor(err))
+}
+
+// writeProblem logs error and answers with problem details of the given status
+func (handler *PersonHandler) writeProblem(w http.ResponseWriter, r *http.Request, status int, msg string, err error) {
+	handler.logger.LogError(msg, err, w.Header().Get("request-id"), r.URL.Path)
+	handler.sendProblem(w, r, newProblem(status, "", err))
+}
+
+func (handler *PersonHandler) routeNotFound(w http.ResponseWriter, r *http.Request) {
+	handler.writeProblem(w, r, http.StatusNotFound, "route not found", errors.New("no route matches the path"))
+}
+
+func (handler *PersonHandler) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	handler.writeProblem(w, r, http.StatusMethodNotAllowed, "method not allowed", errors.New("method is not allowed for the path"))
+}
+
+func (handler *PersonHandler) sendProblem(w http.ResponseWriter, r *http.Request, problem *Problem) {
+	problem.Instance = r.URL.Path
+	problem.RequestID = w.Header().Get("request-id")
+
+	w.Header().Set("Content-Type", problemType)
+	w.WriteHeader(problem.Status)
+	err := json.NewEncoder(w).Encode(problem)
+	if err != nil {
+		handler.logger.LogError("problems marshalling json", err, w.Header().Get("request-id"), r.URL.Path)
+	}
+}
@@ -0,0 +1,111 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	memoryRep "server/server/internal/Person/repository/memory"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	"testing"
+)
+
+func TestProblemFor(t *testing.T) {
+	validationErr := &dto.ValidationError{Errors: []*dto.FieldError{{Field: "age", Code: dto.CodeOutOfRange, Message: "field must be between 0 and 150"}}}
+	duplicateErr := &dto.DuplicateError{Candidates: []*dto.DuplicateCandidate{{Person: &dto.Person{ID: 7}, Rule: dto.DuplicateRuleExact}}}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+		wantDetail bool
+	}{
+		{"not found", dto.ErrNotFound, http.StatusNotFound, "/problems/not-found", true},
+		{"wrapped not found", fmt.Errorf("getting person: %w", dto.ErrNotFound), http.StatusNotFound, "/problems/not-found", true},
+		{"gone", dto.ErrGone, http.StatusGone, "/problems/gone", true},
+		{"version mismatch", dto.ErrVersionMismatch, http.StatusPreconditionFailed, "/problems/version-mismatch", true},
+		{"invalid cursor", dto.ErrInvalidCursor, http.StatusBadRequest, "/problems/invalid-cursor", true},
+		{"invalid patch", dto.ErrInvalidPatch, http.StatusBadRequest, "/problems/invalid-patch", true},
+		{"patch conflict", dto.ErrPatchConflict, http.StatusConflict, "/problems/patch-conflict", true},
+		{"invalid merge", dto.ErrInvalidMerge, http.StatusBadRequest, "/problems/invalid-merge", true},
+		{"invalid buckets", dto.ErrInvalidBuckets, http.StatusBadRequest, "/problems/invalid-buckets", true},
+		{"invalid aggregate", dto.ErrInvalidAggregate, http.StatusBadRequest, "/problems/invalid-aggregate", true},
+		{"invalid batch", dto.ErrInvalidBatch, http.StatusBadRequest, "/problems/invalid-batch", true},
+		{"rolled back", dto.ErrRolledBack, http.StatusFailedDependency, "/problems/rolled-back", true},
+		{"invalid import", dto.ErrInvalidImport, http.StatusBadRequest, "/problems/invalid-import", true},
+		{"duplicate row", dto.ErrDuplicateRow, http.StatusConflict, "/problems/duplicate-row", true},
+		{"validation", validationErr, http.StatusUnprocessableEntity, "/problems/validation", true},
+		{"wrapped validation", fmt.Errorf("row 2: %w", validationErr), http.StatusUnprocessableEntity, "/problems/validation", true},
+		{"duplicate", duplicateErr, http.StatusConflict, "/problems/duplicate", true},
+		//details of internal errors are hidden
+		{"internal", errors.New("connection refused"), http.StatusInternalServerError, "about:blank", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := problemFor(tt.err)
+			if problem.Status != tt.wantStatus || problem.Type != tt.wantType {
+				t.Errorf("problemFor() = %d %s, want %d %s", problem.Status, problem.Type, tt.wantStatus, tt.wantType)
+			}
+			if problem.Title != http.StatusText(tt.wantStatus) {
+				t.Errorf("Title = %q, want %q", problem.Title, http.StatusText(tt.wantStatus))
+			}
+			if (problem.Detail != "") != tt.wantDetail {
+				t.Errorf("Detail = %q, want shown %v", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestProblemExtensions(t *testing.T) {
+	validationErr := &dto.ValidationError{Errors: []*dto.FieldError{{Field: "age", Code: dto.CodeOutOfRange}}}
+	if problem := problemFor(validationErr); len(problem.Errors) != 1 || problem.Errors[0].Field != "age" {
+		t.Errorf("validation problem has errors %+v", problem.Errors)
+	}
+
+	duplicateErr := &dto.DuplicateError{Candidates: []*dto.DuplicateCandidate{{Person: &dto.Person{ID: 7}}}}
+	if problem := problemFor(duplicateErr); len(problem.Candidates) != 1 || problem.Candidates[0].Person.ID != 7 {
+		t.Errorf("duplicate problem has candidates %+v", problem.Candidates)
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	router := newTestRouter(personUsecase.NewPersonUsecase(memoryRep.NewPersonRepo(), &dto.DuplicateRules{}))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantType   string
+	}{
+		{"missing person", http.MethodGet, "/api/people/5", http.StatusNotFound, "/problems/not-found"},
+		{"unknown route", http.MethodGet, "/api/unknown", http.StatusNotFound, "about:blank"},
+		{"wrong method", http.MethodPut, "/api/people/stats", http.StatusMethodNotAllowed, "about:blank"},
+		{"bad If-Match", http.MethodDelete, "/api/people/5", http.StatusPreconditionFailed, "about:blank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("If-Match", "5")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != problemType {
+				t.Errorf("Content-Type = %q, want %q", got, problemType)
+			}
+			problem := &Problem{}
+			if err := json.Unmarshal(rec.Body.Bytes(), problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != tt.wantStatus || problem.Type != tt.wantType || problem.Instance != tt.target {
+				t.Errorf("problem = %+v, want %d %s at %s", problem, tt.wantStatus, tt.wantType, tt.target)
+			}
+		})
+	}
+}
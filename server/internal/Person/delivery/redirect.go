@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//redirectMerged redirects requests for people merged into other people to the survivor.
+//GET and HEAD get 301, other methods get 308 so clients repeat them with the same method and body
+func (handler *PersonHandler) redirectMerged(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		strid := mux.Vars(r)["id"]
+		id, err := strconv.ParseUint(strid, 10, 64)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		targetID, err := handler.persons.GetRedirect(uint(id))
+		if err != nil {
+			handler.writeError(w, r, "problems with getting redirect", err)
+			return
+		}
+		if targetID == 0 {
+			next(w, r)
+			return
+		}
+
+		location := "/api/people/" + strconv.FormatUint(uint64(targetID), 10) + strings.TrimPrefix(r.URL.Path, "/api/people/"+strid)
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
+		}
+
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, location, status)
+	}
+}
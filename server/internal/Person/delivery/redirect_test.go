@@ -0,0 +1,57 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	memoryRep "server/server/internal/Person/repository/memory"
+	personUsecase "server/server/internal/Person/usecase"
+	"server/server/internal/domain/dto"
+	"strings"
+	"testing"
+)
+
+func TestRedirectMerged(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	for _, name := range []string{"Ivan", "Ivan"} {
+		_, err := repo.CreatePerson(&dto.DBGetPerson{Name: name, Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := repo.MergePerson(1, 2, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(personUsecase.NewPersonUsecase(repo, &dto.DuplicateRules{}))
+
+	tests := []struct {
+		method       string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{http.MethodGet, "/api/people/1?fields=name", http.StatusMovedPermanently, "/api/people/2?fields=name"},
+		{http.MethodHead, "/api/people/1", http.StatusMovedPermanently, "/api/people/2"},
+		//changes keep their method and body
+		{http.MethodPut, "/api/people/1", http.StatusPermanentRedirect, "/api/people/2"},
+		{http.MethodPatch, "/api/people/1", http.StatusPermanentRedirect, "/api/people/2"},
+		{http.MethodDelete, "/api/people/1", http.StatusPermanentRedirect, "/api/people/2"},
+		{http.MethodPost, "/api/people/1/revert?version=1", http.StatusPermanentRedirect, "/api/people/2/revert?version=1"},
+		{http.MethodPost, "/api/people/1/merge", http.StatusPermanentRedirect, "/api/people/2/merge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{"age":31}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if location := rec.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"net/http"
+	"server/server/internal/domain/dto"
+)
+
+//flushEvery is a count of people written to client between flushes
+const flushEvery = 100
+
+//ndjsonType is a content type of newline delimited JSON
+const ndjsonType = "application/x-ndjson"
+
+//personStream writes people to response one by one in the chosen format
+type personStream struct {
+	list    listEncoder
+	flusher http.Flusher
+	count   int
+}
+
+func newPersonStream(w http.ResponseWriter, f *format) *personStream {
+	w.Header().Set("Content-Type", f.mediaTypes[0])
+	stream := &personStream{
+		list: f.encoder.List(w),
+	}
+	stream.flusher, _ = w.(http.Flusher)
+	return stream
+}
+
+//Started reports whether response was started, after that its status can not be changed
+func (stream *personStream) Started() bool {
+	return stream.count != 0
+}
+
+//Write writes the next person
+func (stream *personStream) Write(person *dto.Person) error {
+	err := stream.list.Write(person)
+	if err != nil {
+		return err
+	}
+
+	stream.count++
+	if stream.count%flushEvery == 0 && stream.flusher != nil {
+		stream.flusher.Flush()
+	}
+	return nil
+}
+
+//Close finishes the list
+func (stream *personStream) Close() error {
+	return stream.list.Close()
+}
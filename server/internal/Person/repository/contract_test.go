@@ -0,0 +1,674 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"server/server/db"
+	personRep "server/server/internal/Person/repository"
+	personMemRep "server/server/internal/Person/repository/memory"
+	personPgRep "server/server/internal/Person/repository/postgres"
+	personSQLiteRep "server/server/internal/Person/repository/sqlite"
+	"server/server/internal/domain/dto"
+	"server/server/internal/migrate"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+//newRepoFunc creates an empty repository for one test
+type newRepoFunc func(t *testing.T) personRep.PersonRepositoryI
+
+var change = &dto.ChangeInfo{Actor: "contract-test"}
+
+func TestMemoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T) personRep.PersonRepositoryI {
+		return personMemRep.NewPersonRepo()
+	})
+}
+
+//TestSQLiteContract runs against a new database file and a new in-memory database for every test
+func TestSQLiteContract(t *testing.T) {
+	migrations, err := migrate.Load(db.SQLiteMigrations, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := map[string]func(t *testing.T) string{
+		"file":   func(t *testing.T) string { return filepath.Join(t.TempDir(), "person.db") },
+		"memory": func(t *testing.T) string { return ":memory:" },
+	}
+	for name, path := range paths {
+		path := path
+		t.Run(name, func(t *testing.T) {
+			runContract(t, func(t *testing.T) personRep.PersonRepositoryI {
+				writeDB, readDB, err := personSQLiteRep.Open(path(t))
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() {
+					readDB.Close()
+					writeDB.Close()
+				})
+
+				err = migrate.NewMigrator(writeDB, migrate.DialectSQLite, migrations).Up()
+				if err != nil {
+					t.Fatal(err)
+				}
+				return personSQLiteRep.NewPersonRepo(writeDB, readDB)
+			})
+		})
+	}
+}
+
+//TestPostgresContract runs against database of TEST_DATABASE_URL, its tables are truncated before every test
+func TestPostgresContract(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	database, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	migrations, err := migrate.Load(db.PostgresMigrations, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrate.NewMigrator(database, migrate.DialectPostgres, migrations).Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRepo := func(t *testing.T) personRep.PersonRepositoryI {
+		_, err := database.Exec(`TRUNCATE person, person_history, person_redirect RESTART IDENTITY`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return personPgRep.NewPersonRepo(database)
+	}
+	runContract(t, newRepo)
+	//other stores serialize writes, so their records are always committed in order of ids
+	t.Run("FeedWaitsForRunningTransactions", func(t *testing.T) {
+		testFeedWaitsForRunningTransactions(t, newRepo(t))
+	})
+}
+
+//runContract checks behaviour which every implementation of PersonRepositoryI must share
+func runContract(t *testing.T, newRepo newRepoFunc) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo personRep.PersonRepositoryI)
+	}{
+		{"CreateGetUpdateDelete", testCreateGetUpdateDelete},
+		{"SoftDeleteRestore", testSoftDeleteRestore},
+		{"PurgeDeleted", testPurgeDeleted},
+		{"ListFilters", testListFilters},
+		{"Pagination", testPagination},
+		{"VersionConflicts", testVersionConflicts},
+		{"BulkCreate", testBulkCreate},
+		{"TxRollback", testTxRollback},
+		{"History", testHistory},
+		{"Search", testSearch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+func newPerson(name string, surname string, age uint, gender string, nation string) *dto.DBGetPerson {
+	return &dto.DBGetPerson{
+		Name:            name,
+		Surname:         surname,
+		Patronymic:      sql.NullString{String: "Ivanovich", Valid: true},
+		Age:             age,
+		Gender:          gender,
+		Nation:          nation,
+		NamePhonetic:    []string{"000000"},
+		SurnamePhonetic: []string{"111111"},
+	}
+}
+
+func mustCreate(t *testing.T, repo personRep.PersonRepositoryI, person *dto.DBGetPerson) uint {
+	t.Helper()
+	id, err := repo.CreatePerson(person, change)
+	if err != nil {
+		t.Fatalf("CreatePerson: %v", err)
+	}
+	return id
+}
+
+func mustGet(t *testing.T, repo personRep.PersonRepositoryI, id uint) *dto.DBGetPerson {
+	t.Helper()
+	person, err := repo.GetPersonById(id)
+	if err != nil {
+		t.Fatalf("GetPersonById(%d): %v", id, err)
+	}
+	if person == nil {
+		t.Fatalf("GetPersonById(%d) found nothing", id)
+	}
+	return person
+}
+
+func ids(persons []*dto.DBGetPerson) []uint {
+	res := []uint{}
+	for _, person := range persons {
+		res = append(res, person.ID)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func equalIDs(got []uint, want ...uint) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testCreateGetUpdateDelete(t *testing.T, repo personRep.PersonRepositoryI) {
+	id := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+
+	person := mustGet(t, repo, id)
+	if person.Name != "Ivan" || person.Surname != "Petrov" || person.Patronymic.String != "Ivanovich" ||
+		person.Age != 30 || person.Gender != "male" || person.Nation != "RU" {
+		t.Fatalf("created person is %+v", person)
+	}
+	if person.Version != 1 || person.DeletedAt.Valid || person.CreatedAt.IsZero() {
+		t.Fatalf("created person has version %d, deleted %v, created at %v", person.Version, person.DeletedAt.Valid, person.CreatedAt)
+	}
+	person.Name = "Pyotr"
+	person.Age = 31
+	version, err := repo.UpdatePerson(person, change)
+	if err != nil {
+		t.Fatalf("UpdatePerson: %v", err)
+	}
+	if version != 2 {
+		t.Fatalf("version after update is %d, want 2", version)
+	}
+	person = mustGet(t, repo, id)
+	if person.Name != "Pyotr" || person.Age != 31 || person.Version != 2 {
+		t.Fatalf("updated person is %+v", person)
+	}
+
+	err = repo.DeletePerson(id, 0, change)
+	if err != nil {
+		t.Fatalf("DeletePerson: %v", err)
+	}
+	err = repo.DeletePerson(id, 0, change)
+	if !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("second DeletePerson error is %v, want ErrNotFound", err)
+	}
+	err = repo.DeletePerson(id+100, 0, change)
+	if !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("DeletePerson of missing person error is %v, want ErrNotFound", err)
+	}
+
+	missing, err := repo.GetPersonById(id + 100)
+	if err != nil || missing != nil {
+		t.Fatalf("GetPersonById of missing person is %v, %v", missing, err)
+	}
+}
+
+func testSoftDeleteRestore(t *testing.T, repo personRep.PersonRepositoryI) {
+	kept := mustCreate(t, repo, newPerson("Anna", "Smith", 25, "female", "GB"))
+	deleted := mustCreate(t, repo, newPerson("Olga", "Ivanova", 40, "female", "RU"))
+
+	err := repo.DeletePerson(deleted, 1, change)
+	if err != nil {
+		t.Fatalf("DeletePerson: %v", err)
+	}
+
+	person := mustGet(t, repo, deleted)
+	if !person.DeletedAt.Valid || person.Version != 2 {
+		t.Fatalf("deleted person has deleted %v and version %d", person.DeletedAt.Valid, person.Version)
+	}
+
+	persons, err := repo.GetPersons(&dto.PersonFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(persons); !equalIDs(got, kept) {
+		t.Fatalf("GetPersons = %v, want only %d", got, kept)
+	}
+	persons, err = repo.GetPersons(&dto.PersonFilter{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(persons); !equalIDs(got, kept, deleted) {
+		t.Fatalf("GetPersons with deleted = %v, want %d and %d", got, kept, deleted)
+	}
+
+	_, err = repo.UpdatePerson(person, change)
+	if !errors.Is(err, dto.ErrVersionMismatch) {
+		t.Fatalf("UpdatePerson of deleted person error is %v, want ErrVersionMismatch", err)
+	}
+
+	err = repo.RestorePerson(deleted, change)
+	if err != nil {
+		t.Fatalf("RestorePerson: %v", err)
+	}
+	person = mustGet(t, repo, deleted)
+	if person.DeletedAt.Valid || person.Version != 3 {
+		t.Fatalf("restored person has deleted %v and version %d", person.DeletedAt.Valid, person.Version)
+	}
+
+	err = repo.RestorePerson(kept, change)
+	if !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("RestorePerson of not deleted person error is %v, want ErrNotFound", err)
+	}
+}
+
+func testPurgeDeleted(t *testing.T, repo personRep.PersonRepositoryI) {
+	kept := mustCreate(t, repo, newPerson("Anna", "Smith", 25, "female", "GB"))
+	deleted := mustCreate(t, repo, newPerson("Olga", "Ivanova", 40, "female", "RU"))
+	err := repo.DeletePerson(deleted, 0, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := repo.PurgeDeletedPersons(time.Now().Add(-time.Hour), change)
+	if err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedPersons before deletion = %d, %v", purged, err)
+	}
+	purged, err = repo.PurgeDeletedPersons(time.Now().Add(time.Hour), change)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedPersons after deletion = %d, %v", purged, err)
+	}
+
+	person, err := repo.GetPersonById(deleted)
+	if err != nil || person != nil {
+		t.Fatalf("purged person is %v, %v", person, err)
+	}
+	mustGet(t, repo, kept)
+}
+
+func testListFilters(t *testing.T, repo personRep.PersonRepositoryI) {
+	ivan := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+	anna := mustCreate(t, repo, newPerson("Anna", "Smith", 25, "female", "GB"))
+	time.Sleep(20 * time.Millisecond)
+	middle := time.Now()
+	time.Sleep(20 * time.Millisecond)
+	olga := mustCreate(t, repo, newPerson("Olga", "Ivanova", 30, "female", "RU"))
+	minAge, maxAge := uint(26), uint(29)
+
+	tests := []struct {
+		name string
+		list func() ([]*dto.DBGetPerson, error)
+		want []uint
+	}{
+		{"age", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersonsByAge(30, &dto.PersonFilter{})
+		}, []uint{ivan, olga}},
+		{"gender", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersonsByGender("female", &dto.PersonFilter{})
+		}, []uint{anna, olga}},
+		{"nation", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersonsByNation("GB", &dto.PersonFilter{})
+		}, []uint{anna}},
+		{"created_after", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersons(&dto.PersonFilter{CreatedAfter: &middle})
+		}, []uint{olga}},
+		{"age and created_after", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersonsByAge(30, &dto.PersonFilter{CreatedAfter: &middle})
+		}, []uint{olga}},
+		{"no match", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersonsByNation("US", &dto.PersonFilter{})
+		}, []uint{}},
+		//fields of filter ignore case
+		{"name and surname", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersons(&dto.PersonFilter{Name: "ivan", Surname: "PETROV"})
+		}, []uint{ivan}},
+		{"gender and nation", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersons(&dto.PersonFilter{Gender: "Female", Nation: "ru"})
+		}, []uint{olga}},
+		{"min age", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersons(&dto.PersonFilter{MinAge: &minAge})
+		}, []uint{ivan, olga}},
+		{"max age", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersons(&dto.PersonFilter{MaxAge: &maxAge})
+		}, []uint{anna}},
+		{"age out of bounds", func() ([]*dto.DBGetPerson, error) {
+			return repo.GetPersons(&dto.PersonFilter{MinAge: &minAge, MaxAge: &maxAge})
+		}, []uint{}},
+	}
+	for _, tt := range tests {
+		persons, err := tt.list()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ids(persons); !equalIDs(got, tt.want...) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	updated := time.Now()
+	time.Sleep(20 * time.Millisecond)
+	person := mustGet(t, repo, ivan)
+	person.Age = 31
+	_, err := repo.UpdatePerson(person, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	persons, err := repo.GetPersons(&dto.PersonFilter{UpdatedSince: &updated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(persons); !equalIDs(got, ivan) {
+		t.Errorf("updated_since = %v, want %v", got, []uint{ivan})
+	}
+}
+
+func testPagination(t *testing.T, repo personRep.PersonRepositoryI) {
+	created := []uint{}
+	for i := 0; i < 5; i++ {
+		created = append(created, mustCreate(t, repo, newPerson("Ivan", "Petrov", uint(20+i), "male", "RU")))
+	}
+	err := repo.DeletePerson(created[1], 0, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	persons, err := repo.GetPersonsWithLimit(2, &dto.PersonFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(persons) != 2 {
+		t.Fatalf("GetPersonsWithLimit(2) got %d people", len(persons))
+	}
+	for _, person := range persons {
+		if person.DeletedAt.Valid {
+			t.Fatalf("GetPersonsWithLimit returned deleted person %d", person.ID)
+		}
+	}
+
+	persons, err = repo.GetPersonsWithLimit(10, &dto.PersonFilter{IncludeDeleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(persons) != 5 {
+		t.Fatalf("GetPersonsWithLimit(10) with deleted got %d people", len(persons))
+	}
+
+	//keyset pages skip the deleted person and are counted without the page
+	filter := &dto.PersonFilter{Limit: 2}
+	pages := [][]uint{}
+	for {
+		persons, err = repo.GetPersons(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(persons) == 0 {
+			break
+		}
+		pages = append(pages, ids(persons))
+		filter.After = persons[len(persons)-1].ID
+	}
+	if len(pages) != 2 || !equalIDs(pages[0], created[0], created[2]) || !equalIDs(pages[1], created[3], created[4]) {
+		t.Fatalf("pages of GetPersons = %v", pages)
+	}
+	count, err := repo.CountPersons(filter)
+	if err != nil || count != 4 {
+		t.Fatalf("CountPersons = %d, %v, want 4", count, err)
+	}
+
+	streamed := []uint{}
+	err = repo.StreamPersons(context.Background(), &dto.PersonFilter{}, func(person *dto.DBGetPerson) error {
+		streamed = append(streamed, person.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalIDs(streamed, created[0], created[2], created[3], created[4]) {
+		t.Fatalf("StreamPersons = %v", streamed)
+	}
+
+	//error of fn stops streaming
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.StreamPersons(context.Background(), &dto.PersonFilter{}, func(person *dto.DBGetPerson) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("StreamPersons with failing fn = %v after %d people", err, calls)
+	}
+}
+
+func testVersionConflicts(t *testing.T, repo personRep.PersonRepositoryI) {
+	id := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+
+	first := mustGet(t, repo, id)
+	second := mustGet(t, repo, id)
+
+	first.Age = 31
+	_, err := repo.UpdatePerson(first, change)
+	if err != nil {
+		t.Fatalf("first UpdatePerson: %v", err)
+	}
+	second.Age = 32
+	_, err = repo.UpdatePerson(second, change)
+	if !errors.Is(err, dto.ErrVersionMismatch) {
+		t.Fatalf("stale UpdatePerson error is %v, want ErrVersionMismatch", err)
+	}
+	if person := mustGet(t, repo, id); person.Age != 31 {
+		t.Fatalf("stale update changed age to %d", person.Age)
+	}
+
+	err = repo.DeletePerson(id, 1, change)
+	if !errors.Is(err, dto.ErrVersionMismatch) {
+		t.Fatalf("stale DeletePerson error is %v, want ErrVersionMismatch", err)
+	}
+	err = repo.DeletePerson(id, 2, change)
+	if err != nil {
+		t.Fatalf("DeletePerson of current version: %v", err)
+	}
+	err = repo.DeletePerson(id, 3, change)
+	if !errors.Is(err, dto.ErrVersionMismatch) {
+		t.Fatalf("DeletePerson of deleted person with version error is %v, want ErrVersionMismatch", err)
+	}
+}
+
+func testBulkCreate(t *testing.T, repo personRep.PersonRepositoryI) {
+	persons := []*dto.DBGetPerson{}
+	for i := 0; i < 3; i++ {
+		persons = append(persons, newPerson("Ivan", "Petrov", uint(20+i), "male", "RU"))
+	}
+	created, err := repo.CreatePersons(persons, change)
+	if err != nil {
+		t.Fatalf("CreatePersons: %v", err)
+	}
+	if len(created) != len(persons) {
+		t.Fatalf("CreatePersons returned %d ids for %d people", len(created), len(persons))
+	}
+	for i, id := range created {
+		person := mustGet(t, repo, id)
+		if person.Age != persons[i].Age || person.Version != 1 {
+			t.Fatalf("person %d of bulk insert has age %d and version %d", i, person.Age, person.Version)
+		}
+	}
+}
+
+func testTxRollback(t *testing.T, repo personRep.PersonRepositoryI) {
+	id := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+
+	failure := errors.New("failure")
+	var createdInTx uint
+	err := repo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		person, err := txRepo.GetPersonByIdForUpdate(id)
+		if err != nil {
+			return err
+		}
+		person.Age = 99
+		_, err = txRepo.UpdatePerson(person, change)
+		if err != nil {
+			return err
+		}
+		createdInTx, err = txRepo.CreatePerson(newPerson("Anna", "Smith", 25, "female", "GB"), change)
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithTx error is %v, want the error of fn", err)
+	}
+
+	person := mustGet(t, repo, id)
+	if person.Age != 30 || person.Version != 1 {
+		t.Fatalf("rolled back update left age %d and version %d", person.Age, person.Version)
+	}
+	rolledBack, err := repo.GetPersonById(createdInTx)
+	if err != nil || rolledBack != nil {
+		t.Fatalf("rolled back creation left %v, %v", rolledBack, err)
+	}
+	history, err := repo.GetPersonHistory(id)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("history after rollback has %d records, %v", len(history), err)
+	}
+
+	err = repo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		person, err := txRepo.GetPersonByIdForUpdate(id)
+		if err != nil {
+			return err
+		}
+		person.Age = 31
+		_, err = txRepo.UpdatePerson(person, change)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if person := mustGet(t, repo, id); person.Age != 31 {
+		t.Fatalf("committed update left age %d", person.Age)
+	}
+}
+
+func testHistory(t *testing.T, repo personRep.PersonRepositoryI) {
+	id := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+	person := mustGet(t, repo, id)
+	person.Age = 31
+	_, err := repo.UpdatePerson(person, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.DeletePerson(id, 0, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := repo.GetPersonHistory(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations := []string{dto.OperationCreate, dto.OperationUpdate, dto.OperationDelete}
+	if len(history) != len(operations) {
+		t.Fatalf("history has %d records, want %d", len(history), len(operations))
+	}
+	for i, record := range history {
+		if record.Operation != operations[i] || record.Version != uint(i+1) || record.Actor != change.Actor {
+			t.Errorf("record %d is %s of version %d by %s", i, record.Operation, record.Version, record.Actor)
+		}
+	}
+
+	record, err := repo.GetHistoryByVersion(id, 2)
+	if err != nil || record == nil || record.Operation != dto.OperationUpdate {
+		t.Fatalf("GetHistoryByVersion(2) = %+v, %v", record, err)
+	}
+
+	since, err := repo.GetHistorySince(dto.FeedPosition{TxID: history[0].TxID, ID: history[0].ID}, 10)
+	if err != nil || len(since) != 2 {
+		t.Fatalf("GetHistorySince got %d records, %v", len(since), err)
+	}
+}
+
+func testSearch(t *testing.T, repo personRep.PersonRepositoryI) {
+	ivan := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+	other := newPerson("Anna", "Smith", 25, "female", "GB")
+	other.NamePhonetic = []string{"222222"}
+	other.SurnamePhonetic = []string{"333333"}
+	mustCreate(t, repo, other)
+	deleted := mustCreate(t, repo, newPerson("Ivan", "Petrov", 40, "male", "RU"))
+	err := repo.DeletePerson(deleted, 0, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		search *dto.PersonSearch
+		want   []uint
+	}{
+		{"exact ignores case", &dto.PersonSearch{Mode: dto.SearchModeExact, Name: "ivan", Surname: "PETROV"}, []uint{ivan}},
+		{"exact patronymic", &dto.PersonSearch{Mode: dto.SearchModeExact, Name: "Ivan", Patronymic: "ivanovich"}, []uint{ivan}},
+		{"exact no match", &dto.PersonSearch{Mode: dto.SearchModeExact, Name: "Iван"}, []uint{}},
+		{"phonetic", &dto.PersonSearch{Mode: dto.SearchModePhonetic, Name: "Iwan", NameCodes: []string{"999999", "000000"},
+			Surname: "Petroff", SurnameCodes: []string{"111111"}}, []uint{ivan}},
+		{"phonetic no match", &dto.PersonSearch{Mode: dto.SearchModePhonetic, Name: "Iwan", NameCodes: []string{"999999"}}, []uint{}},
+	}
+	for _, tt := range tests {
+		persons, err := repo.SearchPersons(tt.search)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ids(persons); !equalIDs(got, tt.want...) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+//testFeedWaitsForRunningTransactions checks that the change feed does not pass records of transactions
+//which commit after later records
+func testFeedWaitsForRunningTransactions(t *testing.T, repo personRep.PersonRepositoryI) {
+	started := make(chan struct{})
+	commit := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- repo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+			_, err := txRepo.CreatePerson(newPerson("Anna", "Smith", 25, "female", "GB"), change)
+			close(started)
+			<-commit
+			return err
+		})
+	}()
+	<-started
+
+	//the record of Ivan is committed while the earlier transaction is running
+	ivan := mustCreate(t, repo, newPerson("Ivan", "Petrov", 30, "male", "RU"))
+	records, err := repo.GetHistorySince(dto.FeedPosition{}, 10)
+	if err != nil || len(records) != 0 {
+		close(commit)
+		t.Fatalf("feed got %d records while earlier transaction is running, %v", len(records), err)
+	}
+
+	close(commit)
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err = repo.GetHistorySince(dto.FeedPosition{}, 10)
+	if err != nil || len(records) != 2 || records[1].PersonID != ivan {
+		t.Fatalf("feed got %+v, %v, want records of Anna and Ivan", records, err)
+	}
+	last := records[1]
+	records, err = repo.GetHistorySince(dto.FeedPosition{TxID: last.TxID, ID: last.ID}, 10)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("feed got %d records after the last one, %v", len(records), err)
+	}
+}
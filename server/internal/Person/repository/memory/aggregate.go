@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"server/server/internal/domain/dto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Aggregate groups people passing filter and computes metrics of groups
+func (repo *personStore) Aggregate(aggregate *dto.Aggregate, filter *dto.PersonFilter) (*dto.AggregateResult, error) {
+	repo.mu.RLock()
+	persons := repo.filterPersons(filter, func(*dto.DBGetPerson) bool { return true })
+	repo.mu.RUnlock()
+
+	type group struct {
+		values []string
+		ages   []uint
+	}
+	groups := map[string]*group{}
+	keys := []string{}
+	for _, person := range persons {
+		values := []string{}
+		for _, dimension := range aggregate.GroupBy {
+			switch dimension {
+			case dto.DimensionNation:
+				values = append(values, person.Nation)
+			case dto.DimensionGender:
+				values = append(values, person.Gender)
+			case dto.DimensionAge:
+				values = append(values, strconv.FormatUint(uint64(person.Age), 10))
+			default:
+				return nil, dto.ErrInvalidAggregate
+			}
+		}
+
+		key := strings.Join(values, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			g = &group{values: values}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.ages = append(g.ages, person.Age)
+	}
+	//without dimensions all people are one group even if there are no people
+	if len(aggregate.GroupBy) == 0 && len(keys) == 0 {
+		groups[""] = &group{}
+		keys = append(keys, "")
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return lessGroup(aggregate.GroupBy, groups[keys[i]].values, groups[keys[j]].values)
+	})
+
+	result := &dto.AggregateResult{GroupBy: aggregate.GroupBy, Metrics: aggregate.Metrics, Rows: []*dto.AggregateRow{}}
+	for _, key := range keys {
+		g := groups[key]
+		row := &dto.AggregateRow{Group: map[string]string{}, Metrics: map[string]float64{}}
+		for i, dimension := range aggregate.GroupBy {
+			row.Group[dimension] = g.values[i]
+		}
+		for _, metric := range aggregate.Metrics {
+			value, ok := ageMetric(metric, g.ages)
+			if !ok {
+				return nil, dto.ErrInvalidAggregate
+			}
+			row.Metrics[metric] = value
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+//lessGroup orders groups like ORDER BY of dimensions, ages are compared as numbers
+func lessGroup(dimensions []string, first []string, second []string) bool {
+	for i, dimension := range dimensions {
+		if first[i] == second[i] {
+			continue
+		}
+		if dimension == dto.DimensionAge {
+			firstAge, _ := strconv.ParseUint(first[i], 10, 64)
+			secondAge, _ := strconv.ParseUint(second[i], 10, 64)
+			return firstAge < secondAge
+		}
+		return first[i] < second[i]
+	}
+	return false
+}
+
+func ageMetric(metric string, ages []uint) (float64, bool) {
+	if metric == dto.MetricCount {
+		return float64(len(ages)), true
+	}
+	if len(ages) == 0 {
+		return 0, metric == dto.MetricAvgAge || metric == dto.MetricMinAge || metric == dto.MetricMaxAge
+	}
+
+	sum, youngest, oldest := ages[0], ages[0], ages[0]
+	for _, age := range ages[1:] {
+		sum += age
+		if age < youngest {
+			youngest = age
+		}
+		if age > oldest {
+			oldest = age
+		}
+	}
+	switch metric {
+	case dto.MetricAvgAge:
+		return float64(sum) / float64(len(ages)), true
+	case dto.MetricMinAge:
+		return float64(youngest), true
+	case dto.MetricMaxAge:
+		return float64(oldest), true
+	}
+	return 0, false
+}
@@ -0,0 +1,598 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"server/server/internal/domain/dto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//PersonRepo keeps people in memory, it is used to run the server without postgres.
+//Writes wait for the running transaction, so its rollback does not revert them. Reads do not wait for it
+//and are not isolated: they see changes of the running transaction before it commits, including ones
+//which are rolled back later
+type PersonRepo struct {
+	txMu sync.Mutex
+	*personStore
+}
+
+//personStore is a content of PersonRepo, journal records changes while transaction runs
+type personStore struct {
+	mu            sync.RWMutex
+	persons       map[uint]*dto.DBGetPerson
+	history       []*dto.DBHistoryRecord
+	redirects     map[uint]uint
+	nextID        uint
+	nextHistoryID uint
+	journal       *journal
+}
+
+//snapshot is a content of PersonRepo saved to file
+type snapshot struct {
+	NextID        uint                   `json:"next_id"`
+	NextHistoryID uint                   `json:"next_history_id"`
+	Persons       []*dto.DBGetPerson     `json:"persons"`
+	History       []*dto.DBHistoryRecord `json:"history"`
+	Redirects     map[uint]uint          `json:"redirects"`
+}
+
+//NewPersonRepo creates new empty object of Person repo
+func NewPersonRepo() *PersonRepo {
+	return &PersonRepo{
+		personStore: &personStore{
+			persons:       map[uint]*dto.DBGetPerson{},
+			history:       []*dto.DBHistoryRecord{},
+			redirects:     map[uint]uint{},
+			nextID:        1,
+			nextHistoryID: 1,
+		},
+	}
+}
+
+//Load reads people from JSON snapshot, missing file means empty repo
+func (repo *PersonRepo) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	snap := &snapshot{}
+	err = json.Unmarshal(data, snap)
+	if err != nil {
+		return err
+	}
+
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.persons = map[uint]*dto.DBGetPerson{}
+	for _, person := range snap.Persons {
+		repo.persons[person.ID] = person
+	}
+	repo.history = snap.History
+	repo.redirects = snap.Redirects
+	if repo.redirects == nil {
+		repo.redirects = map[uint]uint{}
+	}
+	repo.nextID = snap.NextID
+	repo.nextHistoryID = snap.NextHistoryID
+	for id := range repo.persons {
+		if id >= repo.nextID {
+			repo.nextID = id + 1
+		}
+	}
+	for _, record := range repo.history {
+		if record.ID >= repo.nextHistoryID {
+			repo.nextHistoryID = record.ID + 1
+		}
+	}
+	return nil
+}
+
+//Save writes people to JSON snapshot
+func (repo *personStore) Save(path string) error {
+	repo.mu.RLock()
+	snap := &snapshot{
+		NextID:        repo.nextID,
+		NextHistoryID: repo.nextHistoryID,
+		Persons:       repo.filter(true, func(*dto.DBGetPerson) bool { return true }),
+		History:       repo.history,
+		Redirects:     repo.redirects,
+	}
+	data, err := json.Marshal(snap)
+	repo.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+//GetPersons gets info about people ordered by id, a page is read when filter has Limit
+func (repo *personStore) GetPersons(filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	persons := repo.filterPersons(filter, func(person *dto.DBGetPerson) bool { return person.ID > filter.After })
+	if filter.Limit != 0 && uint(len(persons)) > filter.Limit {
+		persons = persons[:filter.Limit]
+	}
+	return copyPersons(persons), nil
+}
+
+//CountPersons counts people passing filter, the page of filter is not counted
+func (repo *personStore) CountPersons(filter *dto.PersonFilter) (uint, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return uint(len(repo.filterPersons(filter, func(*dto.DBGetPerson) bool { return true }))), nil
+}
+
+//StreamPersons passes people to fn one by one, iteration stops when fn returns error or ctx is canceled
+func (repo *personStore) StreamPersons(ctx context.Context, filter *dto.PersonFilter, fn func(person *dto.DBGetPerson) error) error {
+	repo.mu.RLock()
+	persons := copyPersons(repo.filterPersons(filter, func(*dto.DBGetPerson) bool { return true }))
+	repo.mu.RUnlock()
+
+	for _, person := range persons {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn(person)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *personStore) GetPersonsByAge(age uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return copyPersons(repo.filterPersons(filter, func(person *dto.DBGetPerson) bool {
+		return person.Age == age
+	})), nil
+}
+
+func (repo *personStore) GetPersonsByGender(gender string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return copyPersons(repo.filterPersons(filter, func(person *dto.DBGetPerson) bool {
+		return person.Gender == gender
+	})), nil
+}
+
+func (repo *personStore) GetPersonsByNation(nation string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return copyPersons(repo.filterPersons(filter, func(person *dto.DBGetPerson) bool {
+		return person.Nation == nation
+	})), nil
+}
+
+func (repo *personStore) GetPersonsWithLimit(limit uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	persons := repo.filterPersons(filter, func(*dto.DBGetPerson) bool { return true })
+	if uint(len(persons)) > limit {
+		persons = persons[:limit]
+	}
+	return copyPersons(persons), nil
+}
+
+//GetPersonById gets person by id including deleted one, deletion is reported by DeletedAt
+func (repo *personStore) GetPersonById(id uint) (*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	person, ok := repo.persons[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyPerson(person), nil
+}
+
+//DeletePerson marks person as deleted, the person is removed later by PurgeDeletedPersons.
+//Non-zero version must match the current version of person
+func (repo *personStore) DeletePerson(id uint, version uint, change *dto.ChangeInfo) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	person, ok := repo.persons[id]
+	if !ok || person.DeletedAt.Valid || (version != 0 && person.Version != version) {
+		if version != 0 {
+			return dto.ErrVersionMismatch
+		}
+		return dto.ErrNotFound
+	}
+
+	repo.journal.touchPerson(repo.persons, id)
+	before := copyPerson(person)
+	person.DeletedAt.Time = time.Now()
+	person.DeletedAt.Valid = true
+	person.UpdatedAt = person.DeletedAt
+	person.Version++
+	return repo.addHistory(id, dto.OperationDelete, person.Version, before, person, change)
+}
+
+//RestorePerson clears deletion mark of person and its redirect if person was merged
+func (repo *personStore) RestorePerson(id uint, change *dto.ChangeInfo) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	person, ok := repo.persons[id]
+	if !ok || !person.DeletedAt.Valid {
+		return dto.ErrNotFound
+	}
+
+	repo.journal.touchPerson(repo.persons, id)
+	repo.journal.touchRedirect(repo.redirects, id)
+	before := copyPerson(person)
+	person.DeletedAt.Valid = false
+	person.DeletedAt.Time = time.Time{}
+	person.UpdatedAt.Time = time.Now()
+	person.UpdatedAt.Valid = true
+	person.Version++
+	delete(repo.redirects, id)
+	return repo.addHistory(id, dto.OperationRestore, person.Version, before, person, change)
+}
+
+//PurgeDeletedPersons removes people deleted before the given time
+func (repo *personStore) PurgeDeletedPersons(before time.Time, change *dto.ChangeInfo) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	var purged int64
+	for _, person := range repo.filter(true, func(person *dto.DBGetPerson) bool {
+		return person.DeletedAt.Valid && person.DeletedAt.Time.Before(before)
+	}) {
+		repo.journal.touchPerson(repo.persons, person.ID)
+		delete(repo.persons, person.ID)
+		err := repo.addHistory(person.ID, dto.OperationPurge, person.Version, person, nil, change)
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+//UpdatePerson updates person if its version was not changed since reading and returns the new version
+func (repo *personStore) UpdatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	stored, ok := repo.persons[person.ID]
+	if !ok || stored.DeletedAt.Valid || stored.Version != person.Version {
+		return 0, dto.ErrVersionMismatch
+	}
+
+	repo.journal.touchPerson(repo.persons, stored.ID)
+	before := copyPerson(stored)
+	stored.Name = person.Name
+	stored.Surname = person.Surname
+	stored.Patronymic = person.Patronymic
+	stored.Age = person.Age
+	stored.Gender = person.Gender
+	stored.Nation = person.Nation
+	stored.NamePhonetic = person.NamePhonetic
+	stored.SurnamePhonetic = person.SurnamePhonetic
+	stored.UpdatedAt.Time = time.Now()
+	stored.UpdatedAt.Valid = true
+	stored.Version++
+	err := repo.addHistory(stored.ID, dto.OperationUpdate, stored.Version, before, stored, change)
+	if err != nil {
+		return 0, err
+	}
+	return stored.Version, nil
+}
+
+func (repo *personStore) CreatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	stored := copyPerson(person)
+	stored.ID = repo.nextID
+	stored.Version = 1
+	stored.DeletedAt.Valid = false
+	stored.CreatedAt = time.Now()
+	stored.UpdatedAt.Time = stored.CreatedAt
+	stored.UpdatedAt.Valid = true
+	repo.nextID++
+	repo.journal.touchPerson(repo.persons, stored.ID)
+	repo.persons[stored.ID] = stored
+
+	err := repo.addHistory(stored.ID, dto.OperationCreate, stored.Version, nil, stored, change)
+	if err != nil {
+		return 0, err
+	}
+	return stored.ID, nil
+}
+
+//CreatePersons creates people one by one, ids are returned in order of persons
+func (repo *personStore) CreatePersons(persons []*dto.DBGetPerson, change *dto.ChangeInfo) ([]uint, error) {
+	ids := make([]uint, 0, len(persons))
+	for _, person := range persons {
+		id, err := repo.CreatePerson(person, change)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+//MergePerson retires source person merged into target one and redirects id of source to target.
+//Redirects to source are moved to target, so chains of merges are resolved by one redirect
+func (repo *personStore) MergePerson(sourceID uint, targetID uint, change *dto.ChangeInfo) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	person, ok := repo.persons[sourceID]
+	if !ok || person.DeletedAt.Valid {
+		return dto.ErrNotFound
+	}
+
+	repo.journal.touchPerson(repo.persons, sourceID)
+	before := copyPerson(person)
+	person.DeletedAt.Time = time.Now()
+	person.DeletedAt.Valid = true
+	person.UpdatedAt = person.DeletedAt
+	person.Version++
+
+	repo.journal.touchRedirect(repo.redirects, targetID)
+	delete(repo.redirects, targetID)
+	for id, target := range repo.redirects {
+		if target == sourceID {
+			repo.journal.touchRedirect(repo.redirects, id)
+			repo.redirects[id] = targetID
+		}
+	}
+	repo.journal.touchRedirect(repo.redirects, sourceID)
+	repo.redirects[sourceID] = targetID
+	return repo.addHistory(sourceID, dto.OperationMerge, person.Version, before, person, change)
+}
+
+//GetRedirect gets id of person the given person was merged into, 0 if it was not merged
+func (repo *personStore) GetRedirect(id uint) (uint, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.redirects[id], nil
+}
+
+//SearchPersons searches people by names exactly or by phonetic codes
+func (repo *personStore) SearchPersons(search *dto.PersonSearch) ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return copyPersons(repo.filter(false, func(person *dto.DBGetPerson) bool {
+		if search.Mode == dto.SearchModePhonetic {
+			if search.Name != "" && !overlap(person.NamePhonetic, search.NameCodes) {
+				return false
+			}
+			if search.Surname != "" && !overlap(person.SurnamePhonetic, search.SurnameCodes) {
+				return false
+			}
+		} else {
+			if search.Name != "" && strings.ToLower(person.Name) != strings.ToLower(search.Name) {
+				return false
+			}
+			if search.Surname != "" && strings.ToLower(person.Surname) != strings.ToLower(search.Surname) {
+				return false
+			}
+		}
+		if search.Patronymic != "" && (!person.Patronymic.Valid ||
+			strings.ToLower(person.Patronymic.String) != strings.ToLower(search.Patronymic)) {
+			return false
+		}
+		return true
+	})), nil
+}
+
+//GetDistinctNames gets all different names of people
+func (repo *personStore) GetDistinctNames() ([]string, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.distinct(func(person *dto.DBGetPerson) string { return person.Name }), nil
+}
+
+//GetDistinctSurnames gets all different surnames of people
+func (repo *personStore) GetDistinctSurnames() ([]string, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return repo.distinct(func(person *dto.DBGetPerson) string { return person.Surname }), nil
+}
+
+//GetPersonsWithoutPhonetic gets people whose phonetic codes were not computed yet
+func (repo *personStore) GetPersonsWithoutPhonetic() ([]*dto.DBGetPerson, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return copyPersons(repo.filter(true, func(person *dto.DBGetPerson) bool {
+		return person.NamePhonetic == nil || person.SurnamePhonetic == nil
+	})), nil
+}
+
+//UpdatePersonPhonetic saves phonetic codes of person names
+func (repo *personStore) UpdatePersonPhonetic(person *dto.DBGetPerson) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	stored, ok := repo.persons[person.ID]
+	if ok {
+		repo.journal.touchPerson(repo.persons, person.ID)
+		stored.NamePhonetic = person.NamePhonetic
+		stored.SurnamePhonetic = person.SurnamePhonetic
+		stored.UpdatedAt.Time = time.Now()
+		stored.UpdatedAt.Valid = true
+	}
+	return nil
+}
+
+//GetPersonHistory gets all changes of person from the oldest one
+func (repo *personStore) GetPersonHistory(id uint) ([]*dto.DBHistoryRecord, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	records := []*dto.DBHistoryRecord{}
+	for _, record := range repo.history {
+		if record.PersonID == id {
+			records = append(records, copyRecord(record))
+		}
+	}
+	return records, nil
+}
+
+//GetHistoryByVersion gets change of person which produced the given version
+func (repo *personStore) GetHistoryByVersion(id uint, version uint) (*dto.DBHistoryRecord, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	for i := len(repo.history) - 1; i >= 0; i-- {
+		record := repo.history[i]
+		if record.PersonID == id && record.Version == version && record.After != nil {
+			return copyRecord(record), nil
+		}
+	}
+	return nil, nil
+}
+
+//GetLatestHistory gets the last change of every person made not later than asOf
+func (repo *personStore) GetLatestHistory(asOf time.Time) ([]*dto.DBHistoryRecord, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	latest := map[uint]*dto.DBHistoryRecord{}
+	for _, record := range repo.history {
+		if !record.ChangedAt.After(asOf) {
+			latest[record.PersonID] = record
+		}
+	}
+	records := []*dto.DBHistoryRecord{}
+	for _, record := range latest {
+		records = append(records, copyRecord(record))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].PersonID < records[j].PersonID
+	})
+	return records, nil
+}
+
+//GetHistorySince gets changes of all people made after the given one. Writes are serialized,
+//so records are committed in order of ids
+func (repo *personStore) GetHistorySince(after dto.FeedPosition, limit uint) ([]*dto.DBHistoryRecord, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	records := []*dto.DBHistoryRecord{}
+	for _, record := range repo.history {
+		if uint(len(records)) == limit {
+			break
+		}
+		if record.ID > after.ID {
+			records = append(records, copyRecord(record))
+		}
+	}
+	return records, nil
+}
+
+//filter gets people matching the condition ordered by id, caller must hold the lock
+func (repo *personStore) filter(includeDeleted bool, match func(person *dto.DBGetPerson) bool) []*dto.DBGetPerson {
+	persons := []*dto.DBGetPerson{}
+	for _, person := range repo.persons {
+		if (includeDeleted || !person.DeletedAt.Valid) && match(person) {
+			persons = append(persons, person)
+		}
+	}
+	sort.Slice(persons, func(i, j int) bool {
+		return persons[i].ID < persons[j].ID
+	})
+	return persons
+}
+
+//filterPersons gets people passing filter and matching the condition, caller must hold the lock
+func (repo *personStore) filterPersons(filter *dto.PersonFilter, match func(person *dto.DBGetPerson) bool) []*dto.DBGetPerson {
+	return repo.filter(filter.IncludeDeleted, func(person *dto.DBGetPerson) bool {
+		return filter.Match(dto.ToPerson(person)) && match(person)
+	})
+}
+
+func (repo *personStore) distinct(field func(person *dto.DBGetPerson) string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, person := range repo.filter(false, func(*dto.DBGetPerson) bool { return true }) {
+		value := field(person)
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+//addHistory saves change of person, caller must hold the lock
+func (repo *personStore) addHistory(personID uint, operation string, version uint, before *dto.DBGetPerson, after *dto.DBGetPerson, change *dto.ChangeInfo) error {
+	record := &dto.DBHistoryRecord{
+		ID:        repo.nextHistoryID,
+		PersonID:  personID,
+		Operation: operation,
+		Version:   version,
+		Actor:     change.Actor,
+		RequestID: change.RequestID,
+		ChangedAt: time.Now(),
+	}
+
+	var err error
+	if before != nil {
+		record.Before, err = json.Marshal(dto.ToPerson(before))
+		if err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		record.After, err = json.Marshal(dto.ToPerson(after))
+		if err != nil {
+			return err
+		}
+	}
+
+	repo.nextHistoryID++
+	repo.history = append(repo.history, record)
+	return nil
+}
+
+func overlap(codes []string, searched []string) bool {
+	for _, code := range codes {
+		for _, s := range searched {
+			if code == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func copyPerson(person *dto.DBGetPerson) *dto.DBGetPerson {
+	res := *person
+	res.NamePhonetic = copyCodes(person.NamePhonetic)
+	res.SurnamePhonetic = copyCodes(person.SurnamePhonetic)
+	return &res
+}
+
+func copyCodes(codes []string) []string {
+	if codes == nil {
+		return nil
+	}
+	return append([]string{}, codes...)
+}
+
+func copyPersons(persons []*dto.DBGetPerson) []*dto.DBGetPerson {
+	res := make([]*dto.DBGetPerson, 0, len(persons))
+	for _, person := range persons {
+		res = append(res, copyPerson(person))
+	}
+	return res
+}
+
+func copyRecord(record *dto.DBHistoryRecord) *dto.DBHistoryRecord {
+	res := *record
+	return &res
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"server/server/internal/domain/dto"
+	"sort"
+)
+
+//GetStats computes statistics of people passing filter, bounds of age buckets start from 0
+func (repo *personStore) GetStats(filter *dto.PersonFilter, bounds []uint, top uint) (*dto.Stats, error) {
+	repo.mu.RLock()
+	persons := repo.filterPersons(filter, func(*dto.DBGetPerson) bool { return true })
+	repo.mu.RUnlock()
+
+	stats := &dto.Stats{
+		Total: int64(len(persons)),
+		Age:   &dto.AgeStats{Histogram: dto.NewAgeHistogram(bounds)},
+	}
+
+	ages := []uint{}
+	genders := map[string]int64{}
+	nations := map[string]int64{}
+	var sum uint
+	for _, person := range persons {
+		ages = append(ages, person.Age)
+		sum += person.Age
+		genders[person.Gender]++
+		nations[person.Nation]++
+		for i := len(bounds) - 1; i >= 0; i-- {
+			if person.Age >= bounds[i] || i == 0 {
+				stats.Age.Histogram[i].Count++
+				break
+			}
+		}
+	}
+
+	if len(ages) != 0 {
+		sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+		stats.Age.Mean = float64(sum) / float64(len(ages))
+		middle := len(ages) / 2
+		stats.Age.Median = float64(ages[middle])
+		if len(ages)%2 == 0 {
+			stats.Age.Median = float64(ages[middle-1]+ages[middle]) / 2
+		}
+	}
+
+	stats.Genders = countValues(genders, 0)
+	stats.TopNations = countValues(nations, top)
+	return stats, nil
+}
+
+//countValues sorts counts like ORDER BY COUNT(*) DESC, value LIMIT limit, zero limit means no limit
+func countValues(counts map[string]int64, limit uint) []*dto.ValueCount {
+	res := []*dto.ValueCount{}
+	for value, count := range counts {
+		res = append(res, &dto.ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Value < res[j].Value
+	})
+	if limit != 0 && uint(len(res)) > limit {
+		res = res[:limit]
+	}
+	return res
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+	"time"
+)
+
+//journal keeps state of people and redirects changed by transaction before their first change,
+//rollback restores only them. Nil person or missing redirect means it did not exist
+type journal struct {
+	persons       map[uint]*dto.DBGetPerson
+	redirects     map[uint]*uint
+	historyLen    int
+	nextID        uint
+	nextHistoryID uint
+}
+
+//txPersonRepo is a repo bound to the running transaction, it writes without waiting for transaction lock
+type txPersonRepo struct {
+	*personStore
+}
+
+//WithTx runs fn with repo bound to the transaction, fn runs exclusively with other transactions and writes.
+//Changes are rolled back if fn returns error or panics. Reads outside of the transaction see its uncommitted changes
+func (repo *PersonRepo) WithTx(fn func(txRepo personRep.PersonRepositoryI) error) (err error) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+
+	repo.begin()
+	defer func() {
+		if p := recover(); p != nil {
+			repo.rollback()
+			panic(p)
+		}
+		if err != nil {
+			repo.rollback()
+			return
+		}
+		repo.commit()
+	}()
+
+	return fn(&txPersonRepo{repo.personStore})
+}
+
+//WithTx runs fn in the already opened transaction
+func (repo *txPersonRepo) WithTx(fn func(txRepo personRep.PersonRepositoryI) error) error {
+	return fn(repo)
+}
+
+//GetPersonByIdForUpdate gets person by id, transactions are exclusive so the person is not changed by other ones
+func (repo *personStore) GetPersonByIdForUpdate(id uint) (*dto.DBGetPerson, error) {
+	return repo.GetPersonById(id)
+}
+
+//Writes of PersonRepo wait for the running transaction, writes of txPersonRepo are a part of it
+
+func (repo *PersonRepo) DeletePerson(id uint, version uint, change *dto.ChangeInfo) error {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.DeletePerson(id, version, change)
+}
+
+func (repo *PersonRepo) RestorePerson(id uint, change *dto.ChangeInfo) error {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.RestorePerson(id, change)
+}
+
+func (repo *PersonRepo) PurgeDeletedPersons(before time.Time, change *dto.ChangeInfo) (int64, error) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.PurgeDeletedPersons(before, change)
+}
+
+func (repo *PersonRepo) UpdatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.UpdatePerson(person, change)
+}
+
+func (repo *PersonRepo) CreatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.CreatePerson(person, change)
+}
+
+func (repo *PersonRepo) CreatePersons(persons []*dto.DBGetPerson, change *dto.ChangeInfo) ([]uint, error) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.CreatePersons(persons, change)
+}
+
+func (repo *PersonRepo) MergePerson(sourceID uint, targetID uint, change *dto.ChangeInfo) error {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.MergePerson(sourceID, targetID, change)
+}
+
+func (repo *PersonRepo) UpdatePersonPhonetic(person *dto.DBGetPerson) error {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.personStore.UpdatePersonPhonetic(person)
+}
+
+func (repo *personStore) begin() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.journal = &journal{
+		persons:       map[uint]*dto.DBGetPerson{},
+		redirects:     map[uint]*uint{},
+		historyLen:    len(repo.history),
+		nextID:        repo.nextID,
+		nextHistoryID: repo.nextHistoryID,
+	}
+}
+
+func (repo *personStore) commit() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.journal = nil
+}
+
+//rollback reverts changes of transaction, other writes wait for it so history ends with its records
+func (repo *personStore) rollback() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	for id, person := range repo.journal.persons {
+		if person == nil {
+			delete(repo.persons, id)
+		} else {
+			repo.persons[id] = person
+		}
+	}
+	for id, target := range repo.journal.redirects {
+		if target == nil {
+			delete(repo.redirects, id)
+		} else {
+			repo.redirects[id] = *target
+		}
+	}
+	repo.history = repo.history[:repo.journal.historyLen]
+	repo.nextID = repo.journal.nextID
+	repo.nextHistoryID = repo.journal.nextHistoryID
+	repo.journal = nil
+}
+
+//touchPerson saves person before its first change in transaction, caller must hold the lock
+func (j *journal) touchPerson(persons map[uint]*dto.DBGetPerson, id uint) {
+	if j == nil {
+		return
+	}
+	if _, ok := j.persons[id]; ok {
+		return
+	}
+	var saved *dto.DBGetPerson
+	if person, ok := persons[id]; ok {
+		saved = copyPerson(person)
+	}
+	j.persons[id] = saved
+}
+
+//touchRedirect saves redirect before its first change in transaction, caller must hold the lock
+func (j *journal) touchRedirect(redirects map[uint]uint, id uint) {
+	if j == nil {
+		return
+	}
+	if _, ok := j.redirects[id]; ok {
+		return
+	}
+	var saved *uint
+	if target, ok := redirects[id]; ok {
+		saved = &target
+	}
+	j.redirects[id] = saved
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+	"testing"
+	"time"
+)
+
+func TestWriteDuringFailedTxIsKept(t *testing.T) {
+	repo := NewPersonRepo()
+	change := &dto.ChangeInfo{Actor: "test"}
+	id, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov", Age: 30}, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inTx := make(chan struct{})
+	written := make(chan error)
+	failure := errors.New("failure")
+	var created uint
+	err = repo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		person, err := txRepo.GetPersonByIdForUpdate(id)
+		if err != nil {
+			return err
+		}
+		person.Age = 99
+		_, err = txRepo.UpdatePerson(person, change)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			<-inTx
+			var err error
+			created, err = repo.CreatePerson(&dto.DBGetPerson{Name: "Anna", Surname: "Smith"}, change)
+			written <- err
+		}()
+		close(inTx)
+		//the write outside transaction must not finish before it
+		select {
+		case err := <-written:
+			t.Errorf("write finished during transaction with %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithTx error is %v", err)
+	}
+	if err = <-written; err != nil {
+		t.Fatal(err)
+	}
+
+	person, err := repo.GetPersonById(id)
+	if err != nil || person.Age != 30 {
+		t.Fatalf("rolled back person is %+v, %v", person, err)
+	}
+	anna, err := repo.GetPersonById(created)
+	if err != nil || anna == nil || anna.Name != "Anna" {
+		t.Fatalf("write during transaction is lost: %+v, %v", anna, err)
+	}
+	history, err := repo.GetPersonHistory(created)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("history of write during transaction has %d records, %v", len(history), err)
+	}
+}
+
+func TestRollbackRestoresRedirects(t *testing.T) {
+	repo := NewPersonRepo()
+	change := &dto.ChangeInfo{Actor: "test"}
+	ids := []uint{}
+	for i := 0; i < 3; i++ {
+		id, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov"}, change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	err := repo.MergePerson(ids[0], ids[1], change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("failure")
+	err = repo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		//redirect of the first person moves to the third one
+		err := txRepo.MergePerson(ids[1], ids[2], change)
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithTx error is %v", err)
+	}
+
+	for source, want := range map[uint]uint{ids[0]: ids[1], ids[1]: 0, ids[2]: 0} {
+		target, err := repo.GetRedirect(source)
+		if err != nil || target != want {
+			t.Errorf("redirect of %d is %d, want %d", source, target, want)
+		}
+	}
+	person, err := repo.GetPersonById(ids[1])
+	if err != nil || person.DeletedAt.Valid {
+		t.Fatalf("merge of rolled back transaction left %+v, %v", person, err)
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+	"server/server/internal/domain/dto"
+	"strings"
+)
+
+//aggregateColumns and aggregateFunctions translate whitelisted names to SQL
+var (
+	aggregateColumns = map[string]string{
+		dto.DimensionNation: "nation",
+		dto.DimensionGender: "gender",
+		dto.DimensionAge:    "age",
+	}
+	aggregateFunctions = map[string]string{
+		dto.MetricCount:  "COUNT(*)",
+		dto.MetricAvgAge: "AVG(age)",
+		dto.MetricMinAge: "MIN(age)",
+		dto.MetricMaxAge: "MAX(age)",
+	}
+)
+
+//Aggregate groups people passing filter and computes metrics of groups
+func (repo *PersonRepo) Aggregate(aggregate *dto.Aggregate, filter *dto.PersonFilter) (*dto.AggregateResult, error) {
+	conditions, args := personFilter(filter, nil)
+	query, err := aggregateQuery(aggregate, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAggregate(rows, aggregate)
+}
+
+//aggregateQuery builds GROUP BY query only from whitelisted columns and functions
+func aggregateQuery(aggregate *dto.Aggregate, conditions string) (string, error) {
+	columns := []string{}
+	for _, dimension := range aggregate.GroupBy {
+		column, ok := aggregateColumns[dimension]
+		if !ok {
+			return "", dto.ErrInvalidAggregate
+		}
+		columns = append(columns, column)
+	}
+	selected := append([]string{}, columns...)
+	for _, metric := range aggregate.Metrics {
+		function, ok := aggregateFunctions[metric]
+		if !ok {
+			return "", dto.ErrInvalidAggregate
+		}
+		selected = append(selected, function)
+	}
+
+	query := `SELECT ` + strings.Join(selected, ", ") + ` FROM person WHERE ` + conditions
+	if len(columns) != 0 {
+		query += ` GROUP BY ` + strings.Join(columns, ", ") + ` ORDER BY ` + strings.Join(columns, ", ")
+	}
+	return query, nil
+}
+
+func scanAggregate(rows *sql.Rows, aggregate *dto.Aggregate) (*dto.AggregateResult, error) {
+	defer rows.Close()
+	result := &dto.AggregateResult{GroupBy: aggregate.GroupBy, Metrics: aggregate.Metrics, Rows: []*dto.AggregateRow{}}
+	for rows.Next() {
+		groups := make([]sql.NullString, len(aggregate.GroupBy))
+		metrics := make([]sql.NullFloat64, len(aggregate.Metrics))
+		dest := []interface{}{}
+		for i := range groups {
+			dest = append(dest, &groups[i])
+		}
+		for i := range metrics {
+			dest = append(dest, &metrics[i])
+		}
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		row := &dto.AggregateRow{Group: map[string]string{}, Metrics: map[string]float64{}}
+		for i, dimension := range aggregate.GroupBy {
+			row.Group[dimension] = groups[i].String
+		}
+		for i, metric := range aggregate.Metrics {
+			row.Metrics[metric] = metrics[i].Float64
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
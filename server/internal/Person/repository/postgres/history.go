@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"database/sql"
+	"server/server/internal/domain/dto"
+	"time"
+)
+
+//personSnapshot is a JSON snapshot of person row saved in history, keys match dto.Person
+const personSnapshot = `jsonb_build_object('id', id, 'name', name, 'surname', surname, 'patronymic', patronymic,
+	'age', age, 'gender', gender, 'nation', nation, 'deleted_at', deleted_at, 'version', version,
+	'created_at', created_at, 'updated_at', updated_at)`
+
+//historyColumns are columns selected for dto.DBHistoryRecord
+const historyColumns = `id, tx_id, person_id, operation, version, before, after, actor, request_id, changed_at`
+
+//GetPersonHistory gets all changes of person from the oldest one
+func (repo *PersonRepo) GetPersonHistory(id uint) ([]*dto.DBHistoryRecord, error) {
+	rows, err := repo.q().Query(`SELECT `+historyColumns+` FROM person_history WHERE person_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+//GetHistoryByVersion gets change of person which produced the given version
+func (repo *PersonRepo) GetHistoryByVersion(id uint, version uint) (*dto.DBHistoryRecord, error) {
+	row := repo.q().QueryRow(`SELECT `+historyColumns+` FROM person_history
+							 WHERE person_id = $1 AND version = $2 AND after IS NOT NULL
+							 ORDER BY id DESC LIMIT 1`, id, version)
+	record, err := scanHistoryRecord(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+//GetLatestHistory gets the last change of every person made not later than asOf
+func (repo *PersonRepo) GetLatestHistory(asOf time.Time) ([]*dto.DBHistoryRecord, error) {
+	rows, err := repo.q().Query(`SELECT DISTINCT ON (person_id) `+historyColumns+` FROM person_history
+								WHERE changed_at <= $1
+								ORDER BY person_id, id DESC`, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+//GetHistorySince gets changes of all people made after the given one. Ids of bigserial are taken in order
+//of inserts, not of commits, so the feed is ordered by ids of transactions and then by ids of records.
+//Only transactions older than all running ones are read: later records have greater transaction ids,
+//so the feed never passes a record which is committed later. Long transactions delay the feed, not writes
+func (repo *PersonRepo) GetHistorySince(after dto.FeedPosition, limit uint) ([]*dto.DBHistoryRecord, error) {
+	txID := after.TxID
+	if txID == 0 && after.ID != 0 {
+		//cursors made before transaction ids were recorded have only id of record
+		err := repo.q().QueryRow(`SELECT tx_id FROM person_history WHERE id = $1`, after.ID).Scan(&txID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	rows, err := repo.q().Query(`SELECT `+historyColumns+` FROM person_history
+								WHERE (tx_id, id) > ($1, $2)
+								  AND tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+								ORDER BY tx_id, id LIMIT $3`, txID, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+//selectSnapshot locks person row and gets its snapshot, nil if there is no such person
+func selectSnapshot(tx *sql.Tx, id uint) ([]byte, error) {
+	var snapshot []byte
+	err := tx.QueryRow(`SELECT `+personSnapshot+` FROM person WHERE id = $1 FOR UPDATE`, id).Scan(&snapshot)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+//addHistory saves change of person, tx_id of record is the id of the current transaction
+func addHistory(tx *sql.Tx, personID uint, operation string, version uint, before []byte, after []byte, change *dto.ChangeInfo) error {
+	_, err := tx.Exec(`INSERT INTO person_history (person_id, operation, version, before, after, actor, request_id)
+					   VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		personID, operation, version, jsonArg(before), jsonArg(after), change.Actor, change.RequestID)
+	return err
+}
+
+//jsonArg passes snapshot as a query argument, nil snapshot becomes NULL
+func jsonArg(snapshot []byte) interface{} {
+	if snapshot == nil {
+		return nil
+	}
+	return string(snapshot)
+}
+
+func scanHistoryRecord(row scanner) (*dto.DBHistoryRecord, error) {
+	record := &dto.DBHistoryRecord{}
+	err := row.Scan(
+		&record.ID,
+		&record.TxID,
+		&record.PersonID,
+		&record.Operation,
+		&record.Version,
+		&record.Before,
+		&record.After,
+		&record.Actor,
+		&record.RequestID,
+		&record.ChangedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func scanHistory(rows *sql.Rows) ([]*dto.DBHistoryRecord, error) {
+	defer rows.Close()
+	records := []*dto.DBHistoryRecord{}
+	for rows.Next() {
+		record, err := scanHistoryRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"database/sql"
+	"server/server/internal/domain/dto"
+)
+
+//MergePerson retires source person merged into target one and redirects id of source to target.
+//Redirects to source are moved to target, so chains of merges are resolved by one redirect
+func (repo *PersonRepo) MergePerson(sourceID uint, targetID uint, change *dto.ChangeInfo) error {
+	retirePerson := `UPDATE person SET deleted_at = NOW(), version = version + 1
+				   WHERE id = $1 AND deleted_at IS NULL
+				   RETURNING version, ` + personSnapshot
+	return repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, sourceID)
+		if err != nil {
+			return err
+		}
+
+		var version uint
+		var after []byte
+		err = tx.QueryRow(retirePerson, sourceID).Scan(&version, &after)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return dto.ErrNotFound
+			}
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM person_redirect WHERE source_id = $1 OR source_id = $2`, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE person_redirect SET target_id = $2 WHERE target_id = $1`, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO person_redirect (source_id, target_id) VALUES ($1, $2)`, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+
+		return addHistory(tx, sourceID, dto.OperationMerge, version, before, after, change)
+	})
+}
+
+//GetRedirect gets id of person the given person was merged into, 0 if it was not merged
+func (repo *PersonRepo) GetRedirect(id uint) (uint, error) {
+	var targetID uint
+	err := repo.q().QueryRow(`SELECT target_id FROM person_redirect WHERE source_id = $1`, id).Scan(&targetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return targetID, nil
+}
@@ -1,14 +1,23 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	//"server/internal/domain/dto"
 	"server/server/internal/domain/dto"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+//personColumns are columns selected for dto.DBGetPerson
+const personColumns = `id, name, surname, patronymic, age, gender, nation, deleted_at, version, created_at, updated_at`
+
 //PersonRepo struct
 type PersonRepo struct {
 	DB *sql.DB
+	tx *sql.Tx
 }
 
 //NewPersonRepo creates new object of Person repo
@@ -18,193 +27,419 @@ func NewPersonRepo(db *sql.DB) *PersonRepo {
 	}
 }
 
-//GetPersons gets info about people
-func (repo *PersonRepo) GetPersons() ([]*dto.DBGetPerson, error) {
-	rows, err := repo.DB.Query(`SELECT id, name, surname, patronymic, age, gender, nation 
-								FROM person`)
+//GetPersons gets info about people ordered by id, a page is read when filter has Limit
+func (repo *PersonRepo) GetPersons(filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, nil)
+	args = append(args, filter.After)
+	conditions += fmt.Sprintf(" AND id > $%d ORDER BY id", len(args))
+	if filter.Limit != 0 {
+		args = append(args, filter.Limit)
+		conditions += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	rows, err := repo.q().Query(`SELECT `+personColumns+`
+								FROM person WHERE `+conditions, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+	return scanPersons(rows)
+}
+
+//CountPersons counts people passing filter, the page of filter is not counted
+func (repo *PersonRepo) CountPersons(filter *dto.PersonFilter) (uint, error) {
+	conditions, args := personFilter(filter, nil)
+	var count uint
+	err := repo.q().QueryRow(`SELECT count(*) FROM person WHERE `+conditions, args...).Scan(&count)
+	return count, err
+}
+
+//StreamPersons passes people to fn one by one while reading them from database,
+//reading stops when fn returns error or ctx is canceled
+func (repo *PersonRepo) StreamPersons(ctx context.Context, filter *dto.PersonFilter, fn func(person *dto.DBGetPerson) error) error {
+	conditions, args := personFilter(filter, nil)
+	rows, err := repo.q().QueryContext(ctx, `SELECT `+personColumns+` FROM person WHERE `+conditions+` ORDER BY id`, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
-	var Persons = []*dto.DBGetPerson{}
 	for rows.Next() {
-		person := &dto.DBGetPerson{}
-		err = rows.Scan(
-			&person.ID,
-			&person.Name,
-			&person.Surname,
-			&person.Patronymic,
-			&person.Age,
-			&person.Gender,
-			&person.Nation,
-		)
+		person, err := scanPerson(rows)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		err = fn(person)
+		if err != nil {
+			return err
 		}
-		Persons = append(Persons, person)
 	}
-	return Persons, nil
+	return rows.Err()
 }
 
-func (repo *PersonRepo) GetPersonsByAge(age uint) ([]*dto.DBGetPerson, error) {
-	rows, err := repo.DB.Query(`SELECT id, name, surname, patronymic, age, gender, nation FROM person WHERE age = $1`, age)
+func (repo *PersonRepo) GetPersonsByAge(age uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, []interface{}{age})
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE age = $1 AND `+conditions, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
-	defer rows.Close()
-	var Persons = []*dto.DBGetPerson{}
-	for rows.Next() {
-		person := &dto.DBGetPerson{}
-		err = rows.Scan(
-			&person.ID,
-			&person.Name,
-			&person.Surname,
-			&person.Patronymic,
-			&person.Age,
-			&person.Gender,
-			&person.Nation,
-		)
+	return scanPersons(rows)
+}
+
+func (repo *PersonRepo) GetPersonsByGender(gender string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, []interface{}{gender})
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE gender = $1 AND `+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+func (repo *PersonRepo) GetPersonsByNation(nation string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, []interface{}{nation})
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE nation = $1 AND `+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+func (repo *PersonRepo) GetPersonsWithLimit(limit uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, nil)
+	args = append(args, limit)
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE `+conditions+fmt.Sprintf(` LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+//DeletePerson marks person as deleted, the row is removed later by PurgeDeletedPersons.
+//Non-zero version must match the current version of person
+func (repo *PersonRepo) DeletePerson(id uint, version uint, change *dto.ChangeInfo) error {
+	deletePerson := `UPDATE person SET deleted_at = NOW(), version = version + 1
+				   WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+				   RETURNING version, ` + personSnapshot
+	return repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, id)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		Persons = append(Persons, person)
+
+		var newVersion uint
+		var after []byte
+		err = tx.QueryRow(deletePerson, id, version).Scan(&newVersion, &after)
+		if err != nil {
+			if err == sql.ErrNoRows && version != 0 {
+				return dto.ErrVersionMismatch
+			}
+			if err == sql.ErrNoRows {
+				return dto.ErrNotFound
+			}
+			return err
+		}
+
+		return addHistory(tx, id, dto.OperationDelete, newVersion, before, after, change)
+	})
+}
+
+//RestorePerson clears deletion mark of person and its redirect if person was merged
+func (repo *PersonRepo) RestorePerson(id uint, change *dto.ChangeInfo) error {
+	restorePerson := `UPDATE person SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL
+					RETURNING version, ` + personSnapshot
+	return repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var version uint
+		var after []byte
+		err = tx.QueryRow(restorePerson, id).Scan(&version, &after)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return dto.ErrNotFound
+			}
+			return err
+		}
+
+		//restored person is not merged anymore
+		_, err = tx.Exec(`DELETE FROM person_redirect WHERE source_id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		return addHistory(tx, id, dto.OperationRestore, version, before, after, change)
+	})
+}
+
+//PurgeDeletedPersons removes people deleted before the given time
+func (repo *PersonRepo) PurgeDeletedPersons(before time.Time, change *dto.ChangeInfo) (int64, error) {
+	var purged int64
+	err := repo.inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`DELETE FROM person WHERE deleted_at IS NOT NULL AND deleted_at < $1
+							   RETURNING id, version, `+personSnapshot, before)
+		if err != nil {
+			return err
+		}
+
+		records := []*dto.DBHistoryRecord{}
+		for rows.Next() {
+			record := &dto.DBHistoryRecord{}
+			err = rows.Scan(&record.PersonID, &record.Version, &record.Before)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			records = append(records, record)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			err = addHistory(tx, record.PersonID, dto.OperationPurge, record.Version, record.Before, nil, change)
+			if err != nil {
+				return err
+			}
+		}
+		purged = int64(len(records))
+		return nil
+	})
+	return purged, err
+}
+
+//UpdatePerson updates person if its version was not changed since reading and returns the new version
+func (repo *PersonRepo) UpdatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	updatePerson := `UPDATE person
+				   SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nation = $6,
+				       name_phonetic = $7, surname_phonetic = $8, version = version + 1
+				   WHERE id = $9 AND version = $10 AND deleted_at IS NULL
+				   RETURNING version, ` + personSnapshot
+	var version uint
+	err := repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, person.ID)
+		if err != nil {
+			return err
+		}
+
+		var after []byte
+		err = tx.QueryRow(updatePerson, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation,
+			pq.Array(person.NamePhonetic), pq.Array(person.SurnamePhonetic), person.ID, person.Version).Scan(&version, &after)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return dto.ErrVersionMismatch
+			}
+			return err
+		}
+
+		return addHistory(tx, person.ID, dto.OperationUpdate, version, before, after, change)
+	})
+	if err != nil {
+		return 0, err
 	}
-	return Persons, nil
+	return version, nil
 }
 
-func (repo *PersonRepo) GetPersonsByGender(gender string) ([]*dto.DBGetPerson, error) {
-	rows, err := repo.DB.Query(`SELECT id, name, surname, patronymic, age, gender, nation FROM person WHERE gender = $1`, gender)
+//GetPersonById gets person by id including deleted one, deletion is reported by DeletedAt
+func (repo *PersonRepo) GetPersonById(id uint) (*dto.DBGetPerson, error) {
+	row := repo.q().QueryRow(`SELECT `+personColumns+` FROM person WHERE id = $1`, id)
+	person, err := scanPerson(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	defer rows.Close()
-	var Persons = []*dto.DBGetPerson{}
-	for rows.Next() {
-		person := &dto.DBGetPerson{}
-		err = rows.Scan(
-			&person.ID,
-			&person.Name,
-			&person.Surname,
-			&person.Patronymic,
-			&person.Age,
-			&person.Gender,
-			&person.Nation,
-		)
+	return person, nil
+}
+
+func (repo *PersonRepo) CreatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	insertPerson := `INSERT INTO person (name, surname, patronymic, age, gender, nation, name_phonetic, surname_phonetic)
+					 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, version, ` + personSnapshot
+	var ID uint
+	err := repo.inTx(func(tx *sql.Tx) error {
+		var version uint
+		var after []byte
+		err := tx.QueryRow(insertPerson, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation,
+			pq.Array(person.NamePhonetic), pq.Array(person.SurnamePhonetic)).Scan(&ID, &version, &after)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		Persons = append(Persons, person)
+
+		return addHistory(tx, ID, dto.OperationCreate, version, nil, after, change)
+	})
+	if err != nil {
+		return 0, err
 	}
-	return Persons, nil
+
+	return ID, nil
 }
 
-func (repo *PersonRepo) GetPersonsByNation(nation string) ([]*dto.DBGetPerson, error) {
-	rows, err := repo.DB.Query(`SELECT id, name, surname, patronymic, age, gender, nation FROM person WHERE nation = $1`, nation)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+//SearchPersons searches people by names exactly or by phonetic codes
+func (repo *PersonRepo) SearchPersons(search *dto.PersonSearch) ([]*dto.DBGetPerson, error) {
+	query := `SELECT ` + personColumns + ` FROM person WHERE deleted_at IS NULL`
+	args := []interface{}{}
+
+	if search.Mode == dto.SearchModePhonetic {
+		if search.Name != "" {
+			args = append(args, pq.Array(search.NameCodes))
+			query += fmt.Sprintf(" AND name_phonetic && $%d", len(args))
+		}
+		if search.Surname != "" {
+			args = append(args, pq.Array(search.SurnameCodes))
+			query += fmt.Sprintf(" AND surname_phonetic && $%d", len(args))
+		}
+	} else {
+		if search.Name != "" {
+			args = append(args, search.Name)
+			query += fmt.Sprintf(" AND lower(name) = lower($%d)", len(args))
+		}
+		if search.Surname != "" {
+			args = append(args, search.Surname)
+			query += fmt.Sprintf(" AND lower(surname) = lower($%d)", len(args))
 		}
+	}
+	if search.Patronymic != "" {
+		args = append(args, search.Patronymic)
+		query += fmt.Sprintf(" AND lower(patronymic) = lower($%d)", len(args))
+	}
+
+	rows, err := repo.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+//GetDistinctNames gets all different names of people
+func (repo *PersonRepo) GetDistinctNames() ([]string, error) {
+	return repo.getDistinct(`SELECT DISTINCT name FROM person WHERE deleted_at IS NULL`)
+}
+
+//GetDistinctSurnames gets all different surnames of people
+func (repo *PersonRepo) GetDistinctSurnames() ([]string, error) {
+	return repo.getDistinct(`SELECT DISTINCT surname FROM person WHERE deleted_at IS NULL`)
+}
+
+func (repo *PersonRepo) getDistinct(query string) ([]string, error) {
+	rows, err := repo.q().Query(query)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var Persons = []*dto.DBGetPerson{}
+	values := []string{}
 	for rows.Next() {
-		person := &dto.DBGetPerson{}
-		err = rows.Scan(
-			&person.ID,
-			&person.Name,
-			&person.Surname,
-			&person.Patronymic,
-			&person.Age,
-			&person.Gender,
-			&person.Nation,
-		)
+		var value string
+		err = rows.Scan(&value)
 		if err != nil {
 			return nil, err
 		}
-		Persons = append(Persons, person)
+		values = append(values, value)
 	}
-	return Persons, nil
+	return values, rows.Err()
 }
 
-func (repo *PersonRepo) GetPersonsWithLimit(limit uint) ([]*dto.DBGetPerson, error) {
-	rows, err := repo.DB.Query(`SELECT id, name, surname, patronymic, age, gender, nation FROM person LIMIT $1`, limit)
+//GetPersonsWithoutPhonetic gets people whose phonetic codes were not computed yet
+func (repo *PersonRepo) GetPersonsWithoutPhonetic() ([]*dto.DBGetPerson, error) {
+	rows, err := repo.q().Query(`SELECT id, name, surname FROM person WHERE name_phonetic IS NULL OR surname_phonetic IS NULL`)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
 	defer rows.Close()
 	var Persons = []*dto.DBGetPerson{}
 	for rows.Next() {
 		person := &dto.DBGetPerson{}
-		err = rows.Scan(
-			&person.ID,
-			&person.Name,
-			&person.Surname,
-			&person.Patronymic,
-			&person.Age,
-			&person.Gender,
-			&person.Nation,
-		)
+		err = rows.Scan(&person.ID, &person.Name, &person.Surname)
 		if err != nil {
 			return nil, err
 		}
 		Persons = append(Persons, person)
 	}
-	return Persons, nil
+	return Persons, rows.Err()
 }
 
-func (repo *PersonRepo) DeletePerson(id uint) error {
-	deletePerson := `DELETE FROM person WHERE id = $1`
-	_, err := repo.DB.Exec(deletePerson, id)
-	if err != nil {
-		return err
-	}
-	return nil
+//UpdatePersonPhonetic saves phonetic codes of person names
+func (repo *PersonRepo) UpdatePersonPhonetic(person *dto.DBGetPerson) error {
+	_, err := repo.q().Exec(`UPDATE person SET name_phonetic = $1, surname_phonetic = $2 WHERE id = $3`,
+		pq.Array(person.NamePhonetic), pq.Array(person.SurnamePhonetic), person.ID)
+	return err
 }
 
-func (repo *PersonRepo) UpdatePerson(person *dto.DBGetPerson) error {
-	updatePerson := `UPDATE person 
-				   SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nation = $6
-				   WHERE id = $7`
-	_, err := repo.DB.Exec(updatePerson, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation, person.ID)
-	if err != nil {
-		return err
-	}
-	return nil
+//scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (repo *PersonRepo) GetPersonById(id uint) (*dto.DBGetPerson, error) {
+func scanPerson(row scanner) (*dto.DBGetPerson, error) {
 	person := &dto.DBGetPerson{}
-	row := repo.DB.QueryRow(`SELECT id, name, surname, patronymic, age, gender, nation FROM person WHERE id = $1`, id)
-	err := row.Scan(&person.ID, &person.Name, &person.Surname, &person.Patronymic, &person.Age, &person.Gender, &person.Nation)
+	err := row.Scan(
+		&person.ID,
+		&person.Name,
+		&person.Surname,
+		&person.Patronymic,
+		&person.Age,
+		&person.Gender,
+		&person.Nation,
+		&person.DeletedAt,
+		&person.Version,
+		&person.CreatedAt,
+		&person.UpdatedAt,
+	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
 	return person, nil
 }
 
-func (repo *PersonRepo) CreatePerson(person *dto.DBGetPerson) (uint, error) {
-	insertPerson := `INSERT INTO person (name, surname, patronymic, age, gender, nation) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	var ID uint
-	err := repo.DB.QueryRow(insertPerson, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation).Scan(&ID)
-	if err != nil {
-		return 0, err
+func scanPersons(rows *sql.Rows) ([]*dto.DBGetPerson, error) {
+	defer rows.Close()
+	var Persons = []*dto.DBGetPerson{}
+	for rows.Next() {
+		person, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		Persons = append(Persons, person)
 	}
+	return Persons, rows.Err()
+}
 
-	return ID, nil
+//filterField is a column compared with a text field of filter
+type filterField struct {
+	column string
+	value  string
+}
+
+func filterFields(filter *dto.PersonFilter) []filterField {
+	return []filterField{{"name", filter.Name}, {"surname", filter.Surname}, {"gender", filter.Gender}, {"nation", filter.Nation}}
+}
+
+//personFilter builds conditions of filter, placeholders are numbered after args
+func personFilter(filter *dto.PersonFilter, args []interface{}) (string, []interface{}) {
+	conditions := "deleted_at IS NULL"
+	if filter.IncludeDeleted {
+		conditions = "TRUE"
+	}
+	if filter.CreatedAfter != nil {
+		args = append(args, *filter.CreatedAfter)
+		conditions += fmt.Sprintf(" AND created_at > $%d", len(args))
+	}
+	if filter.UpdatedSince != nil {
+		args = append(args, *filter.UpdatedSince)
+		conditions += fmt.Sprintf(" AND updated_at >= $%d", len(args))
+	}
+	for _, field := range filterFields(filter) {
+		if field.value != "" {
+			args = append(args, field.value)
+			conditions += fmt.Sprintf(" AND lower(%s) = lower($%d)", field.column, len(args))
+		}
+	}
+	if filter.MinAge != nil {
+		args = append(args, *filter.MinAge)
+		conditions += fmt.Sprintf(" AND age >= $%d", len(args))
+	}
+	if filter.MaxAge != nil {
+		args = append(args, *filter.MaxAge)
+		conditions += fmt.Sprintf(" AND age <= $%d", len(args))
+	}
+	return conditions, args
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+	"server/server/internal/domain/dto"
+
+	"github.com/lib/pq"
+)
+
+//GetStats computes statistics of people passing filter, bounds of age buckets start from 0
+func (repo *PersonRepo) GetStats(filter *dto.PersonFilter, bounds []uint, top uint) (*dto.Stats, error) {
+	conditions, args := personFilter(filter, nil)
+	stats := &dto.Stats{Age: &dto.AgeStats{Histogram: dto.NewAgeHistogram(bounds)}}
+
+	err := repo.q().QueryRow(`SELECT COUNT(*), COALESCE(AVG(age), 0),
+									COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY age), 0)
+							 FROM person WHERE `+conditions, args...).Scan(&stats.Total, &stats.Age.Mean, &stats.Age.Median)
+	if err != nil {
+		return nil, err
+	}
+
+	intBounds := []int64{}
+	for _, bound := range bounds {
+		intBounds = append(intBounds, int64(bound))
+	}
+	rows, err := repo.q().Query(fmt.Sprintf(`SELECT width_bucket(age, $%d::int[]) AS bucket, COUNT(*) FROM person
+											WHERE `+conditions+` GROUP BY bucket`, len(args)+1), append(args, pq.Array(intBounds))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket int
+		var count int64
+		err = rows.Scan(&bucket, &count)
+		if err != nil {
+			return nil, err
+		}
+		//width_bucket numbers buckets from 1
+		if bucket > 0 && bucket <= len(stats.Age.Histogram) {
+			stats.Age.Histogram[bucket-1].Count = count
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.Genders, err = repo.countValues(`SELECT gender, COUNT(*) FROM person WHERE `+conditions+`
+										  GROUP BY gender ORDER BY COUNT(*) DESC, gender`, args)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.TopNations, err = repo.countValues(fmt.Sprintf(`SELECT nation, COUNT(*) FROM person WHERE `+conditions+`
+														GROUP BY nation ORDER BY COUNT(*) DESC, nation LIMIT $%d`, len(args)+1), append(args, top))
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (repo *PersonRepo) countValues(query string, args []interface{}) ([]*dto.ValueCount, error) {
+	rows, err := repo.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := []*dto.ValueCount{}
+	for rows.Next() {
+		count := &dto.ValueCount{}
+		err = rows.Scan(&count.Value, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//maxTxAttempts is a maximum count of attempts to run transaction failed because of concurrent changes
+const maxTxAttempts = 3
+
+//querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//q gets transaction of repo if it is opened or database otherwise
+func (repo *PersonRepo) q() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.DB
+}
+
+//WithTx runs fn in repeatable read transaction with repo bound to it. Transaction is rolled back
+//if fn returns error or panics and is retried if it fails because of concurrent changes
+func (repo *PersonRepo) WithTx(fn func(txRepo personRep.PersonRepositoryI) error) error {
+	if repo.tx != nil {
+		return fn(repo)
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = repo.runTx(fn)
+		if !isRetryable(err) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt*10) * time.Millisecond)
+	}
+	return err
+}
+
+func (repo *PersonRepo) runTx(fn func(txRepo personRep.PersonRepositoryI) error) (err error) {
+	tx, err := repo.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(&PersonRepo{DB: repo.DB, tx: tx})
+}
+
+//inTx runs fn in transaction of repo or in a new one
+func (repo *PersonRepo) inTx(fn func(tx *sql.Tx) error) error {
+	if repo.tx != nil {
+		return fn(repo.tx)
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//GetPersonByIdForUpdate gets person by id and locks it until the end of transaction
+func (repo *PersonRepo) GetPersonByIdForUpdate(id uint) (*dto.DBGetPerson, error) {
+	row := repo.q().QueryRow(`SELECT `+personColumns+` FROM person WHERE id = $1 FOR UPDATE`, id)
+	person, err := scanPerson(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return person, nil
+}
+
+//isRetryable reports whether transaction failed because of serialization failure or deadlock
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+	return false
+}
@@ -1,17 +1,39 @@
 package repository
 
 import (
+	"context"
 	"server/server/internal/domain/dto"
+	"time"
 )
 
 type PersonRepositoryI interface {
-	GetPersons() ([]*dto.DBGetPerson, error)
+	WithTx(fn func(txRepo PersonRepositoryI) error) error
+	GetPersonByIdForUpdate(id uint) (*dto.DBGetPerson, error)
+	GetPersons(filter *dto.PersonFilter) ([]*dto.DBGetPerson, error)
+	CountPersons(filter *dto.PersonFilter) (uint, error)
+	StreamPersons(ctx context.Context, filter *dto.PersonFilter, fn func(person *dto.DBGetPerson) error) error
 	GetPersonById(id uint) (*dto.DBGetPerson, error)
-	GetPersonsByAge(age uint) ([]*dto.DBGetPerson, error)
-	GetPersonsByGender(gender string) ([]*dto.DBGetPerson, error)
-	GetPersonsByNation(nation string) ([]*dto.DBGetPerson, error)
-	GetPersonsWithLimit(limit uint) ([]*dto.DBGetPerson, error)
-	DeletePerson(id uint) error
-	UpdatePerson(person *dto.DBGetPerson) error
-	CreatePerson(person *dto.DBGetPerson) (uint, error)
+	GetPersonsByAge(age uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error)
+	GetPersonsByGender(gender string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error)
+	GetPersonsByNation(nation string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error)
+	GetPersonsWithLimit(limit uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error)
+	DeletePerson(id uint, version uint, change *dto.ChangeInfo) error
+	RestorePerson(id uint, change *dto.ChangeInfo) error
+	PurgeDeletedPersons(before time.Time, change *dto.ChangeInfo) (int64, error)
+	UpdatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error)
+	CreatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error)
+	CreatePersons(persons []*dto.DBGetPerson, change *dto.ChangeInfo) ([]uint, error)
+	MergePerson(sourceID uint, targetID uint, change *dto.ChangeInfo) error
+	GetRedirect(id uint) (uint, error)
+	SearchPersons(search *dto.PersonSearch) ([]*dto.DBGetPerson, error)
+	GetStats(filter *dto.PersonFilter, bounds []uint, top uint) (*dto.Stats, error)
+	Aggregate(aggregate *dto.Aggregate, filter *dto.PersonFilter) (*dto.AggregateResult, error)
+	GetDistinctNames() ([]string, error)
+	GetDistinctSurnames() ([]string, error)
+	GetPersonsWithoutPhonetic() ([]*dto.DBGetPerson, error)
+	UpdatePersonPhonetic(person *dto.DBGetPerson) error
+	GetPersonHistory(id uint) ([]*dto.DBHistoryRecord, error)
+	GetHistoryByVersion(id uint, version uint) (*dto.DBHistoryRecord, error)
+	GetLatestHistory(asOf time.Time) ([]*dto.DBHistoryRecord, error)
+	GetHistorySince(after dto.FeedPosition, limit uint) ([]*dto.DBHistoryRecord, error)
 }
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"database/sql"
+	"server/server/internal/domain/dto"
+	"sort"
+	"strings"
+	"time"
+)
+
+//bulkChunk is a count of people inserted by one statement, it keeps parameters of statement under the limit
+const bulkChunk = 500
+
+//insertedPerson is a row returned by bulk insert
+type insertedPerson struct {
+	id      uint
+	version uint
+	after   []byte
+}
+
+//CreatePersons inserts people by multi-row INSERT statements in one transaction,
+//ids are returned in order of persons
+func (repo *PersonRepo) CreatePersons(persons []*dto.DBGetPerson, change *dto.ChangeInfo) ([]uint, error) {
+	ids := make([]uint, 0, len(persons))
+	err := repo.inTx(func(tx *sql.Tx) error {
+		for start := 0; start < len(persons); start += bulkChunk {
+			inserted, err := insertPersons(tx, persons[start:min(start+bulkChunk, len(persons))])
+			if err != nil {
+				return err
+			}
+			err = addCreateHistory(tx, inserted, change)
+			if err != nil {
+				return err
+			}
+			for _, person := range inserted {
+				ids = append(ids, person.id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func insertPersons(tx *sql.Tx, persons []*dto.DBGetPerson) ([]*insertedPerson, error) {
+	values := []string{}
+	args := []interface{}{}
+	for _, person := range persons {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation,
+			codesArg(person.NamePhonetic), codesArg(person.SurnamePhonetic))
+	}
+
+	rows, err := tx.Query(`INSERT INTO person (name, surname, patronymic, age, gender, nation, name_phonetic, surname_phonetic)
+						   VALUES `+strings.Join(values, ", ")+` RETURNING id, version, `+personSnapshot, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := []*insertedPerson{}
+	for rows.Next() {
+		person := &insertedPerson{}
+		err = rows.Scan(&person.id, &person.version, &person.after)
+		if err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, person)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	//order of RETURNING rows is not specified, rowids are assigned in order of VALUES
+	sort.Slice(inserted, func(i, j int) bool {
+		return inserted[i].id < inserted[j].id
+	})
+	return inserted, nil
+}
+
+//addCreateHistory records creation of inserted people
+func addCreateHistory(tx *sql.Tx, inserted []*insertedPerson, change *dto.ChangeInfo) error {
+	values := []string{}
+	args := []interface{}{}
+	changedAt := time.Now().UTC()
+	for _, person := range inserted {
+		values = append(values, "(?, ?, ?, NULL, ?, ?, ?, ?)")
+		args = append(args, person.id, dto.OperationCreate, person.version, jsonArg(person.after), change.Actor, change.RequestID, changedAt)
+	}
+
+	_, err := tx.Exec(`INSERT INTO person_history (person_id, operation, version, before, after, actor, request_id, changed_at)
+					   VALUES `+strings.Join(values, ", "), args...)
+	return err
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"server/server/internal/domain/dto"
+	"time"
+)
+
+//personSnapshot is a JSON snapshot of person row saved in history, keys match dto.Person
+const personSnapshot = `json_object('id', id, 'name', name, 'surname', surname, 'patronymic', patronymic,
+	'age', age, 'gender', gender, 'nation', nation, 'deleted_at', strftime('%Y-%m-%dT%H:%M:%fZ', deleted_at), 'version', version,
+	'created_at', strftime('%Y-%m-%dT%H:%M:%fZ', created_at), 'updated_at', strftime('%Y-%m-%dT%H:%M:%fZ', updated_at))`
+
+//historyColumns are columns selected for dto.DBHistoryRecord
+const historyColumns = `id, person_id, operation, version, before, after, actor, request_id, changed_at`
+
+//GetPersonHistory gets all changes of person from the oldest one
+func (repo *PersonRepo) GetPersonHistory(id uint) ([]*dto.DBHistoryRecord, error) {
+	rows, err := repo.q().Query(`SELECT `+historyColumns+` FROM person_history WHERE person_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+//GetHistoryByVersion gets change of person which produced the given version
+func (repo *PersonRepo) GetHistoryByVersion(id uint, version uint) (*dto.DBHistoryRecord, error) {
+	row := repo.q().QueryRow(`SELECT `+historyColumns+` FROM person_history
+							 WHERE person_id = ? AND version = ? AND after IS NOT NULL
+							 ORDER BY id DESC LIMIT 1`, id, version)
+	record, err := scanHistoryRecord(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+//GetLatestHistory gets the last change of every person made not later than asOf
+func (repo *PersonRepo) GetLatestHistory(asOf time.Time) ([]*dto.DBHistoryRecord, error) {
+	rows, err := repo.q().Query(`SELECT `+historyColumns+` FROM person_history
+								WHERE id IN (SELECT MAX(id) FROM person_history WHERE changed_at <= ? GROUP BY person_id)
+								ORDER BY person_id`, asOf.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+//GetHistorySince gets changes of all people made after the given one. SQLite has one writer,
+//so records are committed in order of ids
+func (repo *PersonRepo) GetHistorySince(after dto.FeedPosition, limit uint) ([]*dto.DBHistoryRecord, error) {
+	rows, err := repo.q().Query(`SELECT `+historyColumns+` FROM person_history WHERE id > ? ORDER BY id LIMIT ?`, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+//selectSnapshot gets snapshot of person, nil if there is no such person.
+//Transactions are opened with immediate lock, so the row can not be changed concurrently
+func selectSnapshot(tx *sql.Tx, id uint) ([]byte, error) {
+	var snapshot []byte
+	err := tx.QueryRow(`SELECT `+personSnapshot+` FROM person WHERE id = ?`, id).Scan(&snapshot)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func addHistory(tx *sql.Tx, personID uint, operation string, version uint, before []byte, after []byte, change *dto.ChangeInfo) error {
+	_, err := tx.Exec(`INSERT INTO person_history (person_id, operation, version, before, after, actor, request_id, changed_at)
+					   VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		personID, operation, version, jsonArg(before), jsonArg(after), change.Actor, change.RequestID, time.Now().UTC())
+	return err
+}
+
+//jsonArg passes snapshot as a query argument, nil snapshot becomes NULL
+func jsonArg(snapshot []byte) interface{} {
+	if snapshot == nil {
+		return nil
+	}
+	return string(snapshot)
+}
+
+func scanHistoryRecord(row scanner) (*dto.DBHistoryRecord, error) {
+	record := &dto.DBHistoryRecord{}
+	err := row.Scan(
+		&record.ID,
+		&record.PersonID,
+		&record.Operation,
+		&record.Version,
+		&record.Before,
+		&record.After,
+		&record.Actor,
+		&record.RequestID,
+		&record.ChangedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func scanHistory(rows *sql.Rows) ([]*dto.DBHistoryRecord, error) {
+	defer rows.Close()
+	records := []*dto.DBHistoryRecord{}
+	for rows.Next() {
+		record, err := scanHistoryRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"path/filepath"
+	"server/server/db"
+	"server/server/internal/domain/dto"
+	"server/server/internal/migrate"
+	"testing"
+)
+
+func TestHistoryOfPeopleBeforeHistory(t *testing.T) {
+	writeDB, readDB, err := Open(filepath.Join(t.TempDir(), "person.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writeDB.Close()
+	defer readDB.Close()
+
+	migrations, err := migrate.Load(db.SQLiteMigrations, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := migrate.NewMigrator(writeDB, migrate.DialectSQLite, migrations)
+	//the last migration before history
+	err = migrator.To(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = writeDB.Exec(`INSERT INTO PERSON (NAME, SURNAME, AGE, GENDER, NATION) VALUES ('Ivan', 'Petrov', 30, 'male', 'RU')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := NewPersonRepo(writeDB, readDB).GetHistoryByVersion(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil {
+		t.Fatal("person created before history has no version to revert to")
+	}
+	history, err := dto.ToHistoryRecord(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Operation != "create" || history.After.Name != "Ivan" || history.After.Age != 30 {
+		t.Errorf("first version is %s of %+v, want creation of Ivan aged 30", history.Operation, history.After)
+	}
+}
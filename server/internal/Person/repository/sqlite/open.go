@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+)
+
+//readConns is a count of connections reading sqlite database at once
+const readConns = 4
+
+//Open opens sqlite database with one connection for writes and a pool of connections for reads.
+//Database is switched to WAL journal, so readers are not blocked by the writer and its transaction.
+//Parameters of connection may be given in the query of path, they are kept.
+//Every connection to in-memory database opens a new database, so its readers share the connection of writer
+func Open(path string) (*sql.DB, *sql.DB, error) {
+	name, rawQuery, _ := strings.Cut(path, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isMemory(name, query) {
+		writeDB, err := openDB(dsn(name, query, url.Values{"_txlock": {"immediate"}}, "busy_timeout(5000)"), 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return writeDB, writeDB, nil
+	}
+
+	//sqlite allows only one writer, so write connection is not shared between transactions.
+	//Journal mode is switched by ping before readers open the file
+	writeDB, err := openDB(dsn(name, query, url.Values{"_txlock": {"immediate"}}, "busy_timeout(5000)", "journal_mode(WAL)"), 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	readDB, err := openDB(dsn(name, query, url.Values{}, "busy_timeout(5000)", "query_only(1)"), readConns)
+	if err != nil {
+		writeDB.Close()
+		return nil, nil, err
+	}
+	return writeDB, readDB, nil
+}
+
+func openDB(dsn string, conns int) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(conns)
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+//isMemory checks that path names in-memory database, it lives only as long as its connection
+func isMemory(name string, query url.Values) bool {
+	return name == "" || name == ":memory:" || strings.HasPrefix(name, "file::memory:") || query.Get("mode") == "memory"
+}
+
+//dsn adds params and pragmas to query of path, pragmas of path are applied too
+func dsn(name string, query url.Values, params url.Values, pragmas ...string) string {
+	values := url.Values{}
+	for key, value := range query {
+		values[key] = append([]string{}, value...)
+	}
+	for key, value := range params {
+		values[key] = value
+	}
+	values["_pragma"] = append(values["_pragma"], pragmas...)
+	values.Set("_time_format", "sqlite")
+	return name + "?" + values.Encode()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"path/filepath"
+	"server/server/db"
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+	"server/server/internal/migrate"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		wantJournal     string
+		wantForeignKeys int
+		wantSharedDB    bool
+	}{
+		{"file", filepath.Join(t.TempDir(), "person.db"), "wal", 0, false},
+		{"parameters of path are kept", filepath.Join(t.TempDir(), "person.db") + "?_pragma=foreign_keys(1)", "wal", 1, false},
+		{"memory", ":memory:", "memory", 0, true},
+		{"memory with parameters", ":memory:?_pragma=foreign_keys(1)", "memory", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeDB, readDB, err := Open(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer writeDB.Close()
+			defer readDB.Close()
+
+			var journal string
+			var foreignKeys int
+			err = writeDB.QueryRow("PRAGMA journal_mode").Scan(&journal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = readDB.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if journal != tt.wantJournal || foreignKeys != tt.wantForeignKeys {
+				t.Errorf("journal_mode = %s, foreign_keys = %d, want %s, %d", journal, foreignKeys, tt.wantJournal, tt.wantForeignKeys)
+			}
+
+			//readers see tables of writer
+			_, err = writeDB.Exec("CREATE TABLE t (id INTEGER)")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = readDB.Exec("SELECT id FROM t")
+			if err != nil {
+				t.Errorf("reader does not see table of writer: %v", err)
+			}
+			if shared := writeDB == readDB; shared != tt.wantSharedDB {
+				t.Errorf("readers share the writer = %v, want %v", shared, tt.wantSharedDB)
+			}
+		})
+	}
+}
+
+func TestReadsDoNotWaitForTx(t *testing.T) {
+	writeDB, readDB, err := Open(filepath.Join(t.TempDir(), "person.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writeDB.Close()
+	defer readDB.Close()
+
+	migrations, err := migrate.Load(db.SQLiteMigrations, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrate.NewMigrator(writeDB, migrate.DialectSQLite, migrations).Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewPersonRepo(writeDB, readDB)
+	_, err = repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan", Surname: "Petrov"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inTx := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- repo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+			_, err := txRepo.CreatePerson(&dto.DBGetPerson{Name: "Anna", Surname: "Smith"}, &dto.ChangeInfo{Actor: "test"})
+			close(inTx)
+			time.Sleep(500 * time.Millisecond)
+			return err
+		})
+	}()
+	<-inTx
+
+	start := time.Now()
+	persons, err := repo.GetPersons(&dto.PersonFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Fatalf("read waited %v for transaction", elapsed)
+	}
+	//uncommitted person is not seen
+	if len(persons) != 1 {
+		t.Fatalf("read got %d people during transaction, want 1", len(persons))
+	}
+
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	persons, err = repo.GetPersons(&dto.PersonFilter{})
+	if err != nil || len(persons) != 2 {
+		t.Fatalf("read got %d people after transaction, %v", len(persons), err)
+	}
+}
@@ -0,0 +1,478 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"server/server/internal/domain/dto"
+	"time"
+)
+
+//touchUpdatedAt sets updated_at in the statement itself, because RETURNING does not see changes of set_timestamp trigger
+const touchUpdatedAt = `updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')`
+
+//streamPageSize is a count of people read at once by StreamPersons
+const streamPageSize = 500
+
+//personColumns are columns selected for dto.DBGetPerson
+const personColumns = `id, name, surname, patronymic, age, gender, nation, deleted_at, version, created_at, updated_at`
+
+//PersonRepo keeps people in sqlite database. DB is the only connection which writes,
+//ReadDB is a pool of connections for reads outside transactions
+type PersonRepo struct {
+	DB     *sql.DB
+	ReadDB *sql.DB
+	tx     *sql.Tx
+}
+
+//NewPersonRepo creates new object of Person repo
+func NewPersonRepo(db *sql.DB, readDB *sql.DB) *PersonRepo {
+	return &PersonRepo{
+		DB:     db,
+		ReadDB: readDB,
+	}
+}
+
+//GetPersons gets info about people ordered by id, a page is read when filter has Limit
+func (repo *PersonRepo) GetPersons(filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, nil)
+	args = append(args, filter.After)
+	conditions += " AND id > ? ORDER BY id"
+	if filter.Limit != 0 {
+		args = append(args, filter.Limit)
+		conditions += " LIMIT ?"
+	}
+	rows, err := repo.q().Query(`SELECT `+personColumns+`
+								FROM person WHERE `+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+//CountPersons counts people passing filter, the page of filter is not counted
+func (repo *PersonRepo) CountPersons(filter *dto.PersonFilter) (uint, error) {
+	conditions, args := personFilter(filter, nil)
+	var count uint
+	err := repo.q().QueryRow(`SELECT count(*) FROM person WHERE `+conditions, args...).Scan(&count)
+	return count, err
+}
+
+//StreamPersons passes people to fn one by one, reading stops when fn returns error or ctx is canceled.
+//Read connection is not held while fn runs, so people are read by pages of streamPageSize
+func (repo *PersonRepo) StreamPersons(ctx context.Context, filter *dto.PersonFilter, fn func(person *dto.DBGetPerson) error) error {
+	conditions, args := personFilter(filter, nil)
+	var lastID uint
+	for {
+		rows, err := repo.q().QueryContext(ctx, `SELECT `+personColumns+` FROM person WHERE `+conditions+`
+												AND id > ? ORDER BY id LIMIT ?`, append(args, lastID, streamPageSize)...)
+		if err != nil {
+			return err
+		}
+		persons, err := scanPersons(rows)
+		if err != nil {
+			return err
+		}
+
+		for _, person := range persons {
+			err = fn(person)
+			if err != nil {
+				return err
+			}
+			lastID = person.ID
+		}
+		if len(persons) < streamPageSize {
+			return nil
+		}
+	}
+}
+
+func (repo *PersonRepo) GetPersonsByAge(age uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, []interface{}{age})
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE age = ? AND `+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+func (repo *PersonRepo) GetPersonsByGender(gender string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, []interface{}{gender})
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE gender = ? AND `+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+func (repo *PersonRepo) GetPersonsByNation(nation string, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, []interface{}{nation})
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE nation = ? AND `+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+func (repo *PersonRepo) GetPersonsWithLimit(limit uint, filter *dto.PersonFilter) ([]*dto.DBGetPerson, error) {
+	conditions, args := personFilter(filter, nil)
+	rows, err := repo.q().Query(`SELECT `+personColumns+` FROM person WHERE `+conditions+` LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+//DeletePerson marks person as deleted, the row is removed later by PurgeDeletedPersons.
+//Non-zero version must match the current version of person
+func (repo *PersonRepo) DeletePerson(id uint, version uint, change *dto.ChangeInfo) error {
+	deletePerson := `UPDATE person SET deleted_at = ?3, version = version + 1, ` + touchUpdatedAt + `
+				   WHERE id = ?1 AND deleted_at IS NULL AND (?2 = 0 OR version = ?2)
+				   RETURNING version, ` + personSnapshot
+	return repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var newVersion uint
+		var after []byte
+		err = tx.QueryRow(deletePerson, id, version, time.Now().UTC()).Scan(&newVersion, &after)
+		if err != nil {
+			if err == sql.ErrNoRows && version != 0 {
+				return dto.ErrVersionMismatch
+			}
+			if err == sql.ErrNoRows {
+				return dto.ErrNotFound
+			}
+			return err
+		}
+
+		return addHistory(tx, id, dto.OperationDelete, newVersion, before, after, change)
+	})
+}
+
+//RestorePerson clears deletion mark of person and its redirect if person was merged
+func (repo *PersonRepo) RestorePerson(id uint, change *dto.ChangeInfo) error {
+	restorePerson := `UPDATE person SET deleted_at = NULL, version = version + 1, ` + touchUpdatedAt + ` WHERE id = ? AND deleted_at IS NOT NULL
+					RETURNING version, ` + personSnapshot
+	return repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var version uint
+		var after []byte
+		err = tx.QueryRow(restorePerson, id).Scan(&version, &after)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return dto.ErrNotFound
+			}
+			return err
+		}
+
+		//restored person is not merged anymore
+		_, err = tx.Exec(`DELETE FROM person_redirect WHERE source_id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		return addHistory(tx, id, dto.OperationRestore, version, before, after, change)
+	})
+}
+
+//PurgeDeletedPersons removes people deleted before the given time
+func (repo *PersonRepo) PurgeDeletedPersons(before time.Time, change *dto.ChangeInfo) (int64, error) {
+	var purged int64
+	err := repo.inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`DELETE FROM person WHERE deleted_at IS NOT NULL AND deleted_at < ?
+							   RETURNING id, version, `+personSnapshot, before.UTC())
+		if err != nil {
+			return err
+		}
+
+		records := []*dto.DBHistoryRecord{}
+		for rows.Next() {
+			record := &dto.DBHistoryRecord{}
+			err = rows.Scan(&record.PersonID, &record.Version, &record.Before)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			records = append(records, record)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			err = addHistory(tx, record.PersonID, dto.OperationPurge, record.Version, record.Before, nil, change)
+			if err != nil {
+				return err
+			}
+		}
+		purged = int64(len(records))
+		return nil
+	})
+	return purged, err
+}
+
+//UpdatePerson updates person if its version was not changed since reading and returns the new version
+func (repo *PersonRepo) UpdatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	updatePerson := `UPDATE person
+				   SET name = ?, surname = ?, patronymic = ?, age = ?, gender = ?, nation = ?,
+				       name_phonetic = ?, surname_phonetic = ?, version = version + 1, ` + touchUpdatedAt + `
+				   WHERE id = ? AND version = ? AND deleted_at IS NULL
+				   RETURNING version, ` + personSnapshot
+	var version uint
+	err := repo.inTx(func(tx *sql.Tx) error {
+		before, err := selectSnapshot(tx, person.ID)
+		if err != nil {
+			return err
+		}
+
+		var after []byte
+		err = tx.QueryRow(updatePerson, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation,
+			codesArg(person.NamePhonetic), codesArg(person.SurnamePhonetic), person.ID, person.Version).Scan(&version, &after)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return dto.ErrVersionMismatch
+			}
+			return err
+		}
+
+		return addHistory(tx, person.ID, dto.OperationUpdate, version, before, after, change)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+//GetPersonById gets person by id including deleted one, deletion is reported by DeletedAt
+func (repo *PersonRepo) GetPersonById(id uint) (*dto.DBGetPerson, error) {
+	row := repo.q().QueryRow(`SELECT `+personColumns+` FROM person WHERE id = ?`, id)
+	person, err := scanPerson(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return person, nil
+}
+
+func (repo *PersonRepo) CreatePerson(person *dto.DBGetPerson, change *dto.ChangeInfo) (uint, error) {
+	insertPerson := `INSERT INTO person (name, surname, patronymic, age, gender, nation, name_phonetic, surname_phonetic)
+					 VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, version, ` + personSnapshot
+	var ID uint
+	err := repo.inTx(func(tx *sql.Tx) error {
+		var version uint
+		var after []byte
+		err := tx.QueryRow(insertPerson, person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nation,
+			codesArg(person.NamePhonetic), codesArg(person.SurnamePhonetic)).Scan(&ID, &version, &after)
+		if err != nil {
+			return err
+		}
+
+		return addHistory(tx, ID, dto.OperationCreate, version, nil, after, change)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return ID, nil
+}
+
+//SearchPersons searches people by names exactly or by phonetic codes
+func (repo *PersonRepo) SearchPersons(search *dto.PersonSearch) ([]*dto.DBGetPerson, error) {
+	query := `SELECT ` + personColumns + ` FROM person WHERE deleted_at IS NULL`
+	args := []interface{}{}
+
+	if search.Mode == dto.SearchModePhonetic {
+		if search.Name != "" {
+			args = append(args, codesArg(search.NameCodes))
+			query += " AND " + overlapCondition("name_phonetic")
+		}
+		if search.Surname != "" {
+			args = append(args, codesArg(search.SurnameCodes))
+			query += " AND " + overlapCondition("surname_phonetic")
+		}
+	} else {
+		if search.Name != "" {
+			args = append(args, search.Name)
+			query += " AND lower(name) = lower(?)"
+		}
+		if search.Surname != "" {
+			args = append(args, search.Surname)
+			query += " AND lower(surname) = lower(?)"
+		}
+	}
+	if search.Patronymic != "" {
+		args = append(args, search.Patronymic)
+		query += " AND lower(patronymic) = lower(?)"
+	}
+
+	rows, err := repo.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPersons(rows)
+}
+
+//GetDistinctNames gets all different names of people
+func (repo *PersonRepo) GetDistinctNames() ([]string, error) {
+	return repo.getDistinct(`SELECT DISTINCT name FROM person WHERE deleted_at IS NULL`)
+}
+
+//GetDistinctSurnames gets all different surnames of people
+func (repo *PersonRepo) GetDistinctSurnames() ([]string, error) {
+	return repo.getDistinct(`SELECT DISTINCT surname FROM person WHERE deleted_at IS NULL`)
+}
+
+func (repo *PersonRepo) getDistinct(query string) ([]string, error) {
+	rows, err := repo.q().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := []string{}
+	for rows.Next() {
+		var value string
+		err = rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+//GetPersonsWithoutPhonetic gets people whose phonetic codes were not computed yet
+func (repo *PersonRepo) GetPersonsWithoutPhonetic() ([]*dto.DBGetPerson, error) {
+	rows, err := repo.q().Query(`SELECT id, name, surname FROM person WHERE name_phonetic IS NULL OR surname_phonetic IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var Persons = []*dto.DBGetPerson{}
+	for rows.Next() {
+		person := &dto.DBGetPerson{}
+		err = rows.Scan(&person.ID, &person.Name, &person.Surname)
+		if err != nil {
+			return nil, err
+		}
+		Persons = append(Persons, person)
+	}
+	return Persons, rows.Err()
+}
+
+//UpdatePersonPhonetic saves phonetic codes of person names
+func (repo *PersonRepo) UpdatePersonPhonetic(person *dto.DBGetPerson) error {
+	return repo.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE person SET name_phonetic = ?, surname_phonetic = ? WHERE id = ?`,
+			codesArg(person.NamePhonetic), codesArg(person.SurnamePhonetic), person.ID)
+		return err
+	})
+}
+
+//scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPerson(row scanner) (*dto.DBGetPerson, error) {
+	person := &dto.DBGetPerson{}
+	err := row.Scan(
+		&person.ID,
+		&person.Name,
+		&person.Surname,
+		&person.Patronymic,
+		&person.Age,
+		&person.Gender,
+		&person.Nation,
+		&person.DeletedAt,
+		&person.Version,
+		&person.CreatedAt,
+		&person.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+func scanPersons(rows *sql.Rows) ([]*dto.DBGetPerson, error) {
+	defer rows.Close()
+	var Persons = []*dto.DBGetPerson{}
+	for rows.Next() {
+		person, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		Persons = append(Persons, person)
+	}
+	return Persons, rows.Err()
+}
+
+//filterField is a column compared with a text field of filter
+type filterField struct {
+	column string
+	value  string
+}
+
+func filterFields(filter *dto.PersonFilter) []filterField {
+	return []filterField{{"name", filter.Name}, {"surname", filter.Surname}, {"gender", filter.Gender}, {"nation", filter.Nation}}
+}
+
+//personFilter builds conditions of filter and appends their arguments to args
+func personFilter(filter *dto.PersonFilter, args []interface{}) (string, []interface{}) {
+	conditions := "deleted_at IS NULL"
+	if filter.IncludeDeleted {
+		conditions = "TRUE"
+	}
+	if filter.CreatedAfter != nil {
+		args = append(args, timeArg(*filter.CreatedAfter))
+		conditions += " AND created_at > ?"
+	}
+	if filter.UpdatedSince != nil {
+		args = append(args, timeArg(*filter.UpdatedSince))
+		conditions += " AND updated_at >= ?"
+	}
+	for _, field := range filterFields(filter) {
+		if field.value != "" {
+			args = append(args, field.value)
+			conditions += " AND lower(" + field.column + ") = lower(?)"
+		}
+	}
+	if filter.MinAge != nil {
+		args = append(args, *filter.MinAge)
+		conditions += " AND age >= ?"
+	}
+	if filter.MaxAge != nil {
+		args = append(args, *filter.MaxAge)
+		conditions += " AND age <= ?"
+	}
+	return conditions, args
+}
+
+//timeArg formats time like default values of created_at and updated_at, so they are compared as text
+func timeArg(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+//codesArg passes phonetic codes as JSON array, nil codes become NULL
+func codesArg(codes []string) interface{} {
+	if codes == nil {
+		return nil
+	}
+	data, _ := json.Marshal(codes)
+	return string(data)
+}
+
+//overlapCondition checks that JSON array column has common codes with JSON array argument
+func overlapCondition(column string) string {
+	return `EXISTS (SELECT 1 FROM json_each(` + column + `) WHERE value IN (SELECT value FROM json_each(?)))`
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"server/server/internal/domain/dto"
+	"strconv"
+	"strings"
+)
+
+//GetStats computes statistics of people passing filter, bounds of age buckets start from 0
+func (repo *PersonRepo) GetStats(filter *dto.PersonFilter, bounds []uint, top uint) (*dto.Stats, error) {
+	conditions, args := personFilter(filter, nil)
+	stats := &dto.Stats{Age: &dto.AgeStats{Histogram: dto.NewAgeHistogram(bounds)}}
+
+	err := repo.q().QueryRow(`SELECT COUNT(*), COALESCE(AVG(age), 0) FROM person WHERE `+conditions, args...).
+		Scan(&stats.Total, &stats.Age.Mean)
+	if err != nil {
+		return nil, err
+	}
+
+	//sqlite has no percentile function, median is an average of one or two middle ages
+	err = repo.q().QueryRow(`SELECT COALESCE(AVG(age), 0) FROM (SELECT age FROM person WHERE `+conditions+`
+							 ORDER BY age LIMIT 2 - ? % 2 OFFSET (? - 1) / 2)`, append(args, stats.Total, stats.Total)...).
+		Scan(&stats.Age.Median)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.q().Query(`SELECT `+bucketExpression(bounds)+` AS bucket, COUNT(*) FROM person
+								WHERE `+conditions+` GROUP BY bucket`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket int
+		var count int64
+		err = rows.Scan(&bucket, &count)
+		if err != nil {
+			return nil, err
+		}
+		if bucket >= 0 && bucket < len(stats.Age.Histogram) {
+			stats.Age.Histogram[bucket].Count = count
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.Genders, err = repo.countValues(`SELECT gender, COUNT(*) FROM person WHERE `+conditions+`
+										  GROUP BY gender ORDER BY COUNT(*) DESC, gender`, args)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.TopNations, err = repo.countValues(`SELECT nation, COUNT(*) FROM person WHERE `+conditions+`
+											 GROUP BY nation ORDER BY COUNT(*) DESC, nation LIMIT ?`, append(args, top))
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+//bucketExpression gets index of age bucket, bounds are numbers so they are put into query as is
+func bucketExpression(bounds []uint) string {
+	builder := strings.Builder{}
+	builder.WriteString("CASE")
+	for i := len(bounds) - 1; i > 0; i-- {
+		builder.WriteString(" WHEN age >= " + strconv.FormatUint(uint64(bounds[i]), 10) + " THEN " + strconv.Itoa(i))
+	}
+	builder.WriteString(" ELSE 0 END")
+	return builder.String()
+}
+
+func (repo *PersonRepo) countValues(query string, args []interface{}) ([]*dto.ValueCount, error) {
+	rows, err := repo.q().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := []*dto.ValueCount{}
+	for rows.Next() {
+		count := &dto.ValueCount{}
+		err = rows.Scan(&count.Value, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+//maxTxAttempts is a maximum count of attempts to run transaction failed because of concurrent changes
+const maxTxAttempts = 3
+
+//querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//q gets transaction of repo if it is opened or pool of read connections otherwise,
+//writes outside transaction go through inTx
+func (repo *PersonRepo) q() querier {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return repo.ReadDB
+}
+
+//WithTx runs fn in transaction with repo bound to it. Transaction is rolled back
+//if fn returns error or panics and is retried if database stays busy
+func (repo *PersonRepo) WithTx(fn func(txRepo personRep.PersonRepositoryI) error) error {
+	if repo.tx != nil {
+		return fn(repo)
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = repo.runTx(fn)
+		if !isRetryable(err) {
+			return err
+		}
+		time.Sleep(time.Duration(attempt*10) * time.Millisecond)
+	}
+	return err
+}
+
+func (repo *PersonRepo) runTx(fn func(txRepo personRep.PersonRepositoryI) error) (err error) {
+	tx, err := repo.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(&PersonRepo{DB: repo.DB, ReadDB: repo.ReadDB, tx: tx})
+}
+
+//inTx runs fn in transaction of repo or in a new one
+func (repo *PersonRepo) inTx(fn func(tx *sql.Tx) error) error {
+	if repo.tx != nil {
+		return fn(repo.tx)
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//GetPersonByIdForUpdate gets person by id. Transactions are opened with immediate lock,
+//so the person can not be changed by others until the end of transaction
+func (repo *PersonRepo) GetPersonByIdForUpdate(id uint) (*dto.DBGetPerson, error) {
+	row := repo.q().QueryRow(`SELECT `+personColumns+` FROM person WHERE id = ?`, id)
+	person, err := scanPerson(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return person, nil
+}
+
+//isRetryable reports whether transaction failed because database is locked by another writer
+func isRetryable(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		//primary result codes SQLITE_BUSY and SQLITE_LOCKED
+		code := sqliteErr.Code() & 0xff
+		return code == 5 || code == 6
+	}
+	return false
+}
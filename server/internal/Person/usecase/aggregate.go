@@ -0,0 +1,37 @@
+package usecase
+
+import "server/server/internal/domain/dto"
+
+//Aggregate groups people passing filter by whitelisted dimensions and computes whitelisted metrics,
+//count is computed if no metrics are requested
+func (per PersonUsecase) Aggregate(aggregate *dto.Aggregate, filter *dto.PersonFilter) (*dto.AggregateResult, error) {
+	if len(aggregate.Metrics) == 0 {
+		aggregate.Metrics = []string{dto.MetricCount}
+	}
+	if !allowed(aggregate.GroupBy, dto.AggregateDimensions) || !allowed(aggregate.Metrics, dto.AggregateMetrics) {
+		return nil, dto.ErrInvalidAggregate
+	}
+
+	return per.personRepo.Aggregate(aggregate, filter)
+}
+
+//allowed checks that values are from whitelist and are not repeated
+func allowed(values []string, whitelist []string) bool {
+	seen := map[string]bool{}
+	for _, value := range values {
+		if seen[value] || !contains(whitelist, value) {
+			return false
+		}
+		seen[value] = true
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+)
+
+//errBatchFailed rolls back transaction of atomic batch, it wraps the error of the failed operation,
+//so transaction is retried if the error is retryable
+var errBatchFailed = errors.New("operation of batch failed")
+
+//ApplyBatch applies operations in order. Operations of non-atomic batch succeed or fail independently,
+//atomic batch is applied in one transaction and all its operations are rolled back if any of them fails
+func (per PersonUsecase) ApplyBatch(ctx context.Context, batch *dto.Batch, change *dto.ChangeInfo) (*dto.BatchResult, error) {
+	if len(batch.Operations) == 0 {
+		return nil, fmt.Errorf("%w: batch has no operations", dto.ErrInvalidBatch)
+	}
+	if len(batch.Operations) > dto.MaxBatchOperations {
+		return nil, fmt.Errorf("%w: batch has more than %d operations", dto.ErrInvalidBatch, dto.MaxBatchOperations)
+	}
+
+	result := &dto.BatchResult{Atomic: batch.Atomic}
+	for i, operation := range batch.Operations {
+		result.Results = append(result.Results, &dto.BatchItemResult{Index: i, Op: operation.Op})
+	}
+
+	//people are enriched before transaction, so it does not wait for external APIs and its retries do not call them again
+	prepared := per.prepareCreates(ctx, batch.Operations, result.Results)
+
+	if !batch.Atomic {
+		per.applyOperations(batch.Operations, prepared, result.Results, false, change)
+		countResults(result)
+		return result, nil
+	}
+
+	err := errBatchFailed
+	if !failed(result.Results) {
+		err = per.personRepo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+			//results of the previous attempt are dropped when transaction is retried
+			for _, item := range result.Results {
+				item.ID, item.Version, item.Err = 0, 0, nil
+			}
+			txUsecase := per
+			txUsecase.personRepo = txRepo
+			txUsecase.applyOperations(batch.Operations, prepared, result.Results, true, change)
+			for _, item := range result.Results {
+				if item.Err != nil {
+					return fmt.Errorf("%w: %w", errBatchFailed, item.Err)
+				}
+			}
+			return nil
+		})
+	}
+	if errors.Is(err, errBatchFailed) {
+		for _, item := range result.Results {
+			if item.Err == nil {
+				item.Err = dto.ErrRolledBack
+			}
+			item.ID = 0
+			item.Version = 0
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	countResults(result)
+	return result, nil
+}
+
+//prepareCreates prepares people of creations like CreatePerson does, people of other operations are nil.
+//Creations similar to earlier creations of the batch are duplicates too unless force is set.
+//People are enriched concurrently, creations which can not be prepared get errors in results
+func (per PersonUsecase) prepareCreates(ctx context.Context, operations []*dto.BatchOperation, results []*dto.BatchItemResult) []*dto.DBGetPerson {
+	prepared := make([]*dto.DBGetPerson, len(operations))
+	newPersons := []*dto.Person{}
+	indexes := []int{}
+	for i, operation := range operations {
+		if operation.Op != dto.BatchCreate {
+			continue
+		}
+		newPerson := &dto.Person{}
+		err := json.Unmarshal(operation.Person, newPerson)
+		if err != nil {
+			results[i].Err = fmt.Errorf("%w: person is not valid JSON object", dto.ErrInvalidBatch)
+			continue
+		}
+		results[i].Err = per.checkCreate(newPerson, operation.Force)
+		if results[i].Err != nil {
+			continue
+		}
+		if !operation.Force {
+			for j, earlier := range newPersons {
+				if _, _, ok := matchDuplicate(per.duplicateRules, newPerson, earlier); ok {
+					results[i].Err = fmt.Errorf("%w: creation at index %d", dto.ErrDuplicateOperation, indexes[j])
+					break
+				}
+			}
+			if results[i].Err != nil {
+				continue
+			}
+		}
+		newPersons = append(newPersons, newPerson)
+		indexes = append(indexes, i)
+	}
+
+	persons, errs := per.enrichAll(ctx, newPersons)
+	for j, i := range indexes {
+		prepared[i], results[i].Err = persons[j], errs[j]
+	}
+	return prepared
+}
+
+//applyOperations applies operations and sets their results, consecutive creations of prepared people
+//are inserted by one bulk insert. Operations are not applied after the first failed one if stopOnError is set
+func (per PersonUsecase) applyOperations(operations []*dto.BatchOperation, prepared []*dto.DBGetPerson, results []*dto.BatchItemResult, stopOnError bool, change *dto.ChangeInfo) {
+	for i := 0; i < len(operations); i++ {
+		if stopOnError && i != 0 && failed(results[:i]) {
+			return
+		}
+		operation := operations[i]
+		switch operation.Op {
+		case dto.BatchCreate:
+			end := i + 1
+			for end < len(operations) && operations[end].Op == dto.BatchCreate {
+				end++
+			}
+			per.createBatch(prepared[i:end], results[i:end], change)
+			i = end - 1
+		case dto.BatchUpdate:
+			if operation.Person == nil {
+				results[i].Err = fmt.Errorf("%w: person is required for update", dto.ErrInvalidBatch)
+				continue
+			}
+			patch := &dto.Patch{Kind: dto.PatchMerge, Body: operation.Person}
+			results[i].ID = operation.ID
+			results[i].Version, results[i].Err = per.PatchPerson(operation.ID, patch, operation.Version, change)
+		case dto.BatchDelete:
+			results[i].ID = operation.ID
+			results[i].Err = per.DeletePerson(operation.ID, operation.Version, change)
+		default:
+			results[i].Err = fmt.Errorf("%w: unknown operation %q", dto.ErrInvalidBatch, operation.Op)
+		}
+	}
+}
+
+//createBatch inserts prepared people by one bulk insert, all of them fail if the insert fails.
+//Creations which were not prepared already have errors
+func (per PersonUsecase) createBatch(prepared []*dto.DBGetPerson, results []*dto.BatchItemResult, change *dto.ChangeInfo) {
+	persons := []*dto.DBGetPerson{}
+	created := []*dto.BatchItemResult{}
+	for i, person := range prepared {
+		if person == nil {
+			continue
+		}
+		persons = append(persons, person)
+		created = append(created, results[i])
+	}
+	if len(persons) == 0 {
+		return
+	}
+
+	ids, err := per.personRepo.CreatePersons(persons, change)
+	if err != nil {
+		for _, item := range created {
+			item.Err = err
+		}
+		return
+	}
+	for i, id := range ids {
+		created[i].ID = id
+		created[i].Version = 1
+	}
+}
+
+func failed(results []*dto.BatchItemResult) bool {
+	for _, item := range results {
+		if item.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func countResults(result *dto.BatchResult) {
+	for _, item := range result.Results {
+		if item.Err == nil {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	personRep "server/server/internal/Person/repository"
+	memoryRep "server/server/internal/Person/repository/memory"
+	"server/server/internal/domain/dto"
+	"testing"
+)
+
+var errSerialization = errors.New("could not serialize access")
+
+//maxTestTxAttempts is a maximum count of attempts of retryRepo transactions like in Postgres repo
+const maxTestTxAttempts = 3
+
+//retryRepo retries transactions like Postgres repo does after serialization failures.
+//The next failCommits transactions fail after fn succeeded, the next failCreates bulk inserts fail
+type retryRepo struct {
+	personRep.PersonRepositoryI
+	transactions int
+	attempts     int
+	failCommits  int
+	failCreates  int
+}
+
+//retryTxRepo is a repo bound to the transaction of retryRepo
+type retryTxRepo struct {
+	personRep.PersonRepositoryI
+	repo *retryRepo
+}
+
+func (repo *retryRepo) WithTx(fn func(txRepo personRep.PersonRepositoryI) error) error {
+	repo.transactions++
+	var err error
+	for attempt := 1; attempt <= maxTestTxAttempts; attempt++ {
+		repo.attempts++
+		err = repo.PersonRepositoryI.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+			err := fn(&retryTxRepo{PersonRepositoryI: txRepo, repo: repo})
+			if err == nil && repo.failCommits > 0 {
+				repo.failCommits--
+				return errSerialization
+			}
+			return err
+		})
+		if !errors.Is(err, errSerialization) {
+			return err
+		}
+	}
+	return err
+}
+
+func (repo *retryTxRepo) WithTx(fn func(txRepo personRep.PersonRepositoryI) error) error {
+	return fn(repo)
+}
+
+func (repo *retryTxRepo) CreatePersons(persons []*dto.DBGetPerson, change *dto.ChangeInfo) ([]uint, error) {
+	if repo.repo.failCreates > 0 {
+		repo.repo.failCreates--
+		return nil, errSerialization
+	}
+	return repo.PersonRepositoryI.CreatePersons(persons, change)
+}
+
+func newBatchUsecase(t *testing.T) (PersonUsecase, *retryRepo, *predictionAPI) {
+	memory := memoryRep.NewPersonRepo()
+	_, err := memory.CreatePerson(&dto.DBGetPerson{Name: "Anna", Surname: "Smith", Age: 25, Gender: "female", Nation: "GB"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &retryRepo{PersonRepositoryI: memory}
+	api, enricher := newPredictionAPI(t)
+	return PersonUsecase{personRepo: repo, duplicateRules: &dto.DuplicateRules{}, enricher: enricher}, repo, api
+}
+
+func TestAtomicBatchEnrichesOnce(t *testing.T) {
+	per, repo, api := newBatchUsecase(t)
+	repo.failCommits = 1
+
+	result, err := per.ApplyBatch(context.Background(), &dto.Batch{Atomic: true, Operations: []*dto.BatchOperation{
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Ivan","surname":"Petrov"}`)},
+		{Op: dto.BatchUpdate, ID: 1, Person: []byte(`{"age":26}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Ivan","surname":"Sidorov"}`)},
+	}}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Succeeded != 3 || result.Failed != 0 {
+		t.Fatalf("batch succeeded %d and failed %d operations, want 3 and 0", result.Succeeded, result.Failed)
+	}
+	if repo.attempts != 2 {
+		t.Fatalf("transaction was attempted %d times, want 2", repo.attempts)
+	}
+	//the retry did not call APIs again and the repeated name was requested once
+	for _, key := range []string{"/age/Ivan", "/gender/Ivan", "/nation/Ivan"} {
+		if api.requests[key] != 1 {
+			t.Errorf("%s was requested %d times, want once", key, api.requests[key])
+		}
+	}
+
+	wantIDs := []uint{2, 1, 3}
+	for i, item := range result.Results {
+		if item.ID != wantIDs[i] {
+			t.Errorf("operation %d has id %d, want %d", i, item.ID, wantIDs[i])
+		}
+	}
+	person, err := repo.GetPersonById(2)
+	if err != nil || person.Age != 42 || person.Nation != "RU" {
+		t.Fatalf("created person = %+v, %v, want enriched one", person, err)
+	}
+}
+
+func TestAtomicBatchFailedPreparation(t *testing.T) {
+	per, repo, _ := newBatchUsecase(t)
+
+	result, err := per.ApplyBatch(context.Background(), &dto.Batch{Atomic: true, Operations: []*dto.BatchOperation{
+		{Op: dto.BatchUpdate, ID: 1, Person: []byte(`{"age":26}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Ivan2","surname":"Petrov"}`)},
+		{Op: dto.BatchCreate, Person: []byte(`not json`)},
+	}}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//transaction is not started when a creation can not be prepared
+	if repo.transactions != 0 {
+		t.Errorf("%d transactions were started, want none", repo.transactions)
+	}
+	if result.Succeeded != 0 || result.Failed != 3 {
+		t.Fatalf("batch succeeded %d and failed %d operations, want 0 and 3", result.Succeeded, result.Failed)
+	}
+	var validationErr *dto.ValidationError
+	if result.Results[0].Err != dto.ErrRolledBack || !errors.As(result.Results[1].Err, &validationErr) ||
+		!errors.Is(result.Results[2].Err, dto.ErrInvalidBatch) {
+		t.Errorf("errors of operations = %v, %v, %v", result.Results[0].Err, result.Results[1].Err, result.Results[2].Err)
+	}
+
+	person, err := repo.GetPersonById(1)
+	if err != nil || person.Age != 25 {
+		t.Fatalf("person = %+v, %v, want unchanged one", person, err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	per, repo, _ := newBatchUsecase(t)
+
+	result, err := per.ApplyBatch(context.Background(), &dto.Batch{Operations: []*dto.BatchOperation{
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Ivan","surname":"Petrov"}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"","surname":"Petrov"}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Zzyx","surname":"Petrov"}`)},
+		{Op: dto.BatchUpdate, ID: 1, Version: 5, Person: []byte(`{"age":26}`)},
+		{Op: dto.BatchDelete, ID: 1},
+		{Op: "rename", ID: 1},
+	}}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//update and delete run in own transactions, creations are inserted without them
+	if repo.transactions != 2 {
+		t.Errorf("%d transactions were started, want 2 of update and delete", repo.transactions)
+	}
+	wantIDs := []uint{2, 0, 3, 1, 1, 0}
+	wantErrs := []error{nil, &dto.ValidationError{}, nil, dto.ErrVersionMismatch, nil, dto.ErrInvalidBatch}
+	for i, item := range result.Results {
+		if item.ID != wantIDs[i] {
+			t.Errorf("operation %d has id %d, want %d", i, item.ID, wantIDs[i])
+		}
+		var validationErr *dto.ValidationError
+		switch want := wantErrs[i].(type) {
+		case nil:
+			if item.Err != nil {
+				t.Errorf("operation %d failed: %v", i, item.Err)
+			}
+		case *dto.ValidationError:
+			if !errors.As(item.Err, &validationErr) {
+				t.Errorf("operation %d error = %v, want validation error", i, item.Err)
+			}
+		default:
+			if !errors.Is(item.Err, want) {
+				t.Errorf("operation %d error = %v, want %v", i, item.Err, want)
+			}
+		}
+	}
+	if result.Succeeded != 3 || result.Failed != 3 {
+		t.Errorf("batch succeeded %d and failed %d operations, want 3 and 3", result.Succeeded, result.Failed)
+	}
+}
+
+func TestAtomicBatchRetriesRetryableErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		failCreates  int
+		wantAttempts int
+		wantErrs     []error
+	}{
+		{"succeeds after retry", 1, 2, []error{nil, nil}},
+		//the last error of operation is reported after all attempts
+		{"attempts are exhausted", maxTestTxAttempts, maxTestTxAttempts, []error{errSerialization, dto.ErrRolledBack}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			per, repo, _ := newBatchUsecase(t)
+			repo.failCreates = tt.failCreates
+
+			result, err := per.ApplyBatch(context.Background(), &dto.Batch{Atomic: true, Operations: []*dto.BatchOperation{
+				{Op: dto.BatchCreate, Person: []byte(`{"name":"Ivan","surname":"Petrov"}`)},
+				{Op: dto.BatchUpdate, ID: 1, Person: []byte(`{"age":26}`)},
+			}}, &dto.ChangeInfo{Actor: "test"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if repo.attempts != tt.wantAttempts {
+				t.Errorf("transaction was attempted %d times, want %d", repo.attempts, tt.wantAttempts)
+			}
+			for i, item := range result.Results {
+				if !errors.Is(item.Err, tt.wantErrs[i]) {
+					t.Errorf("operation %d error = %v, want %v", i, item.Err, tt.wantErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchDuplicateCreations(t *testing.T) {
+	per, repo, api := newBatchUsecase(t)
+	per.duplicateRules = &dto.DuplicateRules{Exact: true, Phonetic: true}
+
+	result, err := per.ApplyBatch(context.Background(), &dto.Batch{Operations: []*dto.BatchOperation{
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Ivan","surname":"Petrov"}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"ivan","surname":"PETROV"}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Iwan","surname":"Petroff"}`)},
+		{Op: dto.BatchCreate, Person: []byte(`{"name":"Zzyx","surname":"Petrov"}`)},
+		//force creates similar people
+		{Op: dto.BatchCreate, Force: true, Person: []byte(`{"name":"Ivan","surname":"Petrov"}`)},
+	}}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantErrs := []error{nil, dto.ErrDuplicateOperation, dto.ErrDuplicateOperation, nil, nil}
+	for i, item := range result.Results {
+		if !errors.Is(item.Err, wantErrs[i]) {
+			t.Errorf("operation %d error = %v, want %v", i, item.Err, wantErrs[i])
+		}
+	}
+	persons, err := repo.GetPersons(&dto.PersonFilter{})
+	if err != nil || len(persons) != 4 {
+		t.Errorf("%d people exist, want 4", len(persons))
+	}
+	//names repeated in the batch are requested once
+	if api.requests["/age/Ivan"] != 1 {
+		t.Errorf("/age/Ivan was requested %d times, want once", api.requests["/age/Ivan"])
+	}
+}
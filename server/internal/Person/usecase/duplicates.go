@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"server/server/internal/domain/dto"
+	"server/server/internal/phonetic"
+	"sort"
+	"strings"
+)
+
+//surnameEnding is a count of last letters of surname which puts people with similar names into one bucket
+const surnameEnding = 3
+
+//findDuplicates gets existing people similar to the person by enabled rules
+func (per PersonUsecase) findDuplicates(person *dto.Person) ([]*dto.DuplicateCandidate, error) {
+	rules := per.duplicateRules
+	if !rules.Exact && !rules.Phonetic && !rules.Fuzzy {
+		return nil, nil
+	}
+
+	searches := []*dto.PersonSearch{{
+		Surname:      person.Surname,
+		Mode:         dto.SearchModePhonetic,
+		SurnameCodes: phonetic.Encode(person.Surname),
+	}, {
+		//name and surname are swapped
+		Name:         person.Surname,
+		Surname:      person.Name,
+		Mode:         dto.SearchModePhonetic,
+		NameCodes:    phonetic.Encode(person.Surname),
+		SurnameCodes: phonetic.Encode(person.Name),
+	}}
+	if rules.Fuzzy {
+		//a typo in the first letters of surname changes its codes, so people with similar name are compared too
+		searches = append(searches, &dto.PersonSearch{
+			Name:      person.Name,
+			Mode:      dto.SearchModePhonetic,
+			NameCodes: phonetic.Encode(person.Name),
+		})
+	}
+	if rules.Exact {
+		searches = append(searches, &dto.PersonSearch{
+			Name:    person.Name,
+			Surname: person.Surname,
+			Mode:    dto.SearchModeExact,
+		})
+	}
+
+	candidates := []*dto.DuplicateCandidate{}
+	seen := map[uint]bool{}
+	for _, search := range searches {
+		dbpers, err := per.personRepo.SearchPersons(search)
+		if err != nil {
+			return nil, err
+		}
+		for _, dbper := range dbpers {
+			if seen[dbper.ID] {
+				continue
+			}
+			seen[dbper.ID] = true
+
+			existing := dto.ToPerson(dbper)
+			rule, distance, ok := matchDuplicate(rules, person, existing)
+			if ok {
+				candidates = append(candidates, &dto.DuplicateCandidate{Person: existing, Rule: rule, Distance: distance})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Distance < candidates[j].Distance
+	})
+	return candidates, nil
+}
+
+//GetDuplicates gets clusters of existing people suspected to be duplicates.
+//Only people from common buckets are compared, see duplicateBuckets
+func (per PersonUsecase) GetDuplicates() ([]*dto.DuplicateCluster, error) {
+	dbpers, err := per.personRepo.GetPersons(&dto.PersonFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	persons := map[uint]*dto.Person{}
+	buckets := map[string][]*dto.Person{}
+	for _, dbper := range dbpers {
+		person := dto.ToPerson(dbper)
+		persons[person.ID] = person
+		for _, key := range duplicateBuckets(person) {
+			buckets[key] = append(buckets[key], person)
+		}
+	}
+
+	//parent joins matched people into clusters
+	parent := map[uint]uint{}
+	var root func(id uint) uint
+	root = func(id uint) uint {
+		if parent[id] == id {
+			return id
+		}
+		parent[id] = root(parent[id])
+		return parent[id]
+	}
+
+	matches := []*dto.DuplicateMatch{}
+	compared := map[[2]uint]bool{}
+	for _, bucket := range buckets {
+		for i, first := range bucket {
+			for _, second := range bucket[i+1:] {
+				pair := [2]uint{first.ID, second.ID}
+				if first.ID > second.ID {
+					pair = [2]uint{second.ID, first.ID}
+				}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+
+				rule, distance, ok := matchDuplicate(per.duplicateRules, first, second)
+				if !ok {
+					continue
+				}
+				matches = append(matches, &dto.DuplicateMatch{FirstID: pair[0], SecondID: pair[1], Rule: rule, Distance: distance})
+				for _, id := range pair {
+					if _, ok := parent[id]; !ok {
+						parent[id] = id
+					}
+				}
+				parent[root(pair[0])] = root(pair[1])
+			}
+		}
+	}
+
+	clusters := map[uint]*dto.DuplicateCluster{}
+	for id := range parent {
+		cluster, ok := clusters[root(id)]
+		if !ok {
+			cluster = &dto.DuplicateCluster{People: []*dto.Person{}, Matches: []*dto.DuplicateMatch{}}
+			clusters[root(id)] = cluster
+		}
+		cluster.People = append(cluster.People, persons[id])
+	}
+	for _, match := range matches {
+		cluster := clusters[root(match.FirstID)]
+		cluster.Matches = append(cluster.Matches, match)
+	}
+
+	res := []*dto.DuplicateCluster{}
+	for _, cluster := range clusters {
+		sort.Slice(cluster.People, func(i, j int) bool {
+			return cluster.People[i].ID < cluster.People[j].ID
+		})
+		sort.Slice(cluster.Matches, func(i, j int) bool {
+			if cluster.Matches[i].FirstID != cluster.Matches[j].FirstID {
+				return cluster.Matches[i].FirstID < cluster.Matches[j].FirstID
+			}
+			return cluster.Matches[i].SecondID < cluster.Matches[j].SecondID
+		})
+		res = append(res, cluster)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].People[0].ID < res[j].People[0].ID
+	})
+	return res, nil
+}
+
+//duplicateBuckets gets keys of buckets of people compared with the person. People share a bucket
+//when they have a common phonetic code of surname, when their codes of name and surname are the same
+//in any order, or when they have a common code of name and the same ending of surname,
+//so typos in the first letters of surname are found too
+func duplicateBuckets(person *dto.Person) []string {
+	keys := []string{}
+	nameCodes := phonetic.Encode(person.Name)
+	surnameCodes := phonetic.Encode(person.Surname)
+	for _, surnameCode := range surnameCodes {
+		keys = append(keys, "surname:"+surnameCode)
+		for _, nameCode := range nameCodes {
+			pair := []string{nameCode, surnameCode}
+			sort.Strings(pair)
+			keys = append(keys, "names:"+strings.Join(pair, ":"))
+		}
+	}
+
+	surname := []rune(phonetic.Normalize(person.Surname))
+	if len(surname) > surnameEnding {
+		ending := string(surname[len(surname)-surnameEnding:])
+		for _, nameCode := range nameCodes {
+			keys = append(keys, "ending:"+nameCode+":"+ending)
+		}
+	}
+	return keys
+}
+
+//matchDuplicate checks whether people look like the same person, it returns the matched rule
+//and count of typos in name and surname
+func matchDuplicate(rules *dto.DuplicateRules, first *dto.Person, second *dto.Person) (string, int, bool) {
+	if rules.Exact && strings.EqualFold(first.Name, second.Name) && strings.EqualFold(first.Surname, second.Surname) &&
+		strings.EqualFold(first.Patronymic, second.Patronymic) {
+		return dto.DuplicateRuleExact, 0, true
+	}
+
+	//patronymic is often omitted, so only different non-empty patronymics tell people apart
+	if first.Patronymic != "" && second.Patronymic != "" &&
+		phonetic.Normalize(first.Patronymic) != phonetic.Normalize(second.Patronymic) {
+		return "", 0, false
+	}
+
+	//name and surname are often swapped, so the closer order is compared
+	distance := phonetic.Distance(first.Name, second.Name) + phonetic.Distance(first.Surname, second.Surname)
+	swapped := phonetic.Distance(first.Name, second.Surname) + phonetic.Distance(first.Surname, second.Name)
+	if swapped < distance {
+		distance = swapped
+	}
+	if rules.Fuzzy && distance <= rules.MaxDistance {
+		return dto.DuplicateRuleFuzzy, distance, true
+	}
+
+	if rules.Phonetic && (soundAlike(first.Name, second.Name) && soundAlike(first.Surname, second.Surname) ||
+		soundAlike(first.Name, second.Surname) && soundAlike(first.Surname, second.Name)) {
+		return dto.DuplicateRulePhonetic, distance, true
+	}
+
+	return "", 0, false
+}
+
+func soundAlike(first string, second string) bool {
+	return overlap(phonetic.Encode(first), phonetic.Encode(second))
+}
+
+func overlap(codes []string, other []string) bool {
+	for _, code := range codes {
+		for _, o := range other {
+			if code == o {
+				return true
+			}
+		}
+	}
+	return false
+}
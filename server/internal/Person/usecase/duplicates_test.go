@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	memoryRep "server/server/internal/Person/repository/memory"
+	"server/server/internal/domain/dto"
+	"testing"
+)
+
+func TestNewDuplicateRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		want  dto.DuplicateRules
+	}{
+		{"none", nil, dto.DuplicateRules{MaxDistance: 2}},
+		{"exact", []string{"exact"}, dto.DuplicateRules{Exact: true, MaxDistance: 2}},
+		{"all", []string{"exact", "phonetic", "fuzzy"}, dto.DuplicateRules{Exact: true, Phonetic: true, Fuzzy: true, MaxDistance: 2}},
+		{"unknown is ignored", []string{"soundex", "fuzzy"}, dto.DuplicateRules{Fuzzy: true, MaxDistance: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dto.NewDuplicateRules(tt.rules, 2); *got != tt.want {
+				t.Errorf("NewDuplicateRules(%q) = %+v, want %+v", tt.rules, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchDuplicate(t *testing.T) {
+	all := dto.NewDuplicateRules([]string{"exact", "phonetic", "fuzzy"}, 2)
+	person := func(name, surname, patronymic string) *dto.Person {
+		return &dto.Person{Name: name, Surname: surname, Patronymic: patronymic}
+	}
+
+	tests := []struct {
+		name         string
+		rules        *dto.DuplicateRules
+		first        *dto.Person
+		second       *dto.Person
+		wantRule     string
+		wantDistance int
+		wantOk       bool
+	}{
+		{"exact ignores case", all, person("Ivan", "Petrov", "Ivanovich"), person("IVAN", "petrov", "ivanovich"), dto.DuplicateRuleExact, 0, true},
+		{"exact needs same patronymic", dto.NewDuplicateRules([]string{"exact"}, 2),
+			person("Ivan", "Petrov", ""), person("Ivan", "Petrov", "Ivanovich"), "", 0, false},
+		{"different patronymics", all, person("Ivan", "Petrov", "Ivanovich"), person("Ivan", "Petrov", "Petrovich"), "", 0, false},
+		{"transliterated patronymic", all, person("Ivan", "Petrov", "Ivanovich"), person("Иван", "Петров", "Иванович"), dto.DuplicateRuleFuzzy, 0, true},
+		{"omitted patronymic", all, person("Ivan", "Petrov", ""), person("Ivan", "Petrov", "Ivanovich"), dto.DuplicateRuleFuzzy, 0, true},
+		{"typo", all, person("Ivan", "Petrov", ""), person("Ivan", "Petorv", ""), dto.DuplicateRuleFuzzy, 2, true},
+		{"too many typos", dto.NewDuplicateRules([]string{"fuzzy"}, 2),
+			person("Alexander", "Shevchenko", ""), person("Aleksandr", "Chevchenko", ""), "", 0, false},
+		{"phonetic", dto.NewDuplicateRules([]string{"phonetic", "fuzzy"}, 2),
+			person("Alexander", "Shevchenko", ""), person("Aleksandr", "Chevchenko", ""), dto.DuplicateRulePhonetic, 4, true},
+		{"swapped", all, person("Ivan", "Petrov", ""), person("Petrov", "Ivan", ""), dto.DuplicateRuleFuzzy, 0, true},
+		{"swapped phonetic", dto.NewDuplicateRules([]string{"phonetic"}, 2),
+			person("Alexander", "Shevchenko", ""), person("Chevchenko", "Aleksandr", ""), dto.DuplicateRulePhonetic, 4, true},
+		{"typo in first letter", all, person("Ivan", "Petrov", ""), person("Ivan", "Getrov", ""), dto.DuplicateRuleFuzzy, 1, true},
+		{"different people", all, person("Ivan", "Petrov", ""), person("Anna", "Smith", ""), "", 0, false},
+		{"no rules", &dto.DuplicateRules{}, person("Ivan", "Petrov", ""), person("Ivan", "Petrov", ""), "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, distance, ok := matchDuplicate(tt.rules, tt.first, tt.second)
+			if rule != tt.wantRule || distance != tt.wantDistance || ok != tt.wantOk {
+				t.Errorf("matchDuplicate() = %q, %d, %v, want %q, %d, %v", rule, distance, ok, tt.wantRule, tt.wantDistance, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestGetDuplicates(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	for _, name := range [][2]string{
+		{"Alexander", "Shevchenko"},
+		{"Aleksandr", "Chevchenko"},
+		{"Александр", "Шевченко"},
+		{"Ivan", "Petrov"},
+		{"Anna", "Smith"},
+		{"Anna", "Smyth"},
+		{"Petrov", "Ivan"},
+		//typo changes phonetic code of surname
+		{"Ivan", "Getrov"},
+	} {
+		person := &dto.DBGetPerson{Name: name[0], Surname: name[1]}
+		setPhonetic(person)
+		if _, err := repo.CreatePerson(person, &dto.ChangeInfo{Actor: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	per := NewPersonUsecase(repo, dto.NewDuplicateRules([]string{"exact", "phonetic", "fuzzy"}, 2))
+	clusters, err := per.GetDuplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]uint{{1, 2, 3}, {4, 7, 8}, {5, 6}}
+	if len(clusters) != len(want) {
+		t.Fatalf("got %d clusters, want %d", len(clusters), len(want))
+	}
+	for i, cluster := range clusters {
+		ids := []uint{}
+		for _, person := range cluster.People {
+			ids = append(ids, person.ID)
+		}
+		if len(ids) != len(want[i]) {
+			t.Fatalf("cluster %d has people %v, want %v", i, ids, want[i])
+		}
+		for j := range ids {
+			if ids[j] != want[i][j] {
+				t.Fatalf("cluster %d has people %v, want %v", i, ids, want[i])
+			}
+		}
+		if len(cluster.Matches) < len(ids)-1 {
+			t.Errorf("cluster %d has %d matches for %d people", i, len(cluster.Matches), len(ids))
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	person := &dto.DBGetPerson{Name: "Alexander", Surname: "Shevchenko"}
+	setPhonetic(person)
+	if _, err := repo.CreatePerson(person, &dto.ChangeInfo{Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		rules  []string
+		person *dto.Person
+		want   string
+	}{
+		{"phonetic", []string{"phonetic"}, &dto.Person{Name: "Aleksandr", Surname: "Chevchenko"}, dto.DuplicateRulePhonetic},
+		{"swapped", []string{"phonetic"}, &dto.Person{Name: "Chevchenko", Surname: "Aleksandr"}, dto.DuplicateRulePhonetic},
+		{"typo in first letter", []string{"fuzzy"}, &dto.Person{Name: "Alexander", Surname: "Bhevchenko"}, dto.DuplicateRuleFuzzy},
+		//people with similar names are compared only by fuzzy rule
+		{"typo in first letter without fuzzy", []string{"phonetic"}, &dto.Person{Name: "Alexander", Surname: "Bhevchenko"}, ""},
+		{"disabled", nil, &dto.Person{Name: "Aleksandr", Surname: "Chevchenko"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			per := NewPersonUsecase(repo, dto.NewDuplicateRules(tt.rules, 2))
+			candidates, err := per.findDuplicates(tt.person)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if len(candidates) != 0 {
+					t.Errorf("got %d candidates, want none", len(candidates))
+				}
+				return
+			}
+			if len(candidates) != 1 || candidates[0].Rule != tt.want || candidates[0].Person.ID != 1 {
+				t.Errorf("got candidates %+v, want person 1 by %q", candidates, tt.want)
+			}
+		})
+	}
+}
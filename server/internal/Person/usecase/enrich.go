@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"server/server/internal/domain/dto"
+	"sync"
+	"time"
+)
+
+//Addresses of APIs which predict age, gender and nation by name
+const (
+	agifyURL       = "https://api.agify.io/"
+	genderizeURL   = "https://api.genderize.io/"
+	nationalizeURL = "https://api.nationalize.io/"
+)
+
+//Limits of requests to prediction APIs
+const (
+	enrichTimeout     = 10 * time.Second
+	maxEnrichRequests = 8
+	maxEnrichResponse = 1 << 20
+	maxEnrichCache    = 10000
+	enrichCacheTTL    = 24 * time.Hour
+)
+
+//prediction is age, gender and nation predicted by name
+type prediction struct {
+	age    uint
+	gender string
+	nation string
+}
+
+//cachedPrediction is an element of the LRU list of cache, predictions are requested again after expiresAt
+type cachedPrediction struct {
+	name      string
+	predicted *prediction
+	expiresAt time.Time
+}
+
+//pendingPrediction is a prediction being requested, callers predicting the same name wait for done.
+//Cancelled is set if the context of the requesting caller was done
+type pendingPrediction struct {
+	done      chan struct{}
+	predicted *prediction
+	err       error
+	cancelled bool
+}
+
+//enricher predicts fields of people by external APIs. Predictions depend only on name, so they are cached
+//for enrichCacheTTL and names repeated in batches and imports are requested once, concurrent callers share
+//pending requests. When maxEnrichCache names are cached the least recently used one is evicted.
+//At most maxEnrichRequests names are requested at once
+type enricher struct {
+	client    *http.Client
+	ageURL    string
+	genderURL string
+	nationURL string
+	requests  chan struct{}
+	now       func() time.Time
+
+	mu      sync.Mutex
+	cache   map[string]*list.Element
+	lru     *list.List
+	pending map[string]*pendingPrediction
+}
+
+func newEnricher(ageURL string, genderURL string, nationURL string) *enricher {
+	return &enricher{
+		client:    &http.Client{Timeout: enrichTimeout},
+		ageURL:    ageURL,
+		genderURL: genderURL,
+		nationURL: nationURL,
+		requests:  make(chan struct{}, maxEnrichRequests),
+		now:       time.Now,
+		cache:     map[string]*list.Element{},
+		lru:       list.New(),
+		pending:   map[string]*pendingPrediction{},
+	}
+}
+
+//enrich fills age, gender and nation of new person by external APIs
+func (per PersonUsecase) enrich(ctx context.Context, newPerson *dto.Person) (*dto.DBGetPerson, error) {
+	predicted, err := per.enricher.predict(ctx, newPerson.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	person := dto.ToDBGetPerson(newPerson)
+	person.Age = predicted.age
+	person.Gender = predicted.gender
+	person.Nation = predicted.nation
+	setPhonetic(person)
+	return person, nil
+}
+
+//enrichAll enriches people concurrently by maxEnrichRequests workers, requests of all callers are still
+//limited by the semaphore of enricher. People which are not enriched have errors
+func (per PersonUsecase) enrichAll(ctx context.Context, newPersons []*dto.Person) ([]*dto.DBGetPerson, []error) {
+	persons := make([]*dto.DBGetPerson, len(newPersons))
+	errs := make([]error, len(newPersons))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < maxEnrichRequests && worker < len(newPersons); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				persons[i], errs[i] = per.enrich(ctx, newPersons[i])
+			}
+		}()
+	}
+	for i := range newPersons {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return persons, errs
+}
+
+//predict gets prediction of name from cache, from the pending request of another caller or from APIs
+func (e *enricher) predict(ctx context.Context, name string) (*prediction, error) {
+	e.mu.Lock()
+	cached := e.cached(name)
+	if cached != nil {
+		e.mu.Unlock()
+		return cached, nil
+	}
+	if pending, ok := e.pending[name]; ok {
+		e.mu.Unlock()
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		//the request of another caller is cancelled with its context, so it is not a failure of APIs
+		if pending.cancelled {
+			return e.predict(ctx, name)
+		}
+		return pending.predicted, pending.err
+	}
+	pending := &pendingPrediction{done: make(chan struct{})}
+	e.pending[name] = pending
+	e.mu.Unlock()
+
+	pending.predicted, pending.err = e.request(ctx, name)
+	pending.cancelled = pending.err != nil && ctx.Err() != nil
+
+	e.mu.Lock()
+	delete(e.pending, name)
+	if pending.err == nil {
+		e.store(name, pending.predicted)
+	}
+	e.mu.Unlock()
+	close(pending.done)
+	return pending.predicted, pending.err
+}
+
+//request requests prediction of name from APIs
+func (e *enricher) request(ctx context.Context, name string) (*prediction, error) {
+	select {
+	case e.requests <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-e.requests }()
+
+	ageObject := &dto.Age{}
+	err := e.get(ctx, e.ageURL, name, ageObject)
+	if err != nil {
+		return nil, err
+	}
+
+	genderObject := &dto.Gender{}
+	err = e.get(ctx, e.genderURL, name, genderObject)
+	if err != nil {
+		return nil, err
+	}
+
+	nationObject := &dto.Nation{}
+	err = e.get(ctx, e.nationURL, name, nationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	predicted := &prediction{age: ageObject.Age, gender: genderObject.Gender}
+	//nationalize returns no countries for names it does not know
+	if len(nationObject.Nation) != 0 {
+		predicted.nation = nationObject.Nation[0].CountryId
+	}
+
+	return predicted, nil
+}
+
+//cached returns not expired prediction of name and marks it as recently used, caller must hold the lock
+func (e *enricher) cached(name string) *prediction {
+	element, ok := e.cache[name]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*cachedPrediction)
+	if !e.now().Before(entry.expiresAt) {
+		e.lru.Remove(element)
+		delete(e.cache, name)
+		return nil
+	}
+	e.lru.MoveToFront(element)
+	return entry.predicted
+}
+
+//store caches prediction of name, the least recently used names are evicted when cache is full.
+//Caller must hold the lock
+func (e *enricher) store(name string, predicted *prediction) {
+	entry := &cachedPrediction{name: name, predicted: predicted, expiresAt: e.now().Add(enrichCacheTTL)}
+	if element, ok := e.cache[name]; ok {
+		element.Value = entry
+		e.lru.MoveToFront(element)
+		return
+	}
+	e.cache[name] = e.lru.PushFront(entry)
+	for e.lru.Len() > maxEnrichCache {
+		oldest := e.lru.Back()
+		e.lru.Remove(oldest)
+		delete(e.cache, oldest.Value.(*cachedPrediction).name)
+	}
+}
+
+//get requests prediction for name and decodes the answer into target
+func (e *enricher) get(ctx context.Context, api string, name string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api+"?name="+url.QueryEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", api, resp.Status)
+	}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxEnrichResponse)).Decode(target)
+	if err != nil {
+		return fmt.Errorf("%s answered invalid JSON: %w", api, err)
+	}
+	return nil
+}
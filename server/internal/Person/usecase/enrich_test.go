@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"server/server/internal/domain/dto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//predictionAPI serves agify, genderize and nationalize answers from maps by name and counts requests
+type predictionAPI struct {
+	mu       sync.Mutex
+	requests map[string]int
+	answers  map[string]string
+	status   int
+	//delay of answers, inFlight and maxInFlight count requests served at once
+	delay       time.Duration
+	inFlight    int
+	maxInFlight int
+}
+
+func newPredictionAPI(t *testing.T) (*predictionAPI, *enricher) {
+	api := &predictionAPI{
+		requests: map[string]int{},
+		status:   http.StatusOK,
+		answers: map[string]string{
+			"/age/Ivan":          `{"count":100,"name":"Ivan","age":42}`,
+			"/gender/Ivan":       `{"count":100,"name":"Ivan","gender":"male","probability":0.99}`,
+			"/nation/Ivan":       `{"count":100,"name":"Ivan","country":[{"country_id":"RU","probability":0.4},{"country_id":"UA","probability":0.2}]}`,
+			"/age/Zzyx":          `{"count":0,"name":"Zzyx","age":null}`,
+			"/gender/Zzyx":       `{"count":0,"name":"Zzyx","gender":null,"probability":0}`,
+			"/nation/Zzyx":       `{"count":0,"name":"Zzyx","country":[]}`,
+			"/age/Anna Maria":    `{"age":30}`,
+			"/gender/Anna Maria": `{"gender":"female"}`,
+			"/nation/Anna Maria": `{"country":[{"country_id":"IT"}]}`,
+			"/age/Broken":        `{"age":`,
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSuffix(r.URL.Path, "/") + "/" + r.URL.Query().Get("name")
+		api.mu.Lock()
+		api.requests[key]++
+		api.inFlight++
+		if api.inFlight > api.maxInFlight {
+			api.maxInFlight = api.inFlight
+		}
+		status, delay := api.status, api.delay
+		answer, ok := api.answers[key]
+		api.mu.Unlock()
+
+		time.Sleep(delay)
+		if !ok {
+			answer = "{}"
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(answer))
+
+		api.mu.Lock()
+		api.inFlight--
+		api.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return api, newEnricher(server.URL+"/age/", server.URL+"/gender/", server.URL+"/nation/")
+}
+
+func TestEnrich(t *testing.T) {
+	_, enricher := newPredictionAPI(t)
+	per := PersonUsecase{enricher: enricher}
+
+	tests := []struct {
+		name    string
+		want    *dto.DBGetPerson
+		wantErr bool
+	}{
+		{"Ivan", &dto.DBGetPerson{Name: "Ivan", Surname: "Petrov", Age: 42, Gender: "male", Nation: "RU"}, false},
+		//unknown names get no predictions
+		{"Zzyx", &dto.DBGetPerson{Name: "Zzyx", Surname: "Petrov"}, false},
+		//names are escaped in query
+		{"Anna Maria", &dto.DBGetPerson{Name: "Anna Maria", Surname: "Petrov", Age: 30, Gender: "female", Nation: "IT"}, false},
+		{"Broken", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			person, err := per.enrich(context.Background(), &dto.Person{Name: tt.name, Surname: "Petrov"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("enrich() = %+v, want error", person)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if person.Name != tt.want.Name || person.Age != tt.want.Age || person.Gender != tt.want.Gender || person.Nation != tt.want.Nation {
+				t.Errorf("enrich() = %+v, want %+v", person, tt.want)
+			}
+			if len(person.NamePhonetic) == 0 || len(person.SurnamePhonetic) == 0 {
+				t.Errorf("enrich() did not set phonetic codes")
+			}
+		})
+	}
+}
+
+func TestEnrichFailedAPI(t *testing.T) {
+	api, enricher := newPredictionAPI(t)
+	api.status = http.StatusTooManyRequests
+	per := PersonUsecase{enricher: enricher}
+
+	_, err := per.enrich(context.Background(), &dto.Person{Name: "Ivan", Surname: "Petrov"})
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("enrich() error = %v, want error of status 429", err)
+	}
+
+	//failures are not cached
+	api.status = http.StatusOK
+	person, err := per.enrich(context.Background(), &dto.Person{Name: "Ivan", Surname: "Petrov"})
+	if err != nil || person.Age != 42 {
+		t.Fatalf("enrich() = %+v, %v after API recovered", person, err)
+	}
+}
+
+func TestEnrichCachesNames(t *testing.T) {
+	api, enricher := newPredictionAPI(t)
+	per := PersonUsecase{enricher: enricher}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		_, err := per.enrich(context.Background(), &dto.Person{Name: "Ivan", Surname: "Petrov"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			per.enrich(context.Background(), &dto.Person{Name: "Ivan", Surname: "Sidorov"})
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range []string{"/age/Ivan", "/gender/Ivan", "/nation/Ivan"} {
+		if api.requests[key] != 1 {
+			t.Errorf("%s was requested %d times, want once", key, api.requests[key])
+		}
+	}
+}
+
+func TestEnrichLimitsRequests(t *testing.T) {
+	api, enricher := newPredictionAPI(t)
+	api.delay = 10 * time.Millisecond
+	per := PersonUsecase{enricher: enricher}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3*maxEnrichRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := per.enrich(context.Background(), &dto.Person{Name: "Name" + strconv.Itoa(i), Surname: "Petrov"})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if api.maxInFlight > maxEnrichRequests {
+		t.Errorf("%d requests were sent at once, want at most %d", api.maxInFlight, maxEnrichRequests)
+	}
+}
+
+func TestEnrichCacheExpires(t *testing.T) {
+	api, enricher := newPredictionAPI(t)
+	now := time.Now()
+	enricher.now = func() time.Time { return now }
+	per := PersonUsecase{enricher: enricher}
+
+	for _, elapsed := range []time.Duration{0, enrichCacheTTL - time.Second, enrichCacheTTL} {
+		now = now.Add(elapsed)
+		_, err := per.enrich(context.Background(), &dto.Person{Name: "Ivan", Surname: "Petrov"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//the second call is cached, the third one is after its prediction expired
+	if api.requests["/age/Ivan"] != 2 {
+		t.Errorf("/age/Ivan was requested %d times, want 2", api.requests["/age/Ivan"])
+	}
+}
+
+func TestEnrichCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	_, enricher := newPredictionAPI(t)
+
+	for i := 0; i < maxEnrichCache; i++ {
+		enricher.store("Name"+strconv.Itoa(i), &prediction{age: uint(i)})
+	}
+	//the oldest name is used again, so the next one is evicted instead of it
+	if enricher.cached("Name0") == nil {
+		t.Fatal("Name0 is not cached")
+	}
+	enricher.store("Ivan", &prediction{age: 42})
+
+	if enricher.lru.Len() != maxEnrichCache || len(enricher.cache) != maxEnrichCache {
+		t.Errorf("%d names are cached, want %d", enricher.lru.Len(), maxEnrichCache)
+	}
+	if enricher.cached("Name1") != nil {
+		t.Error("Name1 is cached, want it evicted")
+	}
+	for _, name := range []string{"Name0", "Name2", "Ivan"} {
+		if enricher.cached(name) == nil {
+			t.Errorf("%s is not cached", name)
+		}
+	}
+}
+
+func TestEnrichSharesPendingRequests(t *testing.T) {
+	api, enricher := newPredictionAPI(t)
+	api.delay = 50 * time.Millisecond
+	per := PersonUsecase{enricher: enricher}
+
+	//the first caller gives up while its request is pending, others wait for the request of another caller
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		per.enrich(ctx, &dto.Person{Name: "Ivan", Surname: "Petrov"})
+	}()
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			person, err := per.enrich(context.Background(), &dto.Person{Name: "Ivan", Surname: "Sidorov"})
+			if err != nil || person.Age != 42 {
+				t.Errorf("enrich() = %+v, %v", person, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if api.requests["/age/Ivan"] != 2 {
+		t.Errorf("/age/Ivan was requested %d times, want 2: cancelled one and one shared by others", api.requests["/age/Ivan"])
+	}
+}
@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/server/internal/domain/dto"
+	"strings"
+)
+
+//ImportPersons validates rows and finds duplicates among people and earlier rows of the file.
+//Dry run stops there, otherwise valid rows are enriched by external APIs concurrently and inserted by bulk insert.
+//Nothing is inserted if enrichment takes longer than dto.MaxImportDuration or ctx is cancelled
+func (per PersonUsecase) ImportPersons(ctx context.Context, rows []*dto.ImportRow, options *dto.ImportOptions, change *dto.ChangeInfo) (*dto.ImportResult, error) {
+	if len(rows) > dto.MaxImportRows {
+		return nil, fmt.Errorf("%w: file has more than %d rows", dto.ErrInvalidImport, dto.MaxImportRows)
+	}
+
+	result := &dto.ImportResult{DryRun: options.DryRun, Total: len(rows)}
+	//lines are keys of rows by names, they find rows repeating earlier ones
+	lines := map[string]int{}
+	newPersons := []*dto.Person{}
+	enriched := []*dto.ImportRowResult{}
+	for _, row := range rows {
+		rowResult := &dto.ImportRowResult{Line: row.Line, Status: dto.ImportRowFailed}
+		result.Rows = append(result.Rows, rowResult)
+
+		rowResult.Err = row.Person.Validate(dto.ImportFields...)
+		if rowResult.Err != nil {
+			continue
+		}
+
+		if !options.Force {
+			key := strings.ToLower(row.Person.Name + "\x00" + row.Person.Surname + "\x00" + row.Person.Patronymic)
+			if line, ok := lines[key]; ok {
+				rowResult.Status = dto.ImportRowSkipped
+				rowResult.DuplicateOfLine = line
+				rowResult.Err = dto.ErrDuplicateRow
+				continue
+			}
+			lines[key] = row.Line
+
+			candidates, err := per.findDuplicates(row.Person)
+			if err != nil {
+				return nil, err
+			}
+			if len(candidates) != 0 {
+				rowResult.Status = dto.ImportRowSkipped
+				rowResult.Err = &dto.DuplicateError{Candidates: candidates}
+				continue
+			}
+		}
+
+		if options.DryRun {
+			rowResult.Status = dto.ImportRowValid
+			continue
+		}
+
+		newPersons = append(newPersons, row.Person)
+		enriched = append(enriched, rowResult)
+	}
+
+	persons := []*dto.DBGetPerson{}
+	created := []*dto.ImportRowResult{}
+	if len(newPersons) != 0 {
+		ctx, cancel := context.WithTimeout(ctx, dto.MaxImportDuration)
+		defer cancel()
+		enrichedPersons, errs := per.enrichAll(ctx, newPersons)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: people are not enriched in %s", dto.ErrImportTimeout, dto.MaxImportDuration)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for i, person := range enrichedPersons {
+			if errs[i] != nil {
+				enriched[i].Err = errs[i]
+				continue
+			}
+			persons = append(persons, person)
+			created = append(created, enriched[i])
+		}
+	}
+
+	if len(persons) != 0 {
+		ids, err := per.personRepo.CreatePersons(persons, change)
+		if err != nil {
+			for _, rowResult := range created {
+				rowResult.Err = err
+			}
+		} else {
+			for i, id := range ids {
+				created[i].Status = dto.ImportRowCreated
+				created[i].ID = id
+			}
+		}
+	}
+
+	for _, rowResult := range result.Rows {
+		switch rowResult.Status {
+		case dto.ImportRowValid:
+			result.Valid++
+		case dto.ImportRowCreated:
+			result.Created++
+		case dto.ImportRowSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"server/server/internal/domain/dto"
+	"testing"
+	"time"
+)
+
+func TestImportPersonsEnrichesConcurrently(t *testing.T) {
+	per, repo, api := newBatchUsecase(t)
+	api.delay = 10 * time.Millisecond
+
+	rows := []*dto.ImportRow{}
+	for i := 0; i < 3*maxEnrichRequests; i++ {
+		rows = append(rows, &dto.ImportRow{Line: i + 2, Person: &dto.Person{Name: "Name" + string(rune('a'+i)), Surname: "Petrov"}})
+	}
+	result, err := per.ImportPersons(context.Background(), rows, &dto.ImportOptions{}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Created != len(rows) {
+		t.Fatalf("%d rows are created, want %d", result.Created, len(rows))
+	}
+	if api.maxInFlight < 2 || api.maxInFlight > maxEnrichRequests {
+		t.Errorf("%d requests were sent at once, want from 2 to %d", api.maxInFlight, maxEnrichRequests)
+	}
+	//ids follow order of rows
+	for i, row := range result.Rows {
+		person, err := repo.GetPersonById(row.ID)
+		if err != nil || person.Name != rows[i].Person.Name {
+			t.Errorf("row %d created %+v, %v", row.Line, person, err)
+		}
+	}
+}
+
+func TestImportPersonsStopsEnriching(t *testing.T) {
+	expired, cancelExpired := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{"time budget is exceeded", expired, dto.ErrImportTimeout},
+		//client disconnected
+		{"request is cancelled", cancelled, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			per, repo, api := newBatchUsecase(t)
+			api.delay = 200 * time.Millisecond
+
+			rows := []*dto.ImportRow{
+				{Line: 2, Person: &dto.Person{Name: "Ivan", Surname: "Petrov"}},
+				{Line: 3, Person: &dto.Person{Name: "Zzyx", Surname: "Petrov"}},
+			}
+			result, err := per.ImportPersons(tt.ctx, rows, &dto.ImportOptions{}, &dto.ChangeInfo{Actor: "test"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportPersons() = %+v, %v, want error %v", result, err, tt.wantErr)
+			}
+
+			//nothing is inserted, only the person of newBatchUsecase exists
+			persons, err := repo.GetPersons(&dto.PersonFilter{})
+			if err != nil || len(persons) != 1 {
+				t.Errorf("%d people exist after failed import, want 1", len(persons))
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+)
+
+//MergePerson merges source person into target one and returns the new version of target.
+//Non-zero version must match the current version of target
+func (per PersonUsecase) MergePerson(targetID uint, merge *dto.Merge, version uint, change *dto.ChangeInfo) (uint, error) {
+	if merge.SourceID == 0 || merge.SourceID == targetID {
+		return 0, dto.ErrInvalidMerge
+	}
+	for field, resolution := range merge.Fields {
+		if !contains(dto.MergeFields, field) ||
+			(resolution != dto.MergeKeepTarget && resolution != dto.MergeTakeSource && resolution != dto.MergePreferNonEmpty) {
+			return 0, dto.ErrInvalidMerge
+		}
+	}
+
+	var newVersion uint
+	err := per.personRepo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		//people are locked in order of ids, so concurrent merges do not deadlock
+		ids := []uint{targetID, merge.SourceID}
+		if ids[0] > ids[1] {
+			ids[0], ids[1] = ids[1], ids[0]
+		}
+		locked := map[uint]*dto.DBGetPerson{}
+		for _, id := range ids {
+			pers, err := txRepo.GetPersonByIdForUpdate(id)
+			if err != nil {
+				return err
+			}
+			if pers == nil || pers.DeletedAt.Valid {
+				return dto.ErrNotFound
+			}
+			locked[id] = pers
+		}
+
+		if version != 0 && locked[targetID].Version != version {
+			return dto.ErrVersionMismatch
+		}
+
+		target := dto.ToPerson(locked[targetID])
+		source := dto.ToPerson(locked[merge.SourceID])
+		mergeFields(target, source, merge.Fields)
+
+		dbPerson := dto.ToDBGetPerson(target)
+		setPhonetic(dbPerson)
+		var err error
+		newVersion, err = txRepo.UpdatePerson(dbPerson, change)
+		if err != nil {
+			return err
+		}
+
+		return txRepo.MergePerson(merge.SourceID, targetID, change)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+//GetRedirect gets id of person the given person was merged into, 0 if it was not merged
+func (per PersonUsecase) GetRedirect(id uint) (uint, error) {
+	return per.personRepo.GetRedirect(id)
+}
+
+//mergeFields resolves fields of target by resolutions
+func mergeFields(target *dto.Person, source *dto.Person, resolutions map[string]string) {
+	take := func(field string, targetEmpty bool) bool {
+		switch resolutions[field] {
+		case dto.MergeKeepTarget:
+			return false
+		case dto.MergeTakeSource:
+			return true
+		default:
+			return targetEmpty
+		}
+	}
+
+	if take("name", target.Name == "") {
+		target.Name = source.Name
+	}
+	if take("surname", target.Surname == "") {
+		target.Surname = source.Surname
+	}
+	if take("patronymic", target.Patronymic == "") {
+		target.Patronymic = source.Patronymic
+	}
+	if take("age", target.Age == 0) {
+		target.Age = source.Age
+	}
+	if take("gender", target.Gender == "") {
+		target.Gender = source.Gender
+	}
+	if take("nation", target.Nation == "") {
+		target.Nation = source.Nation
+	}
+}
@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"encoding/json"
+	personRep "server/server/internal/Person/repository"
+	"server/server/internal/domain/dto"
+	"server/server/internal/jsonpatch"
+)
+
+//PatchPerson applies patch to the current fields of person and returns the new version.
+//Unlike UpdatePerson, fields can be cleared, the result must still have all required fields
+func (per PersonUsecase) PatchPerson(id uint, patch *dto.Patch, version uint, change *dto.ChangeInfo) (uint, error) {
+	var newVersion uint
+	err := per.personRepo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		pers, err := txRepo.GetPersonByIdForUpdate(id)
+		if err != nil {
+			return err
+		}
+
+		if pers == nil || pers.DeletedAt.Valid {
+			return dto.ErrNotFound
+		}
+
+		if version != 0 && pers.Version != version {
+			return dto.ErrVersionMismatch
+		}
+
+		person := dto.ToPerson(pers)
+		before := dto.ToPersonFields(person)
+		fields, err := applyPatch(before, patch)
+		if err != nil {
+			return err
+		}
+
+		person.Name = fields.Name
+		person.Surname = fields.Surname
+		person.Patronymic = fields.Patronymic
+		person.Age = fields.Age
+		person.Gender = fields.Gender
+		person.Nation = fields.Nation
+
+		err = validateChanged(person, before)
+		if err != nil {
+			return err
+		}
+
+		dbPerson := dto.ToDBGetPerson(person)
+		setPhonetic(dbPerson)
+		newVersion, err = txRepo.UpdatePerson(dbPerson, change)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+func applyPatch(fields *dto.PersonFields, patch *dto.Patch) (*dto.PersonFields, error) {
+	doc, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patch.Kind {
+	case dto.PatchMerge:
+		doc, err = jsonpatch.MergePatch(doc, patch.Body)
+	case dto.PatchJSON:
+		doc, err = jsonpatch.Apply(doc, patch.Body)
+	case dto.PatchReplace:
+		doc = patch.Body
+	default:
+		return nil, dto.ErrInvalidPatch
+	}
+	if err != nil {
+		if err == jsonpatch.ErrPathNotFound || err == jsonpatch.ErrTestFailed {
+			return nil, dto.ErrPatchConflict
+		}
+		return nil, dto.ErrInvalidPatch
+	}
+
+	return dto.DecodePersonFields(doc)
+}
+
+//validateChanged validates fields of person which differ from before.
+//Unchanged fields are not validated, so people saved before validation can still be edited
+func validateChanged(person *dto.Person, before *dto.PersonFields) error {
+	after := dto.ToPersonFields(person)
+	changed := []string{}
+	if after.Name != before.Name {
+		changed = append(changed, "name")
+	}
+	if after.Surname != before.Surname {
+		changed = append(changed, "surname")
+	}
+	if after.Patronymic != before.Patronymic {
+		changed = append(changed, "patronymic")
+	}
+	if after.Age != before.Age {
+		changed = append(changed, "age")
+	}
+	if after.Gender != before.Gender {
+		changed = append(changed, "gender")
+	}
+	if after.Nation != before.Nation {
+		changed = append(changed, "nation")
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+	return person.Validate(changed...)
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"errors"
+	memoryRep "server/server/internal/Person/repository/memory"
+	"server/server/internal/domain/dto"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	fields := &dto.PersonFields{Name: "Ivan", Surname: "Petrov", Patronymic: "Ivanovich", Age: 30, Gender: "male", Nation: "RU"}
+
+	tests := []struct {
+		name      string
+		patch     *dto.Patch
+		want      *dto.PersonFields
+		wantErr   error
+		wantCodes []string
+	}{
+		{"merge", &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":31,"patronymic":null}`)},
+			&dto.PersonFields{Name: "Ivan", Surname: "Petrov", Age: 31, Gender: "male", Nation: "RU"}, nil, nil},
+		{"merge removes required field", &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"name":null,"surname":""}`)},
+			nil, nil, []string{"name:required", "surname:required"}},
+		{"merge of unknown field", &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"email":"a@b.c"}`)},
+			nil, nil, []string{"email:unknown_field"}},
+		{"merge of wrong type", &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":"old"}`)},
+			nil, nil, []string{"age:invalid_type"}},
+		{"merge of broken JSON", &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":`)}, nil, dto.ErrInvalidPatch, nil},
+		{"json patch", &dto.Patch{Kind: dto.PatchJSON, Body: []byte(`[{"op":"test","path":"/age","value":30},{"op":"replace","path":"/age","value":31}]`)},
+			&dto.PersonFields{Name: "Ivan", Surname: "Petrov", Patronymic: "Ivanovich", Age: 31, Gender: "male", Nation: "RU"}, nil, nil},
+		{"json patch test failed", &dto.Patch{Kind: dto.PatchJSON, Body: []byte(`[{"op":"test","path":"/age","value":40}]`)},
+			nil, dto.ErrPatchConflict, nil},
+		{"json patch of missing path", &dto.Patch{Kind: dto.PatchJSON, Body: []byte(`[{"op":"remove","path":"/email"}]`)},
+			nil, dto.ErrPatchConflict, nil},
+		{"json patch with unknown op", &dto.Patch{Kind: dto.PatchJSON, Body: []byte(`[{"op":"increment","path":"/age"}]`)},
+			nil, dto.ErrInvalidPatch, nil},
+		{"json patch removes required field", &dto.Patch{Kind: dto.PatchJSON, Body: []byte(`[{"op":"remove","path":"/gender"}]`)},
+			nil, nil, []string{"gender:required"}},
+		{"replace", &dto.Patch{Kind: dto.PatchReplace, Body: []byte(`{"name":"Anna","surname":"Smith","age":25,"gender":"female","nation":"GB"}`)},
+			&dto.PersonFields{Name: "Anna", Surname: "Smith", Age: 25, Gender: "female", Nation: "GB"}, nil, nil},
+		{"replace without required fields", &dto.Patch{Kind: dto.PatchReplace, Body: []byte(`{"name":"Anna","extra":1}`)},
+			nil, nil, []string{"surname:required", "age:required", "gender:required", "nation:required", "extra:unknown_field"}},
+		{"unknown kind", &dto.Patch{Kind: "xml", Body: []byte(`{}`)}, nil, dto.ErrInvalidPatch, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(fields, tt.patch)
+			if tt.wantCodes != nil {
+				assertFieldErrors(t, err, tt.wantCodes)
+				return
+			}
+			if err != tt.wantErr {
+				t.Fatalf("applyPatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Errorf("applyPatch() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchPerson(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	//saved before validation, so the name is not valid now
+	_, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan2", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, &dto.ChangeInfo{Actor: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	per := NewPersonUsecase(repo, &dto.DuplicateRules{})
+	change := &dto.ChangeInfo{Actor: "test"}
+
+	//unchanged invalid fields are not validated
+	version, err := per.PatchPerson(1, &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":31}`)}, 1, change)
+	if err != nil || version != 2 {
+		t.Fatalf("PatchPerson() = %d, %v, want 2, nil", version, err)
+	}
+
+	_, err = per.PatchPerson(1, &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":200,"nation":"XX"}`)}, 2, change)
+	assertFieldErrors(t, err, []string{"age:out_of_range", "nation:invalid_country"})
+
+	_, err = per.PatchPerson(1, &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":32}`)}, 1, change)
+	if err != dto.ErrVersionMismatch {
+		t.Errorf("PatchPerson() with old version error = %v, want %v", err, dto.ErrVersionMismatch)
+	}
+
+	_, err = per.PatchPerson(2, &dto.Patch{Kind: dto.PatchMerge, Body: []byte(`{"age":32}`)}, 0, change)
+	if !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("PatchPerson() of missing person error = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	person, err := repo.GetPersonById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.Age != 31 || person.Version != 2 {
+		t.Errorf("person has age %d and version %d after patches, want 31 and 2", person.Age, person.Version)
+	}
+}
+
+//assertFieldErrors checks that err is validation error with the given field:code pairs in order
+func assertFieldErrors(t *testing.T, err error, want []string) {
+	t.Helper()
+	var validationErr *dto.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error = %v, want validation error", err)
+	}
+	got := []string{}
+	for _, fieldErr := range validationErr.Errors {
+		got = append(got, fieldErr.Field+":"+fieldErr.Code)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("field errors = %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("field errors = %q, want %q", got, want)
+		}
+	}
+}
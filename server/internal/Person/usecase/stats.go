@@ -0,0 +1,37 @@
+package usecase
+
+import "server/server/internal/domain/dto"
+
+//defaultAgeBounds are bounds of age buckets used when they are not requested
+var defaultAgeBounds = []uint{0, 18, 30, 45, 60}
+
+//Counts of nations in statistics
+const (
+	defaultTopNations = 5
+	maxTopNations     = 100
+)
+
+//GetStats computes statistics of people passing filter. Bounds of age buckets must be ascending,
+//ages below the first bound are counted in additional bucket from 0
+func (per PersonUsecase) GetStats(filter *dto.PersonFilter, bounds []uint, top uint) (*dto.Stats, error) {
+	if len(bounds) == 0 {
+		bounds = defaultAgeBounds
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return nil, dto.ErrInvalidBuckets
+		}
+	}
+	if bounds[0] != 0 {
+		bounds = append([]uint{0}, bounds...)
+	}
+
+	if top == 0 {
+		top = defaultTopNations
+	}
+	if top > maxTopNations {
+		top = maxTopNations
+	}
+
+	return per.personRepo.GetStats(filter, bounds, top)
+}
@@ -1,36 +1,70 @@
 package usecase
 
 import (
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
+	"context"
+	"fmt"
 	personRep "server/server/internal/Person/repository"
 	"server/server/internal/domain/dto"
+	"server/server/internal/phonetic"
+	"sort"
+	"time"
+)
+
+//maxSuggestions is a maximum count of "did you mean" suggestions
+const maxSuggestions = 5
+
+//Counts of events in one page of the change feed
+const (
+	defaultChanges = 100
+	maxChanges     = 1000
 )
 
 type PersonUsecaseI interface {
-	GetPersons() ([]*dto.Person, error)
-	GetPersonsByAge(age uint) ([]*dto.Person, error)
-	GetPersonsByGender(gender string) ([]*dto.Person, error)
-	GetPersonsByNation(nation string) ([]*dto.Person, error)
-	GetPersonsWithLimit(limit uint) ([]*dto.Person, error)
-	DeletePerson(id uint) error
-	UpdatePerson(newPerson *dto.Person) error
-	CreatePerson(newPerson *dto.Person) (uint, error)
+	GetPersons(filter *dto.PersonFilter) ([]*dto.Person, error)
+	CountPersons(filter *dto.PersonFilter) (uint, error)
+	GetPerson(id uint) (*dto.Person, error)
+	StreamPersons(ctx context.Context, filter *dto.PersonFilter, fn func(person *dto.Person) error) error
+	GetPersonsByAge(age uint, filter *dto.PersonFilter) ([]*dto.Person, error)
+	GetPersonsByGender(gender string, filter *dto.PersonFilter) ([]*dto.Person, error)
+	GetPersonsByNation(nation string, filter *dto.PersonFilter) ([]*dto.Person, error)
+	GetPersonsWithLimit(limit uint, filter *dto.PersonFilter) ([]*dto.Person, error)
+	GetPersonsAsOf(asOf time.Time, filter *dto.PersonFilter) ([]*dto.Person, error)
+	DeletePerson(id uint, version uint, change *dto.ChangeInfo) error
+	RestorePerson(id uint, change *dto.ChangeInfo) error
+	PurgeDeletedPersons(retention time.Duration) (int64, error)
+	UpdatePerson(newPerson *dto.Person, version uint, change *dto.ChangeInfo) (uint, error)
+	PatchPerson(id uint, patch *dto.Patch, version uint, change *dto.ChangeInfo) (uint, error)
+	CreatePerson(ctx context.Context, newPerson *dto.Person, force bool, change *dto.ChangeInfo) (uint, error)
+	ApplyBatch(ctx context.Context, batch *dto.Batch, change *dto.ChangeInfo) (*dto.BatchResult, error)
+	ImportPersons(ctx context.Context, rows []*dto.ImportRow, options *dto.ImportOptions, change *dto.ChangeInfo) (*dto.ImportResult, error)
+	GetPersonHistory(id uint) ([]*dto.HistoryRecord, error)
+	RevertPerson(id uint, version uint, change *dto.ChangeInfo) (uint, error)
+	GetChanges(cursor string, limit uint) (*dto.ChangeFeed, error)
+	GetDuplicates() ([]*dto.DuplicateCluster, error)
+	GetStats(filter *dto.PersonFilter, bounds []uint, top uint) (*dto.Stats, error)
+	Aggregate(aggregate *dto.Aggregate, filter *dto.PersonFilter) (*dto.AggregateResult, error)
+	MergePerson(targetID uint, merge *dto.Merge, version uint, change *dto.ChangeInfo) (uint, error)
+	GetRedirect(id uint) (uint, error)
+	SearchPersons(search *dto.PersonSearch) (*dto.SearchResult, error)
+	FillPhonetic() error
 }
 
 type PersonUsecase struct {
-	personRepo personRep.PersonRepositoryI
+	personRepo     personRep.PersonRepositoryI
+	duplicateRules *dto.DuplicateRules
+	enricher       *enricher
 }
 
-func NewPersonUsecase(personRepI personRep.PersonRepositoryI) *PersonUsecase {
+func NewPersonUsecase(personRepI personRep.PersonRepositoryI, duplicateRules *dto.DuplicateRules) *PersonUsecase {
 	return &PersonUsecase{
-		personRepo: personRepI,
+		personRepo:     personRepI,
+		duplicateRules: duplicateRules,
+		enricher:       newEnricher(agifyURL, genderizeURL, nationalizeURL),
 	}
 }
 
-func (per PersonUsecase) GetPersons() ([]*dto.Person, error) {
-	dbpers, err := per.personRepo.GetPersons()
+func (per PersonUsecase) GetPersons(filter *dto.PersonFilter) ([]*dto.Person, error) {
+	dbpers, err := per.personRepo.GetPersons(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +77,37 @@ func (per PersonUsecase) GetPersons() ([]*dto.Person, error) {
 	return persons, nil
 }
 
-func (per PersonUsecase) GetPersonsByAge(age uint) ([]*dto.Person, error) {
-	dbpers, err := per.personRepo.GetPersonsByAge(age)
+//CountPersons counts people passing filter
+func (per PersonUsecase) CountPersons(filter *dto.PersonFilter) (uint, error) {
+	return per.personRepo.CountPersons(filter)
+}
+
+//GetPerson gets person by id, soft-deleted person is returned together with ErrGone
+func (per PersonUsecase) GetPerson(id uint) (*dto.Person, error) {
+	dbper, err := per.personRepo.GetPersonById(id)
+	if err != nil {
+		return nil, err
+	}
+	if dbper == nil {
+		return nil, dto.ErrNotFound
+	}
+
+	person := dto.ToPerson(dbper)
+	if person.DeletedAt != nil {
+		return person, dto.ErrGone
+	}
+	return person, nil
+}
+
+//StreamPersons passes people to fn one by one without loading all of them
+func (per PersonUsecase) StreamPersons(ctx context.Context, filter *dto.PersonFilter, fn func(person *dto.Person) error) error {
+	return per.personRepo.StreamPersons(ctx, filter, func(dbper *dto.DBGetPerson) error {
+		return fn(dto.ToPerson(dbper))
+	})
+}
+
+func (per PersonUsecase) GetPersonsByAge(age uint, filter *dto.PersonFilter) ([]*dto.Person, error) {
+	dbpers, err := per.personRepo.GetPersonsByAge(age, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +120,8 @@ func (per PersonUsecase) GetPersonsByAge(age uint) ([]*dto.Person, error) {
 	return persons, nil
 }
 
-func (per PersonUsecase) GetPersonsByGender(gender string) ([]*dto.Person, error) {
-	dbpers, err := per.personRepo.GetPersonsByGender(gender)
+func (per PersonUsecase) GetPersonsByGender(gender string, filter *dto.PersonFilter) ([]*dto.Person, error) {
+	dbpers, err := per.personRepo.GetPersonsByGender(gender, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -71,8 +134,8 @@ func (per PersonUsecase) GetPersonsByGender(gender string) ([]*dto.Person, error
 	return persons, nil
 }
 
-func (per PersonUsecase) GetPersonsByNation(nation string) ([]*dto.Person, error) {
-	dbpers, err := per.personRepo.GetPersonsByNation(nation)
+func (per PersonUsecase) GetPersonsByNation(nation string, filter *dto.PersonFilter) ([]*dto.Person, error) {
+	dbpers, err := per.personRepo.GetPersonsByNation(nation, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -85,8 +148,8 @@ func (per PersonUsecase) GetPersonsByNation(nation string) ([]*dto.Person, error
 	return persons, nil
 }
 
-func (per PersonUsecase) GetPersonsWithLimit(limit uint) ([]*dto.Person, error) {
-	dbpers, err := per.personRepo.GetPersonsWithLimit(limit)
+func (per PersonUsecase) GetPersonsWithLimit(limit uint, filter *dto.PersonFilter) ([]*dto.Person, error) {
+	dbpers, err := per.personRepo.GetPersonsWithLimit(limit, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -99,22 +162,56 @@ func (per PersonUsecase) GetPersonsWithLimit(limit uint) ([]*dto.Person, error)
 	return persons, nil
 }
 
-func (per PersonUsecase) DeletePerson(id uint) error {
-	err := per.personRepo.DeletePerson(id)
-	if err != nil {
-		return err
-	}
-	return nil
+//DeletePerson deletes person, non-zero version must match the current version of person
+func (per PersonUsecase) DeletePerson(id uint, version uint, change *dto.ChangeInfo) error {
+	return per.personRepo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		if version != 0 {
+			pers, err := txRepo.GetPersonByIdForUpdate(id)
+			if err != nil {
+				return err
+			}
+			if pers == nil || pers.DeletedAt.Valid {
+				return dto.ErrNotFound
+			}
+			if pers.Version != version {
+				return dto.ErrVersionMismatch
+			}
+		}
+
+		return txRepo.DeletePerson(id, version, change)
+	})
 }
 
-func (per PersonUsecase) UpdatePerson(newPerson *dto.Person) error {
-	pers, err := per.personRepo.GetPersonById(newPerson.ID)
-	if err != nil {
-		return err
-	}
+//RestorePerson restores soft-deleted person
+func (per PersonUsecase) RestorePerson(id uint, change *dto.ChangeInfo) error {
+	return per.personRepo.RestorePerson(id, change)
+}
+
+//PurgeDeletedPersons removes people which were deleted longer than retention ago
+func (per PersonUsecase) PurgeDeletedPersons(retention time.Duration) (int64, error) {
+	return per.personRepo.PurgeDeletedPersons(time.Now().Add(-retention), &dto.ChangeInfo{Actor: dto.SystemActor})
+}
+
+//UpdatePerson updates non-empty fields of person and returns the new version of person.
+//Non-zero version must match the current version of person
+func (per PersonUsecase) UpdatePerson(newPerson *dto.Person, version uint, change *dto.ChangeInfo) (uint, error) {
+	var newVersion uint
+	err := per.personRepo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		pers, err := txRepo.GetPersonByIdForUpdate(newPerson.ID)
+		if err != nil {
+			return err
+		}
+
+		if pers == nil || pers.DeletedAt.Valid {
+			return dto.ErrNotFound
+		}
+
+		if version != 0 && pers.Version != version {
+			return dto.ErrVersionMismatch
+		}
 
-	if pers != nil {
 		person := dto.ToPerson(pers)
+		before := dto.ToPersonFields(person)
 		if newPerson.Name != "" {
 			person.Name = newPerson.Name
 		}
@@ -139,65 +236,275 @@ func (per PersonUsecase) UpdatePerson(newPerson *dto.Person) error {
 			person.Nation = newPerson.Nation
 		}
 
-		return per.personRepo.UpdatePerson(dto.ToDBGetPerson(person))
-	}
+		err = validateChanged(person, before)
+		if err != nil {
+			return err
+		}
 
-	return dto.ErrNotFound
+		dbPerson := dto.ToDBGetPerson(person)
+		setPhonetic(dbPerson)
+		newVersion, err = txRepo.UpdatePerson(dbPerson, change)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newVersion, nil
 }
 
-func (per PersonUsecase) CreatePerson(newPerson *dto.Person) (uint, error) {
-	person := dto.ToDBGetPerson(newPerson)
-
-	ageresp, err := http.Get("https://api.agify.io/?name=" + person.Name)
+//CreatePerson creates person, similar existing people are reported by dto.DuplicateError unless force is set
+func (per PersonUsecase) CreatePerson(ctx context.Context, newPerson *dto.Person, force bool, change *dto.ChangeInfo) (uint, error) {
+	person, err := per.prepareCreate(ctx, newPerson, force)
 	if err != nil {
 		return 0, err
 	}
 
-	agebody, err := ioutil.ReadAll(ageresp.Body)
+	personid, err := per.personRepo.CreatePerson(person, change)
 	if err != nil {
 		return 0, err
 	}
 
-	ageObject := &dto.Age{}
-	err = json.Unmarshal(agebody, &ageObject)
+	return personid, nil
 
-	person.Age = ageObject.Age
+}
 
-	genderresp, err := http.Get("https://api.genderize.io/?name=" + person.Name)
+//prepareCreate validates new person, checks duplicates and fills other fields by external APIs
+func (per PersonUsecase) prepareCreate(ctx context.Context, newPerson *dto.Person, force bool) (*dto.DBGetPerson, error) {
+	err := per.checkCreate(newPerson, force)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return per.enrich(ctx, newPerson)
+}
 
-	genderbody, err := ioutil.ReadAll(genderresp.Body)
+//checkCreate validates new person and checks that it is not similar to existing people unless force is set
+func (per PersonUsecase) checkCreate(newPerson *dto.Person, force bool) error {
+	//other fields are filled by external APIs
+	err := newPerson.Validate("name", "surname", "patronymic")
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	genderObject := &dto.Gender{}
-	err = json.Unmarshal(genderbody, &genderObject)
+	if !force {
+		candidates, err := per.findDuplicates(newPerson)
+		if err != nil {
+			return err
+		}
+		if len(candidates) != 0 {
+			return &dto.DuplicateError{Candidates: candidates}
+		}
+	}
+	return nil
+}
 
-	person.Gender = genderObject.Gender
+//GetPersonsAsOf gets people as they were at the given time
+func (per PersonUsecase) GetPersonsAsOf(asOf time.Time, filter *dto.PersonFilter) ([]*dto.Person, error) {
+	records, err := per.personRepo.GetLatestHistory(asOf)
+	if err != nil {
+		return nil, err
+	}
+	persons := []*dto.Person{}
+	for _, dbrecord := range records {
+		record, err := dto.ToHistoryRecord(dbrecord)
+		if err != nil {
+			return nil, err
+		}
+		if record.After == nil || !filter.Match(record.After) {
+			continue
+		}
+		persons = append(persons, record.After)
+	}
 
-	nationresp, err := http.Get("https://api.nationalize.io/?name=" + person.Name)
+	return persons, nil
+}
+
+//GetPersonHistory gets all changes of person
+func (per PersonUsecase) GetPersonHistory(id uint) ([]*dto.HistoryRecord, error) {
+	dbrecords, err := per.personRepo.GetPersonHistory(id)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	if len(dbrecords) == 0 {
+		pers, err := per.personRepo.GetPersonById(id)
+		if err != nil {
+			return nil, err
+		}
+		if pers == nil {
+			return nil, dto.ErrNotFound
+		}
 	}
 
-	nationbody, err := ioutil.ReadAll(nationresp.Body)
+	records := []*dto.HistoryRecord{}
+	for _, dbrecord := range dbrecords {
+		record, err := dto.ToHistoryRecord(dbrecord)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+//RevertPerson restores fields of person from the given version and returns the new version.
+//Restored fields are validated like updated ones, because rules may have changed since the version
+func (per PersonUsecase) RevertPerson(id uint, version uint, change *dto.ChangeInfo) (uint, error) {
+	var newVersion uint
+	err := per.personRepo.WithTx(func(txRepo personRep.PersonRepositoryI) error {
+		pers, err := txRepo.GetPersonByIdForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if pers == nil || pers.DeletedAt.Valid {
+			return dto.ErrNotFound
+		}
+
+		dbrecord, err := txRepo.GetHistoryByVersion(id, version)
+		if err != nil {
+			return err
+		}
+		if dbrecord == nil {
+			return fmt.Errorf("%w: version %d", dto.ErrVersionNotFound, version)
+		}
+		record, err := dto.ToHistoryRecord(dbrecord)
+		if err != nil {
+			return err
+		}
+
+		reverted := record.After
+		reverted.DeletedAt = nil
+		err = validateChanged(reverted, dto.ToPersonFields(dto.ToPerson(pers)))
+		if err != nil {
+			return err
+		}
+
+		dbPerson := dto.ToDBGetPerson(reverted)
+		dbPerson.Version = pers.Version
+		setPhonetic(dbPerson)
+		newVersion, err = txRepo.UpdatePerson(dbPerson, change)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
+	return newVersion, nil
+}
+
+//GetChanges gets events of the change feed after cursor
+func (per PersonUsecase) GetChanges(cursor string, limit uint) (*dto.ChangeFeed, error) {
+	after, err := dto.ParseCursor(cursor)
+	if err != nil {
+		return nil, dto.ErrInvalidCursor
+	}
+	if limit == 0 {
+		limit = defaultChanges
+	}
+	if limit > maxChanges {
+		limit = maxChanges
+	}
 
-	nationObject := &dto.Nation{}
-	err = json.Unmarshal(nationbody, &nationObject)
+	dbrecords, err := per.personRepo.GetHistorySince(after, limit+1)
+	if err != nil {
+		return nil, err
+	}
 
-	person.Nation = nationObject.Nation[0].CountryId
+	feed := &dto.ChangeFeed{Events: []*dto.ChangeEvent{}, NextCursor: dto.FormatCursor(after)}
+	if uint(len(dbrecords)) > limit {
+		feed.HasMore = true
+		dbrecords = dbrecords[:limit]
+	}
+	for _, dbrecord := range dbrecords {
+		event, err := dto.ToChangeEvent(dbrecord)
+		if err != nil {
+			return nil, err
+		}
+		feed.Events = append(feed.Events, event)
+		feed.NextCursor = event.Cursor
+	}
+	return feed, nil
+}
 
-	personid, err := per.personRepo.CreatePerson(person)
+//SearchPersons searches people by names and suggests similar names if nobody is found
+func (per PersonUsecase) SearchPersons(search *dto.PersonSearch) (*dto.SearchResult, error) {
+	if search.Mode == dto.SearchModePhonetic {
+		search.NameCodes = phonetic.Encode(search.Name)
+		search.SurnameCodes = phonetic.Encode(search.Surname)
+	}
+
+	dbpers, err := per.personRepo.SearchPersons(search)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	result := &dto.SearchResult{People: []*dto.Person{}}
+	for _, dbper := range dbpers {
+		result.People = append(result.People, dto.ToPerson(dbper))
+	}
+	if len(result.People) != 0 {
+		return result, nil
 	}
 
-	return personid, nil
+	if search.Name != "" {
+		names, err := per.personRepo.GetDistinctNames()
+		if err != nil {
+			return nil, err
+		}
+		result.Suggestions = append(result.Suggestions, suggest("name", search.Name, names)...)
+	}
+	if search.Surname != "" {
+		surnames, err := per.personRepo.GetDistinctSurnames()
+		if err != nil {
+			return nil, err
+		}
+		result.Suggestions = append(result.Suggestions, suggest("surname", search.Surname, surnames)...)
+	}
+
+	return result, nil
+}
 
+//FillPhonetic computes phonetic codes for people added before phonetic search existed
+func (per PersonUsecase) FillPhonetic() error {
+	dbpers, err := per.personRepo.GetPersonsWithoutPhonetic()
+	if err != nil {
+		return err
+	}
+	for _, dbper := range dbpers {
+		setPhonetic(dbper)
+		err = per.personRepo.UpdatePersonPhonetic(dbper)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setPhonetic(person *dto.DBGetPerson) {
+	person.NamePhonetic = phonetic.Encode(person.Name)
+	person.SurnamePhonetic = phonetic.Encode(person.Surname)
+}
+
+func suggest(field string, value string, variants []string) []*dto.Suggestion {
+	maxDistance := 1
+	if len([]rune(value)) >= 5 {
+		maxDistance = 2
+	}
+
+	type candidate struct {
+		value    string
+		distance int
+	}
+	candidates := []candidate{}
+	for _, variant := range variants {
+		distance := phonetic.Distance(value, variant)
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{value: variant, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	suggestions := []*dto.Suggestion{}
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, &dto.Suggestion{Field: field, Value: candidates[i].value})
+	}
+	return suggestions
 }
@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"errors"
+	memoryRep "server/server/internal/Person/repository/memory"
+	"server/server/internal/domain/dto"
+	"testing"
+)
+
+func TestRevertPerson(t *testing.T) {
+	repo := memoryRep.NewPersonRepo()
+	change := &dto.ChangeInfo{Actor: "test"}
+	//saved before validation, so the first version is not valid now
+	_, err := repo.CreatePerson(&dto.DBGetPerson{Name: "Ivan2", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU"}, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.UpdatePerson(&dto.DBGetPerson{ID: 1, Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male", Nation: "RU", Version: 1}, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.UpdatePerson(&dto.DBGetPerson{ID: 1, Name: "Ivan", Surname: "Petrov", Age: 31, Gender: "male", Nation: "RU", Version: 2}, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	per := NewPersonUsecase(repo, &dto.DuplicateRules{})
+
+	_, err = per.RevertPerson(1, 1, change)
+	assertFieldErrors(t, err, []string{"name:invalid_characters"})
+
+	_, err = per.RevertPerson(1, 7, change)
+	if !errors.Is(err, dto.ErrVersionNotFound) {
+		t.Errorf("RevertPerson() to missing version error = %v, want %v", err, dto.ErrVersionNotFound)
+	}
+	_, err = per.RevertPerson(2, 1, change)
+	if !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("RevertPerson() of missing person error = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	version, err := per.RevertPerson(1, 2, change)
+	if err != nil || version != 4 {
+		t.Fatalf("RevertPerson() = %d, %v, want 4, nil", version, err)
+	}
+	person, err := repo.GetPersonById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.Age != 30 || person.Name != "Ivan" {
+		t.Errorf("reverted person is %s aged %d, want Ivan aged 30", person.Name, person.Age)
+	}
+}
@@ -0,0 +1,41 @@
+package dto
+
+//Dimensions people can be grouped by
+const (
+	DimensionNation = "nation"
+	DimensionGender = "gender"
+	DimensionAge    = "age"
+)
+
+//Metrics computed for groups of people
+const (
+	MetricCount  = "count"
+	MetricAvgAge = "avg_age"
+	MetricMinAge = "min_age"
+	MetricMaxAge = "max_age"
+)
+
+//AggregateDimensions and AggregateMetrics are whitelists of aggregation requests
+var (
+	AggregateDimensions = []string{DimensionNation, DimensionGender, DimensionAge}
+	AggregateMetrics    = []string{MetricCount, MetricAvgAge, MetricMinAge, MetricMaxAge}
+)
+
+//Aggregate is a request to group people by dimensions and compute metrics of groups
+type Aggregate struct {
+	GroupBy []string
+	Metrics []string
+}
+
+//AggregateRow is a group of people with its metrics
+type AggregateRow struct {
+	Group   map[string]string  `json:"group"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+//AggregateResult are groups ordered by values of dimensions
+type AggregateResult struct {
+	GroupBy []string        `json:"group_by"`
+	Metrics []string        `json:"metrics"`
+	Rows    []*AggregateRow `json:"rows"`
+}
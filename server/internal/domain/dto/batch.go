@@ -0,0 +1,47 @@
+package dto
+
+import "encoding/json"
+
+//Operations of batch
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+//MaxBatchOperations is a maximum count of operations in one batch
+const MaxBatchOperations = 5000
+
+//Batch is a list of operations applied in order. Atomic batch is applied in one transaction,
+//so it is rolled back if any of operations fails
+type Batch struct {
+	Atomic     bool              `json:"atomic"`
+	Operations []*BatchOperation `json:"operations"`
+}
+
+//BatchOperation is an operation of batch. Person is fields of created person
+//or JSON Merge Patch of updated one, zero Version means any version
+type BatchOperation struct {
+	Op      string          `json:"op"`
+	ID      uint            `json:"id,omitempty"`
+	Version uint            `json:"version,omitempty"`
+	Force   bool            `json:"force,omitempty"`
+	Person  json.RawMessage `json:"person,omitempty"`
+}
+
+//BatchItemResult is a result of operation, Err is set if operation failed or was rolled back
+type BatchItemResult struct {
+	Index   int
+	Op      string
+	ID      uint
+	Version uint
+	Err     error
+}
+
+//BatchResult is a result of batch with results of operations in order of batch
+type BatchResult struct {
+	Atomic    bool
+	Succeeded int
+	Failed    int
+	Results   []*BatchItemResult
+}
@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Types of events in the change feed
+const (
+	ChangeTypeCreate = "create"
+	ChangeTypeUpdate = "update"
+	ChangeTypeDelete = "delete"
+)
+
+//FeedPosition is a position of history record in the change feed. Records are ordered by TxID and then by ID,
+//TxID is zero in stores which commit records in order of their ids
+type FeedPosition struct {
+	TxID uint64
+	ID   uint
+}
+
+//ChangeEvent is a change of person in the feed, deleted person is a tombstone without Person
+type ChangeEvent struct {
+	Cursor    string    `json:"cursor"`
+	Type      string    `json:"type"`
+	PersonID  uint      `json:"person_id"`
+	Version   uint      `json:"version"`
+	Person    *Person   `json:"person,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+//ChangeFeed is a page of change events, the next page is requested with NextCursor
+type ChangeFeed struct {
+	Events     []*ChangeEvent `json:"events"`
+	NextCursor string         `json:"next_cursor"`
+	HasMore    bool           `json:"has_more"`
+}
+
+//ToChangeEvent converts history record to event, restored person is created again for the mirror
+func ToChangeEvent(record *DBHistoryRecord) (*ChangeEvent, error) {
+	event := &ChangeEvent{
+		Cursor:    FormatCursor(FeedPosition{TxID: record.TxID, ID: record.ID}),
+		PersonID:  record.PersonID,
+		Version:   record.Version,
+		ChangedAt: record.ChangedAt,
+	}
+
+	switch record.Operation {
+	case OperationCreate, OperationRestore:
+		event.Type = ChangeTypeCreate
+	case OperationUpdate:
+		event.Type = ChangeTypeUpdate
+	default:
+		event.Type = ChangeTypeDelete
+		return event, nil
+	}
+
+	var err error
+	event.Person, err = snapshotToPerson(record.After)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+//FormatCursor makes cursor of the change feed from position of history record, it is "txid.id"
+//or just id of record if TxID is zero
+func FormatCursor(position FeedPosition) string {
+	id := strconv.FormatUint(uint64(position.ID), 10)
+	if position.TxID == 0 {
+		return id
+	}
+	return strconv.FormatUint(position.TxID, 10) + "." + id
+}
+
+//ParseCursor gets position of history record from cursor, empty cursor is the start of the feed
+func ParseCursor(cursor string) (FeedPosition, error) {
+	position := FeedPosition{}
+	if cursor == "" {
+		return position, nil
+	}
+	strTxID, strID, found := strings.Cut(cursor, ".")
+	if !found {
+		strID = strTxID
+	} else {
+		txID, err := strconv.ParseUint(strTxID, 10, 64)
+		if err != nil {
+			return position, err
+		}
+		position.TxID = txID
+	}
+	id, err := strconv.ParseUint(strID, 10, 64)
+	if err != nil {
+		return position, err
+	}
+	position.ID = uint(id)
+	return position, nil
+}
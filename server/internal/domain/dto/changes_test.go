@@ -0,0 +1,37 @@
+package dto
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	tests := []struct {
+		cursor   string
+		position FeedPosition
+		wantErr  bool
+	}{
+		{"", FeedPosition{}, false},
+		//stores which commit in order of ids and cursors made before transaction ids were recorded
+		{"42", FeedPosition{ID: 42}, false},
+		{"1001.42", FeedPosition{TxID: 1001, ID: 42}, false},
+		{"1001.", FeedPosition{}, true},
+		{".42", FeedPosition{}, true},
+		{"abc", FeedPosition{}, true},
+		{"1.2.3", FeedPosition{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cursor, func(t *testing.T) {
+			position, err := ParseCursor(tt.cursor)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCursor(%q) = %+v, want error", tt.cursor, position)
+				}
+				return
+			}
+			if err != nil || position != tt.position {
+				t.Fatalf("ParseCursor(%q) = %+v, %v, want %+v", tt.cursor, position, err, tt.position)
+			}
+			if tt.cursor != "" && FormatCursor(position) != tt.cursor {
+				t.Errorf("FormatCursor(%+v) = %q, want %q", position, FormatCursor(position), tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"strconv"
+	"strings"
+)
+
+//Rules of duplicate detection
+const (
+	DuplicateRuleExact    = "exact"
+	DuplicateRulePhonetic = "phonetic"
+	DuplicateRuleFuzzy    = "fuzzy"
+)
+
+//DuplicateRules are enabled rules of duplicate detection.
+//Exact rule compares names case-insensitively, phonetic rule compares Daitch-Mokotoff codes
+//and fuzzy rule allows MaxDistance typos in normalized name and surname together.
+//Phonetic and fuzzy rules also match people whose name and surname are swapped
+type DuplicateRules struct {
+	Exact       bool
+	Phonetic    bool
+	Fuzzy       bool
+	MaxDistance int
+}
+
+//NewDuplicateRules enables rules by their names
+func NewDuplicateRules(rules []string, maxDistance int) *DuplicateRules {
+	res := &DuplicateRules{MaxDistance: maxDistance}
+	for _, rule := range rules {
+		switch rule {
+		case DuplicateRuleExact:
+			res.Exact = true
+		case DuplicateRulePhonetic:
+			res.Phonetic = true
+		case DuplicateRuleFuzzy:
+			res.Fuzzy = true
+		}
+	}
+	return res
+}
+
+//DuplicateCandidate is an existing person similar to the created one
+type DuplicateCandidate struct {
+	Person   *Person `json:"person"`
+	Rule     string  `json:"rule"`
+	Distance int     `json:"distance"`
+}
+
+//DuplicateMatch is a pair of similar people in the duplicates report
+type DuplicateMatch struct {
+	FirstID  uint   `json:"first_id"`
+	SecondID uint   `json:"second_id"`
+	Rule     string `json:"rule"`
+	Distance int    `json:"distance"`
+}
+
+//DuplicateCluster is a group of people connected by matches
+type DuplicateCluster struct {
+	People  []*Person         `json:"people"`
+	Matches []*DuplicateMatch `json:"matches"`
+}
+
+//DuplicateError is returned when created person is similar to existing people
+type DuplicateError struct {
+	Candidates []*DuplicateCandidate
+}
+
+func (err *DuplicateError) Error() string {
+	ids := []string{}
+	for _, candidate := range err.Candidates {
+		ids = append(ids, strconv.FormatUint(uint64(candidate.Person.ID), 10))
+	}
+	return "person may be a duplicate of " + strings.Join(ids, ", ")
+}
@@ -2,7 +2,22 @@ package dto
 
 import "errors"
 
-//Errors
+// Errors
 var (
-	ErrNotFound = errors.New("item is not found")
+	ErrNotFound           = errors.New("item is not found")
+	ErrGone               = errors.New("item is deleted")
+	ErrVersionMismatch    = errors.New("version of item does not match")
+	ErrInvalidCursor      = errors.New("cursor is invalid")
+	ErrInvalidPatch       = errors.New("patch is invalid")
+	ErrPatchConflict      = errors.New("patch does not apply to item")
+	ErrInvalidMerge       = errors.New("merge is invalid")
+	ErrInvalidBuckets     = errors.New("age buckets must be ascending")
+	ErrInvalidAggregate   = errors.New("dimension or metric is not supported")
+	ErrInvalidBatch       = errors.New("batch is invalid")
+	ErrRolledBack         = errors.New("operation is rolled back because batch failed")
+	ErrInvalidImport      = errors.New("imported file is invalid")
+	ErrDuplicateRow       = errors.New("row repeats an earlier row of the file")
+	ErrImportTimeout      = errors.New("import took longer than its time budget")
+	ErrDuplicateOperation = errors.New("creation repeats an earlier creation of the batch")
+	ErrVersionNotFound    = errors.New("version is not in history of item")
 )
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"strings"
+	"time"
+)
+
+//PersonFilter restricts lists of people. Name, Surname, Gender and Nation are compared ignoring case
+//and empty ones match any value. After and Limit choose a page of GetPersons ordered by id,
+//zero Limit means all people after After
+type PersonFilter struct {
+	IncludeDeleted bool
+	CreatedAfter   *time.Time
+	UpdatedSince   *time.Time
+	Name           string
+	Surname        string
+	Gender         string
+	Nation         string
+	MinAge         *uint
+	MaxAge         *uint
+	After          uint
+	Limit          uint
+}
+
+//Match checks that person passes the filter, the page is not checked
+func (filter *PersonFilter) Match(person *Person) bool {
+	if person.DeletedAt != nil && !filter.IncludeDeleted {
+		return false
+	}
+	if filter.CreatedAfter != nil && !person.CreatedAt.After(*filter.CreatedAfter) {
+		return false
+	}
+	if filter.UpdatedSince != nil && (person.UpdatedAt == nil || person.UpdatedAt.Before(*filter.UpdatedSince)) {
+		return false
+	}
+	if !matchField(filter.Name, person.Name) || !matchField(filter.Surname, person.Surname) ||
+		!matchField(filter.Gender, person.Gender) || !matchField(filter.Nation, person.Nation) {
+		return false
+	}
+	if filter.MinAge != nil && person.Age < *filter.MinAge {
+		return false
+	}
+	if filter.MaxAge != nil && person.Age > *filter.MaxAge {
+		return false
+	}
+	return true
+}
+
+func matchField(expected string, value string) bool {
+	return expected == "" || strings.EqualFold(expected, value)
+}
@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//Operations saved in history of person
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+	OperationMerge   = "merge"
+)
+
+//SystemActor is an actor of changes made by the server itself
+const SystemActor = "system"
+
+//ChangeInfo describes who and in which request changes person
+type ChangeInfo struct {
+	Actor     string
+	RequestID string
+}
+
+type DBHistoryRecord struct {
+	ID        uint
+	TxID      uint64
+	PersonID  uint
+	Operation string
+	Version   uint
+	Before    []byte
+	After     []byte
+	Actor     string
+	RequestID string
+	ChangedAt time.Time
+}
+
+type HistoryRecord struct {
+	ID        uint      `json:"id"`
+	PersonID  uint      `json:"person_id"`
+	Operation string    `json:"operation"`
+	Version   uint      `json:"version"`
+	Before    *Person   `json:"before"`
+	After     *Person   `json:"after"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"request_id"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type Revert struct {
+	Version uint `json:"version"`
+}
+
+func ToHistoryRecord(record *DBHistoryRecord) (*HistoryRecord, error) {
+	before, err := snapshotToPerson(record.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := snapshotToPerson(record.After)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryRecord{
+		ID:        record.ID,
+		PersonID:  record.PersonID,
+		Operation: record.Operation,
+		Version:   record.Version,
+		Before:    before,
+		After:     after,
+		Actor:     record.Actor,
+		RequestID: record.RequestID,
+		ChangedAt: record.ChangedAt,
+	}, nil
+}
+
+func snapshotToPerson(snapshot []byte) (*Person, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	person := &Person{}
+	err := json.Unmarshal(snapshot, person)
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
@@ -0,0 +1,55 @@
+package dto
+
+import "time"
+
+//Statuses of imported rows
+const (
+	ImportRowValid   = "valid"
+	ImportRowCreated = "created"
+	ImportRowSkipped = "skipped"
+	ImportRowFailed  = "failed"
+)
+
+//MaxImportRows is a maximum count of rows in one imported file
+const MaxImportRows = 10000
+
+//MaxImportDuration is a time budget of enriching rows of imported file by external APIs
+const MaxImportDuration = 2 * time.Minute
+
+//ImportFields are fields of person read from imported files, other fields are filled by external APIs
+var ImportFields = []string{"name", "surname", "patronymic"}
+
+//ImportRow is a person read from row of imported file, Line is a number of the row in the file
+type ImportRow struct {
+	Line   int
+	Person *Person
+}
+
+//ImportOptions are options of import. Dry run validates rows and finds duplicates without creating people,
+//force creates people even if they may be duplicates
+type ImportOptions struct {
+	DryRun bool
+	Force  bool
+}
+
+//ImportRowResult is a result of row. Err is set for skipped and failed rows,
+//DuplicateOfLine is set for rows which repeat earlier rows of the file
+type ImportRowResult struct {
+	Line            int
+	Status          string
+	ID              uint
+	DuplicateOfLine int
+	Err             error
+}
+
+//ImportResult is a summary of import with results of rows in order of file.
+//Valid counts rows which would be created by dry run
+type ImportResult struct {
+	DryRun  bool
+	Total   int
+	Valid   int
+	Created int
+	Skipped int
+	Failed  int
+	Rows    []*ImportRowResult
+}
@@ -0,0 +1,18 @@
+package dto
+
+//Resolutions of field conflicts when people are merged
+const (
+	MergeKeepTarget     = "keep_target"
+	MergeTakeSource     = "take_source"
+	MergePreferNonEmpty = "prefer_non_empty"
+)
+
+//MergeFields are fields of person resolved by merge
+var MergeFields = []string{"name", "surname", "patronymic", "age", "gender", "nation"}
+
+//Merge is a request to merge source person into target one. Fields maps names of fields
+//to resolutions, fields which are not listed prefer non-empty value of target
+type Merge struct {
+	SourceID uint              `json:"source_id"`
+	Fields   map[string]string `json:"fields"`
+}
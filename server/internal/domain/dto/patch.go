@@ -0,0 +1,116 @@
+package dto
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+//Kinds of patch
+const (
+	PatchMerge   = "merge"
+	PatchJSON    = "json"
+	PatchReplace = "replace"
+)
+
+//PersonRequiredFields are fields which person can not be left without
+var PersonRequiredFields = []string{"name", "surname", "age", "gender", "nation"}
+
+//Patch is a change of person: JSON Merge Patch, JSON Patch or a full replacement document
+type Patch struct {
+	Kind string
+	Body []byte
+}
+
+//PersonFields are fields of person which clients can change, patches are applied to them
+type PersonFields struct {
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+	Age        uint   `json:"age"`
+	Gender     string `json:"gender"`
+	Nation     string `json:"nation"`
+}
+
+func ToPersonFields(person *Person) *PersonFields {
+	return &PersonFields{
+		Name:       person.Name,
+		Surname:    person.Surname,
+		Patronymic: person.Patronymic,
+		Age:        person.Age,
+		Gender:     person.Gender,
+		Nation:     person.Nation,
+	}
+}
+
+//DecodePersonFields decodes document made by patch. Required fields must be present and not empty,
+//unknown fields are not allowed
+func DecodePersonFields(doc []byte) (*PersonFields, error) {
+	raw := map[string]json.RawMessage{}
+	err := json.Unmarshal(doc, &raw)
+	if err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	errs := []*FieldError{}
+	for _, field := range PersonRequiredFields {
+		value, ok := raw[field]
+		if !ok || string(value) == "null" || string(value) == `""` {
+			errs = append(errs, &FieldError{Field: field, Code: CodeRequired, Message: "field is required"})
+		}
+	}
+
+	fields := &PersonFields{}
+	order := map[string]int{}
+	for i, field := range MergeFields {
+		order[field] = i
+	}
+	for field, value := range raw {
+		if _, ok := order[field]; !ok {
+			errs = append(errs, &FieldError{Field: field, Code: CodeUnknownField, Message: "field is not a field of person"})
+			continue
+		}
+		if string(value) == "null" || string(value) == `""` {
+			continue
+		}
+		err = json.Unmarshal(value, fields.pointer(field))
+		if err != nil {
+			errs = append(errs, &FieldError{Field: field, Code: CodeInvalidType, Message: "field has invalid type"})
+		}
+	}
+
+	if len(errs) != 0 {
+		//errors are ordered like fields of person, unknown fields go last
+		position := func(field string) int {
+			if i, ok := order[field]; ok {
+				return i
+			}
+			return len(order)
+		}
+		sort.SliceStable(errs, func(i, j int) bool {
+			if position(errs[i].Field) != position(errs[j].Field) {
+				return position(errs[i].Field) < position(errs[j].Field)
+			}
+			return errs[i].Field < errs[j].Field
+		})
+		return nil, &ValidationError{Errors: errs}
+	}
+	return fields, nil
+}
+
+//pointer gets pointer to the field with the given JSON name
+func (fields *PersonFields) pointer(field string) interface{} {
+	switch field {
+	case "name":
+		return &fields.Name
+	case "surname":
+		return &fields.Surname
+	case "patronymic":
+		return &fields.Patronymic
+	case "age":
+		return &fields.Age
+	case "gender":
+		return &fields.Gender
+	default:
+		return &fields.Nation
+	}
+}
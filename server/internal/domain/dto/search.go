@@ -0,0 +1,29 @@
+package dto
+
+//Search modes
+const (
+	SearchModeExact    = "exact"
+	SearchModePhonetic = "phonetic"
+)
+
+//PersonSearch is a search query by names
+type PersonSearch struct {
+	Name         string
+	Surname      string
+	Patronymic   string
+	Mode         string
+	NameCodes    []string
+	SurnameCodes []string
+}
+
+//Suggestion is a "did you mean" variant for a searched field
+type Suggestion struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+//SearchResult is a result of search by names
+type SearchResult struct {
+	People      []*Person     `json:"people"`
+	Suggestions []*Suggestion `json:"suggestions,omitempty"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+//Stats are demographic statistics of people
+type Stats struct {
+	Total      int64         `json:"total"`
+	Age        *AgeStats     `json:"age"`
+	Genders    []*ValueCount `json:"genders"`
+	TopNations []*ValueCount `json:"top_nations"`
+}
+
+//AgeStats are statistics of age of people
+type AgeStats struct {
+	Mean      float64      `json:"mean"`
+	Median    float64      `json:"median"`
+	Histogram []*AgeBucket `json:"histogram"`
+}
+
+//AgeBucket is a count of people with age from From inclusive to To exclusive, the last bucket has no To
+type AgeBucket struct {
+	From  uint  `json:"from"`
+	To    *uint `json:"to,omitempty"`
+	Count int64 `json:"count"`
+}
+
+//ValueCount is a count of people with the value of field
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+//NewAgeHistogram makes empty buckets from ascending bounds, the first bound must be 0
+func NewAgeHistogram(bounds []uint) []*AgeBucket {
+	histogram := []*AgeBucket{}
+	for i, bound := range bounds {
+		bucket := &AgeBucket{From: bound}
+		if i+1 < len(bounds) {
+			to := bounds[i+1]
+			bucket.To = &to
+		}
+		histogram = append(histogram, bucket)
+	}
+	return histogram
+}
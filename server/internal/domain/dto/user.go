@@ -2,26 +2,37 @@ package dto
 
 import (
 	"database/sql"
+	"time"
 )
 
 type DBGetPerson struct {
-	ID         uint
-	Name       string
-	Surname    string
-	Patronymic sql.NullString
-	Age        uint
-	Gender     string
-	Nation     string
+	ID              uint
+	Name            string
+	Surname         string
+	Patronymic      sql.NullString
+	Age             uint
+	Gender          string
+	Nation          string
+	NamePhonetic    []string
+	SurnamePhonetic []string
+	DeletedAt       sql.NullTime
+	Version         uint
+	CreatedAt       time.Time
+	UpdatedAt       sql.NullTime
 }
 
 type Person struct {
-	ID         uint   `json:"id"`
-	Name       string `json:"name"`
-	Surname    string `json:"surname"`
-	Patronymic string `json:"patronymic"`
-	Age        uint   `json:"age"`
-	Gender     string `json:"gender"`
-	Nation     string `json:"nation"`
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Surname    string     `json:"surname"`
+	Patronymic string     `json:"patronymic"`
+	Age        uint       `json:"age"`
+	Gender     string     `json:"gender"`
+	Nation     string     `json:"nation"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	Version    uint       `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
 }
 
 type Age struct {
@@ -53,6 +64,10 @@ func ToPerson(person *DBGetPerson) *Person {
 		Age:        person.Age,
 		Gender:     person.Gender,
 		Nation:     person.Nation,
+		DeletedAt:  transformSQLTimeToTime(person.DeletedAt),
+		Version:    person.Version,
+		CreatedAt:  person.CreatedAt,
+		UpdatedAt:  transformSQLTimeToTime(person.UpdatedAt),
 	}
 }
 
@@ -65,6 +80,10 @@ func ToDBGetPerson(person *Person) *DBGetPerson {
 		Age:        person.Age,
 		Gender:     person.Gender,
 		Nation:     person.Nation,
+		DeletedAt:  transformTimeToSQLTime(person.DeletedAt),
+		Version:    person.Version,
+		CreatedAt:  person.CreatedAt,
+		UpdatedAt:  transformTimeToSQLTime(person.UpdatedAt),
 	}
 }
 
@@ -81,3 +100,17 @@ func transformSQLStringToString(str sql.NullString) string {
 	}
 	return ""
 }
+
+func transformTimeToSQLTime(t *time.Time) sql.NullTime {
+	if t != nil {
+		return sql.NullTime{Time: *t, Valid: true}
+	}
+	return sql.NullTime{Valid: false}
+}
+
+func transformSQLTimeToTime(t sql.NullTime) *time.Time {
+	if t.Valid {
+		return &t.Time
+	}
+	return nil
+}
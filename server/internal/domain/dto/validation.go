@@ -0,0 +1,160 @@
+package dto
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Codes of field errors
+const (
+	CodeRequired          = "required"
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeOutOfRange        = "out_of_range"
+	CodeNotAllowed        = "not_allowed"
+	CodeInvalidCountry    = "invalid_country"
+	CodeUnknownField      = "unknown_field"
+	CodeInvalidType       = "invalid_type"
+)
+
+//Limits of person fields
+const (
+	maxNameLength = 100
+	maxAge        = 150
+)
+
+//Genders of person
+var Genders = []string{"male", "female"}
+
+//FieldError describes why a field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//ValidationError is returned when person has invalid fields
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (err *ValidationError) Error() string {
+	fields := []string{}
+	for _, fieldErr := range err.Errors {
+		fields = append(fields, fieldErr.Field+": "+fieldErr.Code)
+	}
+	return "person is invalid: " + strings.Join(fields, ", ")
+}
+
+//rule checks value of field, it returns code and message of error or empty code.
+//Rules except required skip empty values
+type rule func(value interface{}) (string, string)
+
+//fieldRules are rules of one field of person
+type fieldRules struct {
+	field string
+	value func(person *Person) interface{}
+	rules []rule
+}
+
+//personRules are rules of person fields in order of reported errors
+var personRules = []fieldRules{
+	{"name", func(person *Person) interface{} { return person.Name }, []rule{required, maxLength(maxNameLength), letters}},
+	{"surname", func(person *Person) interface{} { return person.Surname }, []rule{required, maxLength(maxNameLength), letters}},
+	{"patronymic", func(person *Person) interface{} { return person.Patronymic }, []rule{maxLength(maxNameLength), letters}},
+	{"age", func(person *Person) interface{} { return person.Age }, []rule{atMost(maxAge)}},
+	{"gender", func(person *Person) interface{} { return person.Gender }, []rule{oneOf(Genders)}},
+	{"nation", func(person *Person) interface{} { return person.Nation }, []rule{country}},
+}
+
+//Validate checks the given fields of person, all fields are checked if none are given.
+//The first failed rule of every field is reported
+func (person *Person) Validate(fields ...string) error {
+	errs := []*FieldError{}
+	for _, field := range personRules {
+		if len(fields) != 0 && !containsString(fields, field.field) {
+			continue
+		}
+		value := field.value(person)
+		for _, check := range field.rules {
+			code, message := check(value)
+			if code != "" {
+				errs = append(errs, &FieldError{Field: field.field, Code: code, Message: message})
+				break
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func required(value interface{}) (string, string) {
+	if str, ok := value.(string); ok && strings.TrimSpace(str) == "" {
+		return CodeRequired, "field is required"
+	}
+	return "", ""
+}
+
+func maxLength(length int) rule {
+	return func(value interface{}) (string, string) {
+		if str, ok := value.(string); ok && utf8.RuneCountInString(str) > length {
+			return CodeTooLong, "field must be at most " + strconv.Itoa(length) + " characters long"
+		}
+		return "", ""
+	}
+}
+
+//letters allows Latin and Cyrillic letters separated by spaces, hyphens and apostrophes
+func letters(value interface{}) (string, string) {
+	str, ok := value.(string)
+	if !ok || str == "" {
+		return "", ""
+	}
+	for i, r := range str {
+		isLetter := unicode.IsLetter(r) && (unicode.Is(unicode.Latin, r) || unicode.Is(unicode.Cyrillic, r))
+		isSeparator := (r == ' ' || r == '-' || r == '\'') && i != 0
+		if !isLetter && !isSeparator {
+			return CodeInvalidCharacters, "field may contain only Latin or Cyrillic letters, spaces, hyphens and apostrophes"
+		}
+	}
+	return "", ""
+}
+
+func atMost(limit uint) rule {
+	return func(value interface{}) (string, string) {
+		if number, ok := value.(uint); ok && number > limit {
+			return CodeOutOfRange, "field must be between 0 and " + strconv.FormatUint(uint64(limit), 10)
+		}
+		return "", ""
+	}
+}
+
+func oneOf(allowed []string) rule {
+	return func(value interface{}) (string, string) {
+		if str, ok := value.(string); ok && str != "" && !containsString(allowed, str) {
+			return CodeNotAllowed, "field must be one of " + strings.Join(allowed, ", ")
+		}
+		return "", ""
+	}
+}
+
+//country allows ISO 3166-1 alpha-2 codes
+func country(value interface{}) (string, string) {
+	if str, ok := value.(string); ok && str != "" && !countryCodes[str] {
+		return CodeInvalidCountry, "field must be ISO 3166-1 alpha-2 country code"
+	}
+	return "", ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
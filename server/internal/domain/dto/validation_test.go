@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *Person {
+		return &Person{Name: "Ivan", Surname: "Petrov", Patronymic: "Ivanovich", Age: 30, Gender: "male", Nation: "RU"}
+	}
+
+	tests := []struct {
+		name   string
+		change func(person *Person)
+		fields []string
+		want   []string
+	}{
+		{"valid", func(person *Person) {}, nil, nil},
+		{"cyrillic", func(person *Person) {
+			person.Name, person.Surname, person.Patronymic = "Иван", "Петров-Водкин", "Иванович"
+		}, nil, nil},
+		{"apostrophe", func(person *Person) { person.Surname = "O'Neil" }, nil, nil},
+		{"double name", func(person *Person) { person.Name = "Anna Maria" }, nil, nil},
+		{"empty patronymic", func(person *Person) { person.Patronymic = "" }, nil, nil},
+		{"empty gender and nation", func(person *Person) { person.Gender, person.Nation = "", "" }, nil, nil},
+		{"max age", func(person *Person) { person.Age = 150 }, nil, nil},
+		{"max length", func(person *Person) { person.Name = strings.Repeat("я", 100) }, nil, nil},
+		{"missing name", func(person *Person) { person.Name = "" }, nil, []string{"name:required"}},
+		{"blank surname", func(person *Person) { person.Surname = "  " }, nil, []string{"surname:required"}},
+		{"too long", func(person *Person) { person.Name = strings.Repeat("я", 101) }, nil, []string{"name:too_long"}},
+		{"digits", func(person *Person) { person.Name = "Ivan2" }, nil, []string{"name:invalid_characters"}},
+		{"leading hyphen", func(person *Person) { person.Surname = "-Petrov" }, nil, []string{"surname:invalid_characters"}},
+		{"greek letters", func(person *Person) { person.Patronymic = "Αλέξης" }, nil, []string{"patronymic:invalid_characters"}},
+		{"too old", func(person *Person) { person.Age = 151 }, nil, []string{"age:out_of_range"}},
+		{"unknown gender", func(person *Person) { person.Gender = "unknown" }, nil, []string{"gender:not_allowed"}},
+		{"gender is case sensitive", func(person *Person) { person.Gender = "Male" }, nil, []string{"gender:not_allowed"}},
+		{"unknown country", func(person *Person) { person.Nation = "XX" }, nil, []string{"nation:invalid_country"}},
+		{"lower case country", func(person *Person) { person.Nation = "ru" }, nil, []string{"nation:invalid_country"}},
+		{"errors in field order", func(person *Person) { person.Nation, person.Name, person.Age = "XX", "", 200 },
+			nil, []string{"name:required", "age:out_of_range", "nation:invalid_country"}},
+		{"first failed rule only", func(person *Person) { person.Name = strings.Repeat("1", 101) }, nil, []string{"name:too_long"}},
+		{"only given fields", func(person *Person) { person.Name, person.Age = "", 200 }, []string{"age"}, []string{"age:out_of_range"}},
+		{"given fields are valid", func(person *Person) { person.Name = "" }, []string{"surname", "nation"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			person := valid()
+			tt.change(person)
+			err := person.Validate(tt.fields...)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() = %v, want validation error", err)
+			}
+			got := []string{}
+			for _, fieldErr := range validationErr.Errors {
+				if fieldErr.Message == "" {
+					t.Errorf("field %s has no message", fieldErr.Field)
+				}
+				got = append(got, fieldErr.Field+":"+fieldErr.Code)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() errors = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Errors: []*FieldError{
+		{Field: "name", Code: CodeRequired},
+		{Field: "age", Code: CodeOutOfRange},
+	}}
+	want := "person is invalid: name: required, age: out_of_range"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,245 @@
+package graphqllimit
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+//typenameField is resolved by the executor itself, so it costs nothing
+const typenameField = "__typename"
+
+//Codes of errors of limits
+const (
+	CodeDepth      = "DEPTH_LIMIT"
+	CodeComplexity = "COMPLEXITY_LIMIT"
+)
+
+//Limits restrict depth and complexity of GraphQL operations, zero limit means no limit.
+//Every resolved field costs one, selections of object fields cost as many times as multipliers
+//of the fields tell, so fields of list items are counted for every item
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+	//Multipliers are keyed by "Type.field" and get arguments of the field
+	Multipliers map[string]func(args map[string]interface{}) int
+}
+
+//Error is a violated limit, its code is put into extensions of GraphQL error
+type Error struct {
+	Message string
+	Code    string
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func (err *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": err.Code}
+}
+
+//measure is a state of checking one operation
+type measure struct {
+	limits    *Limits
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+//Check measures the operation of validated document, fragments of the document must not have cycles.
+//Variables are values of request, defaults of variables are used for missing ones
+func (limits *Limits) Check(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) error {
+	m := &measure{limits: limits, fragments: map[string]*ast.FragmentDefinition{}, variables: map[string]interface{}{}}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch value := definition.(type) {
+		case *ast.FragmentDefinition:
+			m.fragments[value.Name.Value] = value
+		case *ast.OperationDefinition:
+			if operationName == "" || (value.Name != nil && value.Name.Value == operationName) {
+				operation = value
+			}
+		}
+	}
+	//executor reports missing operations
+	if operation == nil {
+		return nil
+	}
+
+	for _, definition := range operation.VariableDefinitions {
+		name := definition.Variable.Name.Value
+		if value, ok := variables[name]; ok {
+			m.variables[name] = normalize(value)
+		} else if definition.DefaultValue != nil {
+			m.variables[name] = m.value(definition.DefaultValue)
+		}
+	}
+
+	root := schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	if root == nil {
+		return nil
+	}
+
+	complexity, err := m.selections(root, operation.SelectionSet, 1)
+	if err != nil {
+		return err
+	}
+	if limits.MaxComplexity != 0 && complexity > limits.MaxComplexity {
+		return &Error{
+			Message: "operation complexity " + strconv.Itoa(complexity) + " exceeds limit " + strconv.Itoa(limits.MaxComplexity),
+			Code:    CodeComplexity,
+		}
+	}
+	return nil
+}
+
+//selections returns complexity of selections of object at the depth
+func (m *measure) selections(object *graphql.Object, set *ast.SelectionSet, depth int) (int, error) {
+	if m.limits.MaxDepth != 0 && depth > m.limits.MaxDepth {
+		return 0, &Error{Message: "operation depth exceeds limit " + strconv.Itoa(m.limits.MaxDepth), Code: CodeDepth}
+	}
+
+	complexity := 0
+	for _, field := range m.collectFields(set, nil) {
+		name := field.Name.Value
+		if name == typenameField {
+			continue
+		}
+		definition, ok := object.Fields()[name]
+		if !ok {
+			//introspection fields are not fields of the object
+			complexity++
+			continue
+		}
+		child, ok := graphql.GetNamed(definition.Type).(*graphql.Object)
+		if !ok || field.SelectionSet == nil {
+			complexity++
+			continue
+		}
+
+		children, err := m.selections(child, field.SelectionSet, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		multiplier := 1
+		if multiply, ok := m.limits.Multipliers[object.Name()+"."+name]; ok {
+			multiplier = multiply(m.arguments(field.Arguments))
+		}
+		complexity = addCost(complexity, 1, children, multiplier)
+	}
+	return complexity, nil
+}
+
+//collectFields flattens fragments and drops fields which are skipped by @skip and @include
+func (m *measure) collectFields(set *ast.SelectionSet, fields []*ast.Field) []*ast.Field {
+	if set == nil {
+		return fields
+	}
+	for _, selection := range set.Selections {
+		switch value := selection.(type) {
+		case *ast.Field:
+			if m.included(value.Directives) {
+				fields = append(fields, value)
+			}
+		case *ast.InlineFragment:
+			if m.included(value.Directives) {
+				fields = m.collectFields(value.SelectionSet, fields)
+			}
+		case *ast.FragmentSpread:
+			fragment, ok := m.fragments[value.Name.Value]
+			if ok && m.included(value.Directives) {
+				fields = m.collectFields(fragment.SelectionSet, fields)
+			}
+		}
+	}
+	return fields
+}
+
+func (m *measure) included(directives []*ast.Directive) bool {
+	for _, directive := range directives {
+		condition := false
+		for _, argument := range directive.Arguments {
+			if argument.Name.Value == "if" {
+				condition, _ = m.value(argument.Value).(bool)
+			}
+		}
+		switch directive.Name.Value {
+		case "skip":
+			if condition {
+				return false
+			}
+		case "include":
+			if !condition {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (m *measure) arguments(arguments []*ast.Argument) map[string]interface{} {
+	args := map[string]interface{}{}
+	for _, argument := range arguments {
+		args[argument.Name.Value] = m.value(argument.Value)
+	}
+	return args
+}
+
+//value converts literal or variable to Go value, integers are int64
+func (m *measure) value(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.Variable:
+		return m.variables[value.Name.Value]
+	case *ast.IntValue:
+		number, err := strconv.ParseInt(value.Value, 10, 64)
+		if err != nil {
+			return math.MaxInt64
+		}
+		return number
+	case *ast.FloatValue:
+		number, _ := strconv.ParseFloat(value.Value, 64)
+		return number
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.ListValue:
+		items := []interface{}{}
+		for _, item := range value.Values {
+			items = append(items, m.value(item))
+		}
+		return items
+	case *ast.ObjectValue:
+		fields := map[string]interface{}{}
+		for _, field := range value.Fields {
+			fields[field.Name.Value] = m.value(field.Value)
+		}
+		return fields
+	}
+	return nil
+}
+
+//normalize makes integers of JSON variables int64 like integer literals
+func normalize(value interface{}) interface{} {
+	number, ok := value.(float64)
+	if ok && number == math.Trunc(number) && math.Abs(number) <= math.MaxInt64 {
+		return int64(number)
+	}
+	return value
+}
+
+//addCost adds cost of a field with children resolved multiplier times, the sum stops growing at MaxInt
+//so huge pages can not overflow it
+func addCost(complexity int, cost int, children int, multiplier int) int {
+	if multiplier != 0 && children > (math.MaxInt-complexity-cost)/multiplier {
+		return math.MaxInt
+	}
+	return complexity + cost + children*multiplier
+}
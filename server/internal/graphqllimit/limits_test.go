@@ -0,0 +1,104 @@
+package graphqllimit
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+//newTestSchema makes schema of recursive nodes, lists of nodes have 10 items by default
+func newTestSchema(t *testing.T) *graphql.Schema {
+	node := graphql.NewObject(graphql.ObjectConfig{Name: "Node", Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.ID},
+		"name": &graphql.Field{Type: graphql.String},
+	}})
+	children := &graphql.Field{
+		Type: graphql.NewList(node),
+		Args: graphql.FieldConfigArgument{"first": {Type: graphql.Int, DefaultValue: 10}},
+	}
+	node.AddFieldConfig("child", &graphql.Field{Type: node})
+	node.AddFieldConfig("children", children)
+
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"node":  &graphql.Field{Type: node},
+		"nodes": children,
+	}})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &schema
+}
+
+func pageMultiplier(args map[string]interface{}) int {
+	first, ok := args["first"].(int64)
+	if !ok {
+		return 10
+	}
+	return int(first)
+}
+
+func TestCheck(t *testing.T) {
+	schema := newTestSchema(t)
+	tests := []struct {
+		name          string
+		maxDepth      int
+		maxComplexity int
+		query         string
+		variables     map[string]interface{}
+		wantCode      string
+	}{
+		{"depth at limit", 4, 0, `{node{child{child{id}}}}`, nil, ""},
+		{"depth over limit", 3, 0, `{node{child{child{id}}}}`, nil, CodeDepth},
+		{"depth of fragment", 3, 0, `{node{...deep}} fragment deep on Node{child{child{id}}}`, nil, CodeDepth},
+		{"depth of inline fragment", 3, 0, `{node{... on Node{child{child{id}}}}}`, nil, CodeDepth},
+		{"skipped field is not counted", 3, 0, `{node{child{child @skip(if: true){id}}}}`, nil, ""},
+		{"skipped by variable", 3, 0, `query($deep: Boolean!){node{child{child @include(if: $deep){id}}}}`,
+			map[string]interface{}{"deep": true}, CodeDepth},
+		{"default of variable", 3, 0, `query($deep: Boolean = false){node{child{child @include(if: $deep){id}}}}`, nil, ""},
+		{"no depth limit", 0, 0, `{node{child{child{child{child{child{id}}}}}}}`, nil, ""},
+		//node costs 1 and its two scalars cost 1 each
+		{"complexity at limit", 0, 3, `{node{id name}}`, nil, ""},
+		{"complexity over limit", 0, 2, `{node{id name}}`, nil, CodeComplexity},
+		//list multiplies complexity of its selections: 1 + 2*10
+		{"default page", 0, 21, `{nodes{id name}}`, nil, ""},
+		{"default page over limit", 0, 20, `{nodes{id name}}`, nil, CodeComplexity},
+		{"page of argument", 0, 100, `{nodes(first: 50){id name}}`, nil, CodeComplexity},
+		//variables are decoded from JSON
+		{"page of variable", 0, 100, `query($first: Int){nodes(first: $first){id name}}`, map[string]interface{}{"first": float64(5)}, ""},
+		{"nested lists", 0, 1000, `{nodes(first: 10){children(first: 10){children(first: 10){id}}}}`, nil, CodeComplexity},
+		{"huge pages", 0, 1000, `{nodes(first: 2147483647){children(first: 2147483647){children(first: 2147483647){id}}}}`, nil, CodeComplexity},
+		{"aliases are counted", 0, 3, `{a: node{id} b: node{id}}`, nil, CodeComplexity},
+		{"typename is free", 0, 2, `{node{id __typename}}`, nil, ""},
+		{"no complexity limit", 0, 0, `{nodes(first: 100){children(first: 100){id}}}`, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			limits := &Limits{
+				MaxDepth:      tt.maxDepth,
+				MaxComplexity: tt.maxComplexity,
+				Multipliers: map[string]func(args map[string]interface{}) int{
+					"Query.nodes":   pageMultiplier,
+					"Node.children": pageMultiplier,
+				},
+			}
+
+			err = limits.Check(schema, doc, "", tt.variables)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			limitErr, ok := err.(*Error)
+			if !ok || limitErr.Code != tt.wantCode {
+				t.Fatalf("Check() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
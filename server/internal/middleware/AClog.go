@@ -26,6 +26,13 @@ func (r *ResponseRecorder) WriteHeader(status int) {
 	r.ResponseWriter.WriteHeader(status)
 }
 
+//Flush sends buffered response to client, it is used by streaming responses
+func (r *ResponseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 //NewLoggingResponseWriter creates new ResponseRecorder
 func NewLoggingResponseWriter(w http.ResponseWriter) *ResponseRecorder {
 	return &ResponseRecorder{w, http.StatusOK}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+//AdminTokenHeader is a header with admin token
+const AdminTokenHeader = "X-Admin-Token"
+
+type adminKey struct{}
+
+//AdminAuth recognizes requests of admins by token
+type AdminAuth struct {
+	token string
+}
+
+//NewAdminAuth creates new object of AdminAuth, empty token disables admin access
+func NewAdminAuth(token string) *AdminAuth {
+	return &AdminAuth{
+		token: token,
+	}
+}
+
+//AdminMiddleware marks requests with valid admin token
+func (auth *AdminAuth) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(AdminTokenHeader)
+		if auth.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(auth.token)) == 1 {
+			r = r.WithContext(context.WithValue(r.Context(), adminKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//IsAdmin reports whether request is made by admin
+func IsAdmin(r *http.Request) bool {
+	return IsAdminContext(r.Context())
+}
+
+//IsAdminContext reports whether context belongs to request made by admin
+func IsAdminContext(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
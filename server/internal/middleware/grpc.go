@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+//requestIDMetadata is a key of gRPC header with id of request
+const requestIDMetadata = "request-id"
+
+type requestIDKey struct{}
+
+//serverStream is a gRPC stream with context changed by interceptors
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *serverStream) Context() context.Context {
+	return stream.ctx
+}
+
+//RequestID gets id of gRPC request set by access log interceptors
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+//UnaryInterceptor creates access logs of unary gRPC calls
+func (ac *ACLog) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = ac.startCall(ctx)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	ac.logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+//StreamInterceptor creates access logs of streaming gRPC calls
+func (ac *ACLog) StreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := ac.startCall(stream.Context())
+	start := time.Now()
+	err := handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	ac.logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+//RecoverUnaryInterceptor turns panics of unary gRPC calls into Internal errors, so one call can not stop the server
+func (ac *ACLog) RecoverUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = ac.recovered(ctx, info.FullMethod, p)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+//RecoverStreamInterceptor turns panics of streaming gRPC calls into Internal errors
+func (ac *ACLog) RecoverStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = ac.recovered(stream.Context(), info.FullMethod, p)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+//recovered logs panic with its stack, clients get Internal error without details
+func (ac *ACLog) recovered(ctx context.Context, method string, p interface{}) error {
+	ac.errorLogger.Errorw("panic in gRPC call",
+		zap.Error(fmt.Errorf("%v", p)),
+		zap.String("request-id", RequestID(ctx)),
+		zap.String("url", method),
+		zap.ByteString("stack", debug.Stack()),
+	)
+	return status.Error(codes.Internal, "internal error")
+}
+
+//startCall generates id of request and sends it to client in header
+func (ac *ACLog) startCall(ctx context.Context) context.Context {
+	requestID := uuid.New().String()
+	err := grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+	if err != nil {
+		ac.LogError("problems with setting gRPC header", err, requestID, "")
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func (ac *ACLog) logCall(ctx context.Context, method string, start time.Time, err error) {
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	ac.logger.Infow("Access log info",
+		zap.String("method", "gRPC"),
+		zap.String("remote addr", remoteAddr),
+		zap.String("url", method),
+		zap.String("request-id", RequestID(ctx)),
+		zap.String("status", status.Code(err).String()),
+		zap.Duration("work time", time.Duration(time.Since(start).Microseconds())),
+	)
+}
+
+//UnaryInterceptor marks unary gRPC calls with valid admin token in metadata
+func (auth *AdminAuth) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(auth.adminContext(ctx), req)
+}
+
+//StreamInterceptor marks streaming gRPC calls with valid admin token in metadata
+func (auth *AdminAuth) StreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: stream, ctx: auth.adminContext(stream.Context())})
+}
+
+func (auth *AdminAuth) adminContext(ctx context.Context) context.Context {
+	tokens := metadata.ValueFromIncomingContext(ctx, AdminTokenHeader)
+	if auth.token != "" && len(tokens) != 0 && subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(auth.token)) == 1 {
+		return context.WithValue(ctx, adminKey{}, true)
+	}
+	return ctx
+}
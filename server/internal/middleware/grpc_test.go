@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoverUnaryInterceptor(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	ac := NewACLog(zap.NewNop().Sugar(), zap.New(core).Sugar())
+	info := &grpc.UnaryServerInfo{FullMethod: "/person.v1.PersonService/CreatePerson"}
+
+	tests := []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		wantCode codes.Code
+		wantLogs int
+	}{
+		{"ok", func(ctx context.Context, req interface{}) (interface{}, error) { return "resp", nil }, codes.OK, 0},
+		{"error", func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		}, codes.NotFound, 0},
+		{"panic", func(ctx context.Context, req interface{}) (interface{}, error) {
+			var countries []string
+			return countries[0], nil
+		}, codes.Internal, 1},
+		{"panic with error", func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic(errors.New("broken"))
+		}, codes.Internal, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			_, err := ac.RecoverUnaryInterceptor(context.Background(), nil, info, tt.handler)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+			if logs.Len() != tt.wantLogs {
+				t.Errorf("%d errors logged, want %d", logs.Len(), tt.wantLogs)
+			}
+			//details of panic are not sent to client
+			if tt.wantCode == codes.Internal && status.Convert(err).Message() != "internal error" {
+				t.Errorf("message = %q", status.Convert(err).Message())
+			}
+		})
+	}
+}
+
+func TestRecoverStreamInterceptor(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	ac := NewACLog(zap.NewNop().Sugar(), zap.New(core).Sugar())
+	info := &grpc.StreamServerInfo{FullMethod: "/person.v1.PersonService/ListPersons"}
+	stream := &serverStream{ctx: context.Background()}
+
+	err := ac.RecoverStreamInterceptor(nil, stream, info, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("broken")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("code = %v, want %v", status.Code(err), codes.Internal)
+	}
+	if logs.Len() != 1 || logs.All()[0].ContextMap()["url"] != info.FullMethod {
+		t.Errorf("logs = %+v, want one error of %s", logs.All(), info.FullMethod)
+	}
+}
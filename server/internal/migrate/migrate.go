@@ -0,0 +1,229 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//separator separates up and down parts of tern migration
+const separator = "---- create above / drop below ----"
+
+//lockID is a key of postgres advisory lock taken while migrating
+const lockID = 7406198
+
+//Dialects of databases
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+//Errors
+var (
+	ErrIrreversible   = errors.New("migration is irreversible")
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+//Migration is a migration in tern format: up statements, separator line and down statements
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+//Migrator applies migrations to database
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []*Migration
+}
+
+//Load reads migrations named like 001_create_person_table.sql from directory of fsys
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	migrations := []*Migration{}
+	for i, file := range files {
+		name := path.Base(file)
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil || version != i+1 {
+			return nil, fmt.Errorf("migration %s must be numbered %03d", name, i+1)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.SplitN(string(content), separator, 2)
+		migration := &Migration{Version: version, Name: name, Up: parts[0]}
+		if len(parts) == 2 {
+			migration.Down = parts[1]
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+//NewMigrator creates new object of Migrator
+func NewMigrator(db *sql.DB, dialect string, migrations []*Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}
+}
+
+//Status gets current version of database schema and the latest known version
+func (m *Migrator) Status() (int, int, error) {
+	var current int
+	err := m.withConn(func(conn *sql.Conn) error {
+		var err error
+		current, err = m.currentVersion(conn)
+		return err
+	})
+	return current, len(m.migrations), err
+}
+
+//Up applies all new migrations
+func (m *Migrator) Up() error {
+	return m.To(len(m.migrations))
+}
+
+//Down rolls back the last applied migration
+func (m *Migrator) Down() error {
+	return m.withConn(func(conn *sql.Conn) error {
+		current, err := m.currentVersion(conn)
+		if err != nil || current == 0 {
+			return err
+		}
+		return m.migrate(conn, current, current-1)
+	})
+}
+
+//To applies or rolls back migrations until database schema has the given version
+func (m *Migrator) To(version int) error {
+	if version < 0 || version > len(m.migrations) {
+		return ErrUnknownVersion
+	}
+	return m.withConn(func(conn *sql.Conn) error {
+		current, err := m.currentVersion(conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(conn, current, version)
+	})
+}
+
+func (m *Migrator) migrate(conn *sql.Conn, current int, target int) error {
+	if current > len(m.migrations) {
+		return ErrUnknownVersion
+	}
+	for current < target {
+		err := m.apply(conn, m.migrations[current].Up, current+1)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.migrations[current].Name, err)
+		}
+		current++
+	}
+	for current > target {
+		migration := m.migrations[current-1]
+		if strings.TrimSpace(migration.Down) == "" {
+			return fmt.Errorf("%s: %w", migration.Name, ErrIrreversible)
+		}
+		err := m.apply(conn, migration.Down, current-1)
+		if err != nil {
+			return fmt.Errorf("%s: %w", migration.Name, err)
+		}
+		current--
+	}
+	return nil
+}
+
+//apply runs statements of migration and saves new version in one transaction
+func (m *Migrator) apply(conn *sql.Conn, statements string, version int) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(statements)
+	if err == nil {
+		err = m.setVersion(tx, version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//withConn runs fn on one connection, for postgres the connection holds advisory lock
+//so concurrent instances do not migrate at the same time
+func (m *Migrator) withConn(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == DialectPostgres {
+		_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID)
+		if err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockID)
+
+		//version table of tern, so databases migrated by tern keep their version
+		_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS public.schema_version (version int4 NOT NULL)`)
+		if err != nil {
+			return err
+		}
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) currentVersion(conn *sql.Conn) (int, error) {
+	var version int
+	if m.dialect == DialectSQLite {
+		err := conn.QueryRowContext(context.Background(), `PRAGMA user_version`).Scan(&version)
+		return version, err
+	}
+
+	err := conn.QueryRowContext(context.Background(), `SELECT version FROM public.schema_version`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (m *Migrator) setVersion(tx *sql.Tx, version int) error {
+	if m.dialect == DialectSQLite {
+		_, err := tx.Exec(`PRAGMA user_version = ` + strconv.Itoa(version))
+		return err
+	}
+
+	res, err := tx.Exec(`UPDATE public.schema_version SET version = $1`, version)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil || updated != 0 {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO public.schema_version (version) VALUES ($1)`, version)
+	return err
+}
@@ -0,0 +1,252 @@
+package phonetic
+
+import (
+	"sort"
+	"strings"
+)
+
+//codeLength is a length of Daitch-Mokotoff code
+const codeLength = 6
+
+//rule is a Daitch-Mokotoff rule: pattern and its codes at the start of a word,
+//before a vowel and in any other position. Alternatives are separated by '|'
+type rule struct {
+	pattern     string
+	atStart     []string
+	beforeVowel []string
+	other       []string
+}
+
+var rawRules = [][4]string{
+	{"a", "0", "", ""},
+	{"ai", "0", "1", ""},
+	{"aj", "0", "1", ""},
+	{"ay", "0", "1", ""},
+	{"au", "0", "7", ""},
+	{"e", "0", "", ""},
+	{"ei", "0", "1", ""},
+	{"ej", "0", "1", ""},
+	{"ey", "0", "1", ""},
+	{"eu", "1", "1", ""},
+	{"i", "0", "", ""},
+	{"ia", "1", "", ""},
+	{"ie", "1", "", ""},
+	{"io", "1", "", ""},
+	{"iu", "1", "", ""},
+	{"o", "0", "", ""},
+	{"oi", "0", "1", ""},
+	{"oj", "0", "1", ""},
+	{"oy", "0", "1", ""},
+	{"u", "0", "", ""},
+	{"ue", "0", "", ""},
+	{"ui", "0", "1", ""},
+	{"uj", "0", "1", ""},
+	{"uy", "0", "1", ""},
+	{"y", "1", "", ""},
+
+	{"b", "7", "7", "7"},
+	{"c", "5|4", "5|4", "5|4"},
+	{"ch", "5|4", "5|4", "5|4"},
+	{"chs", "5", "54", "54"},
+	{"ck", "5|45", "5|45", "5|45"},
+	{"cs", "4", "4", "4"},
+	{"csz", "4", "4", "4"},
+	{"cz", "4", "4", "4"},
+	{"czs", "4", "4", "4"},
+	{"d", "3", "3", "3"},
+	{"drs", "4", "4", "4"},
+	{"drz", "4", "4", "4"},
+	{"ds", "4", "4", "4"},
+	{"dsh", "4", "4", "4"},
+	{"dsz", "4", "4", "4"},
+	{"dt", "3", "3", "3"},
+	{"dz", "4", "4", "4"},
+	{"dzh", "4", "4", "4"},
+	{"dzs", "4", "4", "4"},
+	{"f", "7", "7", "7"},
+	{"fb", "7", "7", "7"},
+	{"g", "5", "5", "5"},
+	{"h", "5", "5", ""},
+	{"j", "1|4", "|4", "|4"},
+	{"k", "5", "5", "5"},
+	{"kh", "5", "5", "5"},
+	{"ks", "5", "54", "54"},
+	{"l", "8", "8", "8"},
+	{"m", "6", "6", "6"},
+	{"mn", "66", "66", "66"},
+	{"n", "6", "6", "6"},
+	{"nm", "66", "66", "66"},
+	{"p", "7", "7", "7"},
+	{"pf", "7", "7", "7"},
+	{"ph", "7", "7", "7"},
+	{"q", "5", "5", "5"},
+	{"r", "9", "9", "9"},
+	{"rs", "94|4", "94|4", "94|4"},
+	{"rz", "94|4", "94|4", "94|4"},
+	{"s", "4", "4", "4"},
+	{"sch", "4", "4", "4"},
+	{"schd", "2", "43", "43"},
+	{"scht", "2", "43", "43"},
+	{"schtch", "2", "4", "4"},
+	{"schtsch", "2", "4", "4"},
+	{"schtsh", "2", "4", "4"},
+	{"sd", "2", "43", "43"},
+	{"sh", "4", "4", "4"},
+	{"shch", "2", "4", "4"},
+	{"shd", "2", "43", "43"},
+	{"sht", "2", "43", "43"},
+	{"shtch", "2", "4", "4"},
+	{"shtsh", "2", "4", "4"},
+	{"st", "2", "43", "43"},
+	{"stch", "2", "4", "4"},
+	{"strs", "2", "4", "4"},
+	{"strz", "2", "4", "4"},
+	{"stsch", "2", "4", "4"},
+	{"stsh", "2", "4", "4"},
+	{"sz", "4", "4", "4"},
+	{"szcz", "2", "4", "4"},
+	{"szcs", "2", "4", "4"},
+	{"szd", "2", "43", "43"},
+	{"szt", "2", "43", "43"},
+	{"t", "3", "3", "3"},
+	{"tc", "4", "4", "4"},
+	{"tch", "4", "4", "4"},
+	{"th", "3", "3", "3"},
+	{"trs", "4", "4", "4"},
+	{"trz", "4", "4", "4"},
+	{"ts", "4", "4", "4"},
+	{"tsch", "4", "4", "4"},
+	{"tsh", "4", "4", "4"},
+	{"tsz", "4", "4", "4"},
+	{"ttch", "4", "4", "4"},
+	{"ttsch", "4", "4", "4"},
+	{"ttsz", "4", "4", "4"},
+	{"ttz", "4", "4", "4"},
+	{"tz", "4", "4", "4"},
+	{"tzs", "4", "4", "4"},
+	{"v", "7", "7", "7"},
+	{"w", "7", "7", "7"},
+	{"x", "5", "54", "54"},
+	{"z", "4", "4", "4"},
+	{"zd", "2", "43", "43"},
+	{"zdzh", "2", "4", "4"},
+	{"zh", "4", "4", "4"},
+	{"zhdzh", "2", "4", "4"},
+	{"zs", "4", "4", "4"},
+	{"zsch", "4", "4", "4"},
+	{"zsh", "4", "4", "4"},
+}
+
+//rules are grouped by the first letter and sorted from the longest pattern
+var rules = buildRules()
+
+func buildRules() map[byte][]*rule {
+	res := map[byte][]*rule{}
+	for _, raw := range rawRules {
+		r := &rule{
+			pattern:     raw[0],
+			atStart:     strings.Split(raw[1], "|"),
+			beforeVowel: strings.Split(raw[2], "|"),
+			other:       strings.Split(raw[3], "|"),
+		}
+		res[r.pattern[0]] = append(res[r.pattern[0]], r)
+	}
+	for _, group := range res {
+		sort.SliceStable(group, func(i, j int) bool {
+			return len(group[i].pattern) > len(group[j].pattern)
+		})
+	}
+	return res
+}
+
+//branch is one of alternative codes being built
+type branch struct {
+	code    string
+	last    string
+	hasLast bool
+}
+
+func (b *branch) append(replacement string, force bool) {
+	if !b.hasLast || !strings.HasSuffix(b.last, replacement) || force {
+		b.code += replacement
+		if len(b.code) > codeLength {
+			b.code = b.code[:codeLength]
+		}
+	}
+	b.last = replacement
+	b.hasLast = true
+}
+
+func isVowel(ch byte) bool {
+	return ch == 'a' || ch == 'e' || ch == 'i' || ch == 'o' || ch == 'u'
+}
+
+//Encode returns Daitch-Mokotoff Soundex codes of a name. Cyrillic names are transliterated first,
+//so "Александр" and "Aleksandr" have the same codes
+func Encode(name string) []string {
+	word := Normalize(name)
+	if word == "" {
+		return nil
+	}
+
+	branches := []*branch{{}}
+	for i := 0; i < len(word); {
+		var matched *rule
+		for _, r := range rules[word[i]] {
+			if strings.HasPrefix(word[i:], r.pattern) {
+				matched = r
+				break
+			}
+		}
+		if matched == nil {
+			i++
+			continue
+		}
+
+		next := i + len(matched.pattern)
+		replacements := matched.other
+		if i == 0 {
+			replacements = matched.atStart
+		} else if next < len(word) && isVowel(word[next]) {
+			replacements = matched.beforeVowel
+		}
+
+		force := len(replacements) > 1
+		newBranches := []*branch{}
+		seen := map[string]bool{}
+		for _, b := range branches {
+			for _, replacement := range replacements {
+				nb := *b
+				nb.append(replacement, force)
+				if !seen[nb.code+"|"+nb.last] {
+					seen[nb.code+"|"+nb.last] = true
+					newBranches = append(newBranches, &nb)
+				}
+			}
+		}
+		branches = newBranches
+		i = next
+	}
+
+	codes := []string{}
+	seen := map[string]bool{}
+	for _, b := range branches {
+		code := b.code + strings.Repeat("0", codeLength-len(b.code))
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+//Normalize transliterates a name to lowercase latin letters without diacritics and drops other symbols
+func Normalize(name string) string {
+	builder := strings.Builder{}
+	for _, ch := range strings.ToLower(Transliterate(name)) {
+		if ch >= 'a' && ch <= 'z' {
+			builder.WriteRune(ch)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,104 @@
+package phonetic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Aleksandr", []string{"085463"}},
+		{"Alexander", []string{"085463"}},
+		{"Александр", []string{"085463"}},
+		{"Shevchenko", []string{"475650", "474650"}},
+		{"Шевченко", []string{"475650", "474650"}},
+		{"Chevchenko", []string{"575650", "574650", "475650", "474650"}},
+		{"Moskowitz", []string{"645740"}},
+		{"Moskovitz", []string{"645740"}},
+		{"Иванов", []string{"076700"}},
+		{"Łukasz", []string{"854000"}},
+		//alternatives of "rs", "ch" and "ck" branch codes
+		{"Peters", []string{"739400", "734000"}},
+		{"Auerbach", []string{"097500", "097400"}},
+		{"Jackson", []string{"154600", "145460", "454600", "445460"}},
+		{"Schwarzenegger", []string{"479465", "474659"}},
+		{"", nil},
+		{"123", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Encode(tt.name)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeMatches(t *testing.T) {
+	pairs := [][2]string{
+		{"Aleksandr", "Alexander"},
+		{"Alexander", "Александр"},
+		{"Shevchenko", "Chevchenko"},
+		{"Chevchenko", "Шевченко"},
+	}
+	for _, pair := range pairs {
+		if !shareCode(Encode(pair[0]), Encode(pair[1])) {
+			t.Errorf("%q and %q have no common code", pair[0], pair[1])
+		}
+	}
+}
+
+func shareCode(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Александр", "aleksandr"},
+		{"Щукин", "shchukin"},
+		{"Łukasz", "lukasz"},
+		{"O'Neil-Smith", "oneilsmith"},
+		{"Ёлкин", "elkin"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"Ivan", "ivan", 0},
+		{"Ёж", "еж", 0},
+		{"Иван", "Ivan", 0},
+		{"Aleksandr", "Александр", 0},
+		{"Alexander", "Александр", 3},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package transcode
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+//MsgPack converts JSON document to MessagePack. Integers get the smallest format which fits them,
+//other numbers are float 64
+func MsgPack(w io.Writer, data []byte) error {
+	n, err := parse(data)
+	if err != nil {
+		return err
+	}
+	return writeMsgPack(msgpack.NewEncoder(w), n)
+}
+
+func writeMsgPack(encoder *msgpack.Encoder, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		err := encoder.EncodeMapLen(len(n.Content) / 2)
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			err = encoder.EncodeString(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			err = writeMsgPack(encoder, n.Content[i+1])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		err := encoder.EncodeArrayLen(len(n.Content))
+		if err != nil {
+			return err
+		}
+		for _, value := range n.Content {
+			err = writeMsgPack(encoder, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch n.Tag {
+	case nullTag:
+		return encoder.EncodeNil()
+	case "!!bool":
+		var value bool
+		err := n.Decode(&value)
+		if err != nil {
+			return err
+		}
+		return encoder.EncodeBool(value)
+	case "!!int":
+		var value int64
+		if n.Decode(&value) == nil {
+			return encoder.EncodeInt(value)
+		}
+		var unsigned uint64
+		if n.Decode(&unsigned) == nil {
+			return encoder.EncodeUint(unsigned)
+		}
+		fallthrough
+	case "!!float":
+		var value float64
+		err := n.Decode(&value)
+		if err != nil {
+			return err
+		}
+		return encoder.EncodeFloat64(value)
+	}
+	return encoder.EncodeString(n.Value)
+}
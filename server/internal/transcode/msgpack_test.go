@@ -0,0 +1,81 @@
+package transcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMsgPack(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []byte
+	}{
+		{"zero", `0`, []byte{0x00}},
+		{"positive fixint", `127`, []byte{0x7f}},
+		{"uint 8", `128`, []byte{0xcc, 0x80}},
+		{"uint 16", `256`, []byte{0xcd, 0x01, 0x00}},
+		{"uint 32", `65536`, []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+		{"uint 64", `4294967296`, []byte{0xcf, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{"max uint 64", `18446744073709551615`, []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"negative fixint", `-32`, []byte{0xe0}},
+		{"int 8", `-33`, []byte{0xd0, 0xdf}},
+		{"int 16", `-129`, []byte{0xd1, 0xff, 0x7f}},
+		{"int 32", `-32769`, []byte{0xd2, 0xff, 0xff, 0x7f, 0xff}},
+		{"int 64", `-2147483649`, []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff}},
+		{"float 64", `1.5`, []byte{0xcb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"integral float", `2.0`, []byte{0xcb, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"true", `true`, []byte{0xc3}},
+		{"false", `false`, []byte{0xc2}},
+		{"nil", `null`, []byte{0xc0}},
+		{"fixstr", `"ab"`, []byte{0xa2, 'a', 'b'}},
+		{"utf-8 length in bytes", `"я"`, []byte{0xa2, 0xd1, 0x8f}},
+		{"fixmap in order", `{"b":1,"a":[true]}`, []byte{0x82, 0xa1, 'b', 0x01, 0xa1, 'a', 0x91, 0xc3}},
+		{"empty map", `{}`, []byte{0x80}},
+		{"empty array", `[]`, []byte{0x90}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := MsgPack(buf, []byte(tt.doc)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("MsgPack(%s) = % x, want % x", tt.doc, buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMsgPackLengths(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		header []byte
+	}{
+		{"fixstr 31", `"` + strings.Repeat("x", 31) + `"`, []byte{0xbf}},
+		{"str 8", `"` + strings.Repeat("x", 32) + `"`, []byte{0xd9, 0x20}},
+		{"str 16", `"` + strings.Repeat("x", 256) + `"`, []byte{0xda, 0x01, 0x00}},
+		{"str 32", `"` + strings.Repeat("x", 70000) + `"`, []byte{0xdb, 0x00, 0x01, 0x11, 0x70}},
+		{"fixarray 15", `[` + strings.Repeat("0,", 14) + `0]`, []byte{0x9f}},
+		{"array 16", `[` + strings.Repeat("0,", 15) + `0]`, []byte{0xdc, 0x00, 0x10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := MsgPack(buf, []byte(tt.doc)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(buf.Bytes(), tt.header) {
+				t.Errorf("MsgPack() starts with % x, want % x", buf.Bytes()[:len(tt.header)], tt.header)
+			}
+		})
+	}
+}
+
+func TestMsgPackInvalid(t *testing.T) {
+	if err := MsgPack(&bytes.Buffer{}, []byte(`{"a"}`)); err != ErrInvalidJSON {
+		t.Errorf("MsgPack() error = %v, want %v", err, ErrInvalidJSON)
+	}
+}
@@ -0,0 +1,25 @@
+package transcode
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+//ErrInvalidJSON is returned when converted document is not JSON
+var ErrInvalidJSON = errors.New("document is not JSON")
+
+//parse reads JSON document as YAML node, JSON is YAML, so the node keeps order of object members
+//and numbers as they are written
+func parse(data []byte) (*yaml.Node, error) {
+	if !json.Valid(data) {
+		return nil, ErrInvalidJSON
+	}
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(data, doc)
+	if err != nil || len(doc.Content) != 1 {
+		return nil, ErrInvalidJSON
+	}
+	return doc.Content[0], nil
+}
@@ -0,0 +1,101 @@
+package transcode
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+//xmlItem is a name of elements of arrays
+const xmlItem = "item"
+
+//nullTag is a tag of JSON null
+const nullTag = "!!null"
+
+//XML converts JSON document to XML document with the given root element.
+//Members of objects become child elements and items of arrays become item elements
+func XML(w io.Writer, root string, data []byte) error {
+	n, err := parse(data)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	return encodeXML(w, root, n)
+}
+
+//XMLElement converts JSON document to one XML element without XML declaration
+func XMLElement(w io.Writer, name string, data []byte) error {
+	n, err := parse(data)
+	if err != nil {
+		return err
+	}
+	return encodeXML(w, name, n)
+}
+
+func encodeXML(w io.Writer, name string, n *yaml.Node) error {
+	encoder := xml.NewEncoder(w)
+	err := writeXML(encoder, xmlName(name), n)
+	if err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func writeXML(encoder *xml.Encoder, name string, n *yaml.Node) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	err := encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			err = writeXML(encoder, xmlName(n.Content[i].Value), n.Content[i+1])
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, value := range n.Content {
+			err = writeXML(encoder, xmlItem, value)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		if n.Tag != nullTag {
+			err = encoder.EncodeToken(xml.CharData(n.Value))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+//xmlName replaces characters which can not be used in names of XML elements
+func xmlName(name string) string {
+	builder := &strings.Builder{}
+	for i, r := range name {
+		valid := unicode.IsLetter(r) || r == '_' || (i != 0 && (unicode.IsDigit(r) || r == '-' || r == '.'))
+		if !valid {
+			if i == 0 && unicode.IsDigit(r) {
+				builder.WriteRune('_')
+				builder.WriteRune(r)
+				continue
+			}
+			r = '_'
+		}
+		builder.WriteRune(r)
+	}
+	if builder.Len() == 0 {
+		return "_"
+	}
+	return builder.String()
+}
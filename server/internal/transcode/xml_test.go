@@ -0,0 +1,89 @@
+package transcode
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestXMLName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"person", "person"},
+		{"deleted_at", "deleted_at"},
+		{"first-name.v2", "first-name.v2"},
+		{"имя", "имя"},
+		{"1st", "_1st"},
+		{"-a", "_a"},
+		{"a b", "a_b"},
+		{"a:b", "a_b"},
+		{"<x>", "_x_"},
+		{"", "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := xmlName(tt.name); got != tt.want {
+				t.Errorf("xmlName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXMLElement(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+		doc  string
+		want string
+	}{
+		{"person", "person", `{"id":1,"name":"Ivan","age":30,"deleted_at":null,"active":true}`,
+			"<person><id>1</id><name>Ivan</name><age>30</age><deleted_at></deleted_at><active>true</active></person>"},
+		{"array", "people", `[{"id":1},{"id":2}]`, "<people><item><id>1</id></item><item><id>2</id></item></people>"},
+		{"nested", "r", `{"a":{"b":[1,[2]]},"c":{},"d":[]}`, "<r><a><b><item>1</item><item><item>2</item></item></b></a><c></c><d></d></r>"},
+		{"escaped text", "r", `{"name":"<b>&\"x\""}`, "<r><name>&lt;b&gt;&amp;&#34;x&#34;</name></r>"},
+		{"renamed members", "r", `{"1st":1,"a b":2}`, "<r><_1st>1</_1st><a_b>2</a_b></r>"},
+		{"numbers as written", "r", `[1.50,1e3]`, "<r><item>1.50</item><item>1e3</item></r>"},
+		{"scalar", "r", `false`, "<r>false</r>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := &strings.Builder{}
+			if err := XMLElement(builder, tt.root, []byte(tt.doc)); err != nil {
+				t.Fatal(err)
+			}
+			if builder.String() != tt.want {
+				t.Errorf("XMLElement() = %s, want %s", builder.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestXML(t *testing.T) {
+	builder := &strings.Builder{}
+	if err := XML(builder, "person", []byte(`{"name":"Иван","note":"a\nb"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(builder.String(), xml.Header) {
+		t.Fatalf("XML() = %s, want XML declaration", builder.String())
+	}
+
+	//the document is well-formed and keeps text
+	var person struct {
+		Name string `xml:"name"`
+		Note string `xml:"note"`
+	}
+	if err := xml.Unmarshal([]byte(builder.String()), &person); err != nil {
+		t.Fatal(err)
+	}
+	if person.Name != "Иван" || person.Note != "a\nb" {
+		t.Errorf("decoded %+v", person)
+	}
+}
+
+func TestXMLInvalid(t *testing.T) {
+	if err := XML(&strings.Builder{}, "r", []byte(`[1,`)); err != ErrInvalidJSON {
+		t.Errorf("XML() error = %v, want %v", err, ErrInvalidJSON)
+	}
+}
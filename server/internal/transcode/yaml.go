@@ -0,0 +1,71 @@
+package transcode
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//YAML converts JSON document to block style YAML
+func YAML(w io.Writer, data []byte) error {
+	n, err := parse(data)
+	if err != nil {
+		return err
+	}
+	return encodeYAML(w, n)
+}
+
+//YAMLItem converts JSON document to an item of YAML sequence indented by the given count of spaces
+func YAMLItem(w io.Writer, data []byte, indent int) error {
+	n, err := parse(data)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	err = encodeYAML(buf, &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{n}})
+	if err != nil {
+		return err
+	}
+
+	pad := strings.Repeat(" ", indent)
+	builder := &strings.Builder{}
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			builder.WriteString(pad + line)
+		}
+	}
+	_, err = io.WriteString(w, builder.String())
+	return err
+}
+
+func encodeYAML(w io.Writer, n *yaml.Node) error {
+	err := blockStyle(n)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err = encoder.Encode(n)
+	if err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+//blockStyle drops flow style and quotes of JSON. Strings are encoded again like Go strings,
+//so only strings which would be read as something else, YAML 1.1 booleans too, are quoted
+func blockStyle(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		return n.Encode(n.Value)
+	}
+	n.Style = 0
+	for _, child := range n.Content {
+		err := blockStyle(child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
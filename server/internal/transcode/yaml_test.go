@@ -0,0 +1,93 @@
+package transcode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYAMLStrings(t *testing.T) {
+	//strings which would be read as something else are quoted, others are kept as they are
+	values := []string{
+		"Ivan", "Иван", "O'Neil", "Anna Maria", "a.b-c_d", "e1", "",
+		" Ivan", "Ivan ",
+		//booleans and nulls of YAML 1.1 and 1.2 in any case
+		"true", "False", "YES", "no", "On", "off", "y", "N", "null", "Null", "~",
+		//numbers, dates and times
+		"123", "1.5", "0x1F", ".inf", "2024-01-02", "12:30",
+		//indicators and comments
+		"-", "- a", "a: b", "key:", "a #b", "#c", "*a", "!tag", "|", "%x", "@x", "[a]", "{a}", "a,b", "?",
+		`"q"`, "line\nbreak", "tab\tx", "bell\a", "<<", "&a",
+	}
+	for _, value := range values {
+		t.Run(value, func(t *testing.T) {
+			doc, err := json.Marshal(map[string]string{"value": value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			builder := &strings.Builder{}
+			if err := YAML(builder, doc); err != nil {
+				t.Fatal(err)
+			}
+			var decoded map[string]interface{}
+			if err := yaml.Unmarshal([]byte(builder.String()), &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded["value"] != value {
+				t.Errorf("YAML() = %q is read as %#v, want %q", builder.String(), decoded["value"], value)
+			}
+		})
+	}
+}
+
+func TestYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"person", `{"id":1,"name":"Ivan","patronymic":"","age":30,"gender":"male","nation":"NO","deleted_at":null}`,
+			"id: 1\nname: Ivan\npatronymic: \"\"\nage: 30\ngender: male\nnation: \"NO\"\ndeleted_at: null\n"},
+		{"nested", `{"a":1,"b":[1,{"c":"yes","d":[]},[2,3],{}],"e":{"f":{"g":"x: y"}},"true":false}`,
+			"a: 1\nb:\n  - 1\n  - c: \"yes\"\n    d: []\n  - - 2\n    - 3\n  - {}\ne:\n  f:\n    g: 'x: y'\n\"true\": false\n"},
+		{"array of objects", `[{"a":1,"b":2},{"a":3}]`, "- a: 1\n  b: 2\n- a: 3\n"},
+		{"nested arrays", `[[1,[2]],[{"a":{"b":[]}}]]`, "- - 1\n  - - 2\n- - a:\n      b: []\n"},
+		{"empty object", `{}`, "{}\n"},
+		{"empty array", `[]`, "[]\n"},
+		{"string", `"on"`, "\"on\"\n"},
+		{"number", `-1.5e3`, "-1.5e3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := &strings.Builder{}
+			if err := YAML(builder, []byte(tt.doc)); err != nil {
+				t.Fatal(err)
+			}
+			if builder.String() != tt.want {
+				t.Errorf("YAML() = %q, want %q", builder.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLItem(t *testing.T) {
+	builder := &strings.Builder{}
+	builder.WriteString("items:\n")
+	for _, doc := range []string{`{"id":1,"tags":["a","b"]}`, `{"id":2,"tags":[]}`, `"off"`} {
+		if err := YAMLItem(builder, []byte(doc), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "items:\n  - id: 1\n    tags:\n      - a\n      - b\n  - id: 2\n    tags: []\n  - \"off\"\n"
+	if builder.String() != want {
+		t.Errorf("YAMLItem() = %q, want %q", builder.String(), want)
+	}
+}
+
+func TestYAMLInvalid(t *testing.T) {
+	if err := YAML(&strings.Builder{}, []byte(`{"a":`)); err != ErrInvalidJSON {
+		t.Errorf("YAML() error = %v, want %v", err, ErrInvalidJSON)
+	}
+}